@@ -0,0 +1,130 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// Adaptive configures a limit adapting to the health of a backend, following an AIMD algorithm: the limit
+// increases additively while signals are good, and decreases multiplicatively on overload.
+// The limit is adapted locally: every instance of a service has its own, driven by the signals it receives.
+type Adaptive struct {
+	// Min is the lowest limit. If zero, the limit doesn't go below one.
+	Min int64
+	// Max is the highest limit, which is also the initial one. If zero, the limit of the rate is used.
+	Max int64
+	// Increase is added to the limit on a good signal. If zero, one is used.
+	Increase int64
+	// Decrease multiplies the limit on overload. If not between zero and one, a half is used.
+	Decrease float64
+	// Latency is the latency above which a signal is an overload. Zero means latency is ignored.
+	Latency time.Duration
+	// Interval is the minimum duration between two decreases, and between an adjustment and an increase of the
+	// limit, so a burst of signals is handled as a single one. If zero, DefaultAdaptiveInterval is used.
+	Interval time.Duration
+	// Clock gives the current time to space adjustments. If nil, SystemClock is used.
+	Clock Clock
+}
+
+// Signal is a measure of the health of the backend protected by a limiter.
+type Signal struct {
+	// Latency is the time taken to handle a request.
+	Latency time.Duration
+	// Overload is true if the request has failed because the backend is overloaded.
+	Overload bool
+}
+
+// adaptiveLimit is the current limit of a limiter configured with Adaptive.
+type adaptiveLimit struct {
+	adaptive  Adaptive
+	mutex     sync.RWMutex
+	limit     int64
+	adjusted  time.Time
+	decreased time.Time
+}
+
+// newAdaptiveLimit returns the limit adapted for given rate, or nil if adaptive limits are disabled.
+func newAdaptiveLimit(adaptive *Adaptive, rate Rate) *adaptiveLimit {
+	if adaptive == nil {
+		return nil
+	}
+
+	config := *adaptive
+	if config.Max <= 0 {
+		config.Max = rate.Limit
+	}
+	if config.Min <= 0 {
+		config.Min = 1
+	}
+	if config.Min > config.Max {
+		config.Min = config.Max
+	}
+	if config.Increase <= 0 {
+		config.Increase = 1
+	}
+	if config.Decrease <= 0 || config.Decrease >= 1 {
+		config.Decrease = 0.5
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultAdaptiveInterval
+	}
+	if config.Clock == nil {
+		config.Clock = SystemClock
+	}
+
+	return &adaptiveLimit{
+		adaptive: config,
+		limit:    config.Max,
+	}
+}
+
+// get returns the current limit.
+func (adaptive *adaptiveLimit) get() int64 {
+	adaptive.mutex.RLock()
+	defer adaptive.mutex.RUnlock()
+
+	return adaptive.limit
+}
+
+// signal adjusts the limit according to given signal, unless it has been adjusted during the last interval.
+// An overload is only ignored after a recent decrease, so the limit drops as soon as the backend is overloaded.
+func (adaptive *adaptiveLimit) signal(signal Signal) {
+	overload := signal.Overload || (adaptive.adaptive.Latency > 0 && signal.Latency > adaptive.adaptive.Latency)
+	now := adaptive.adaptive.Clock.Now()
+
+	adaptive.mutex.Lock()
+	defer adaptive.mutex.Unlock()
+
+	last := adaptive.adjusted
+	if overload {
+		last = adaptive.decreased
+	}
+	if !last.IsZero() && now.Sub(last) < adaptive.adaptive.Interval {
+		return
+	}
+
+	limit := adaptive.limit
+	if overload {
+		limit = int64(float64(limit) * adaptive.adaptive.Decrease)
+	} else {
+		limit += adaptive.adaptive.Increase
+	}
+
+	if limit < adaptive.adaptive.Min {
+		limit = adaptive.adaptive.Min
+	}
+	if limit > adaptive.adaptive.Max {
+		limit = adaptive.adaptive.Max
+	}
+
+	// Signals which don't change the limit don't delay the next adjustment.
+	if limit == adaptive.limit {
+		return
+	}
+
+	adaptive.limit = limit
+	adaptive.adjusted = now
+	if overload {
+		adaptive.decreased = now
+	}
+}
@@ -0,0 +1,91 @@
+package limiter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
+)
+
+func TestLimiterAdaptive(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	overrides := limiter.NewOverrides()
+	instance := limiter.New(memory.NewStore(), limiter.Rate{Limit: 100, Period: time.Minute},
+		limiter.WithOverrides(overrides),
+		limiter.WithAdaptive(limiter.Adaptive{
+			Min:      10,
+			Increase: 5,
+			Decrease: 0.5,
+			Latency:  100 * time.Millisecond,
+			Interval: time.Second,
+			Clock:    clock,
+		}))
+
+	overload := limiter.Signal{Overload: true}
+	healthy := limiter.Signal{Latency: 10 * time.Millisecond}
+
+	is.Equal(int64(100), instance.GetRate("foo").Limit)
+
+	// Good signals don't go above the maximum.
+	instance.Signal(healthy)
+	is.Equal(int64(100), instance.GetRate("foo").Limit)
+
+	instance.Signal(overload)
+	is.Equal(int64(50), instance.GetRate("foo").Limit)
+
+	// Signals are ignored during the interval following an adjustment.
+	instance.Signal(overload)
+	instance.Signal(healthy)
+	is.Equal(int64(50), instance.GetRate("foo").Limit)
+
+	clock.Advance(time.Second)
+	instance.Signal(overload)
+	is.Equal(int64(25), instance.GetRate("foo").Limit)
+
+	clock.Advance(time.Second)
+	instance.Signal(healthy)
+	is.Equal(int64(30), instance.GetRate("foo").Limit)
+
+	// An overload right after an increase decreases the limit.
+	instance.Signal(limiter.Signal{Latency: 200 * time.Millisecond})
+	is.Equal(int64(15), instance.GetRate("foo").Limit)
+
+	clock.Advance(time.Second)
+	instance.Signal(overload)
+	is.Equal(int64(10), instance.GetRate("foo").Limit)
+
+	// The adapted limit is used by the limiter, unless an identifier has an override.
+	lctx, err := instance.Get(ctx, "foo")
+	is.NoError(err)
+	is.Equal(int64(10), lctx.Limit)
+	is.Equal(int64(9), lctx.Remaining)
+
+	contexts, err := instance.GetMulti(ctx, []string{"foo", "bar"})
+	is.NoError(err)
+	is.Equal(int64(10), contexts[0].Limit)
+	is.Equal(int64(8), contexts[0].Remaining)
+	is.Equal(int64(10), contexts[1].Limit)
+
+	overrides.Set("bar", limiter.Rate{Limit: 1000, Period: time.Minute}, 0)
+	is.Equal(int64(1000), instance.GetRate("bar").Limit)
+
+	for i := 0; i < 20; i++ {
+		clock.Advance(time.Second)
+		instance.Signal(healthy)
+	}
+	is.Equal(int64(100), instance.GetRate("foo").Limit)
+	is.Equal(instance.Rate, instance.GetRate("foo"))
+
+	// Signals are ignored without adaptive limit.
+	instance = limiter.New(memory.NewStore(), limiter.Rate{Limit: 100, Period: time.Minute})
+	instance.Signal(overload)
+	is.Equal(int64(100), instance.GetRate("foo").Limit)
+}
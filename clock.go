@@ -0,0 +1,22 @@
+package limiter
+
+import (
+	"time"
+)
+
+// Clock gives the current time to a store.
+// A custom clock is mainly useful to control time in tests, without waiting for a window to expire.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// SystemClock is the default clock, using the system time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+// Now returns the current system time.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
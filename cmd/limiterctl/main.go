@@ -0,0 +1,343 @@
+// Command limiterctl inspects and changes the limits kept in a Redis store, validates rates,
+// and simulates a load against a limiter.
+//
+// Usage:
+//
+//	limiterctl [flags] peek <key>
+//	limiterctl [flags] reset <key>
+//	limiterctl [flags] list [prefix]
+//	limiterctl [flags] set <key> <count>
+//	limiterctl parse <rate>
+//	limiterctl [flags] simulate
+//
+// Keys are the identifiers given to the limiter: they're prefixed like redis.Store does, with the -prefix and
+// -hash-tag flags.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	libredis "github.com/redis/go-redis/v9"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/drivers/store/redis"
+)
+
+const usage = `Usage: limiterctl [flags] <command> [arguments]
+
+Commands:
+  peek <key>           print the limit of an identifier, without modification
+  reset <key>          reset the limit of an identifier, for a period of the rate
+  list [prefix]        print every identifier starting with prefix, and its count
+  set <key> <count>    set the count of an identifier, for a period of the rate
+  parse <rate>         validate a rate, like 100-M, and print its limit and period
+  simulate             send requests to a limiter using a memory store, and print admitted and rejected
+                       requests per second
+
+Flags:
+`
+
+// config contains the flags of the command.
+type config struct {
+	redisURL string
+	prefix   string
+	hashTag  bool
+	rate     string
+	rps      int
+	duration time.Duration
+	keys     int
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command with given arguments, and returns its exit code.
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	cfg := config{}
+
+	flags := flag.NewFlagSet("limiterctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&cfg.redisURL, "redis", "redis://localhost:6379/0", "URL of the Redis server")
+	flags.StringVar(&cfg.prefix, "prefix", limiter.DefaultPrefix, "prefix of the keys in Redis")
+	flags.BoolVar(&cfg.hashTag, "hash-tag", false, "wrap identifiers in a hash tag, for Redis Cluster")
+	flags.StringVar(&cfg.rate, "rate", "", "rate of the limiter, like 100-M")
+	flags.IntVar(&cfg.rps, "rps", 100, "requests per second sent by simulate")
+	flags.DurationVar(&cfg.duration, "duration", 10*time.Second, "duration of simulate")
+	flags.IntVar(&cfg.keys, "keys", 1, "number of identifiers used by simulate")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	command, args := flags.Arg(0), flags.Args()[1:]
+
+	switch command {
+	case "peek", "reset", "list", "set":
+		err = runStoreCommand(cfg, command, args, stdout)
+	case "parse":
+		err = runParse(args, stdout)
+	case "simulate":
+		err = runSimulate(cfg, args, stdout)
+	default:
+		err = errors.Errorf("unknown command %q", command)
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "limiterctl: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// runStoreCommand executes a command using the Redis store.
+func runStoreCommand(cfg config, command string, args []string, stdout io.Writer) error {
+	ctx := context.Background()
+
+	options, err := libredis.ParseURL(cfg.redisURL)
+	if err != nil {
+		return errors.Wrap(err, "invalid redis URL")
+	}
+
+	client := libredis.NewClient(options)
+	defer client.Close()
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix:  cfg.prefix,
+		HashTag: cfg.hashTag,
+	})
+	if err != nil {
+		return errors.Wrap(err, "cannot connect to redis")
+	}
+
+	switch command {
+	case "peek":
+		return runPeek(ctx, store, cfg, args, stdout)
+	case "reset":
+		return runReset(ctx, store, cfg, args, stdout)
+	case "list":
+		return runList(ctx, store.(limiter.Scanner), args, stdout)
+	default:
+		return runSet(ctx, store.(setter), cfg, args, stdout)
+	}
+}
+
+// runPeek prints the limit of an identifier, without modification.
+func runPeek(ctx context.Context, store limiter.Store, cfg config, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: peek <key>")
+	}
+
+	rate, err := requireRate(cfg)
+	if err != nil {
+		return err
+	}
+
+	context, err := store.Peek(ctx, args[0], rate)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "key=%s limit=%d remaining=%d reset=%s reached=%t\n", args[0], context.Limit,
+		context.Remaining, time.Unix(context.Reset, 0).Format(time.RFC3339), context.Reached)
+	return nil
+}
+
+// runReset resets the limit of an identifier.
+func runReset(ctx context.Context, store limiter.Store, cfg config, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: reset <key>")
+	}
+
+	rate, err := requireRate(cfg)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.Reset(ctx, args[0], rate)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "key=%s reset\n", args[0])
+	return nil
+}
+
+// runList prints every identifier starting with a prefix, and its count.
+func runList(ctx context.Context, scanner limiter.Scanner, args []string, stdout io.Writer) error {
+	if len(args) > 1 {
+		return errors.New("usage: list [prefix]")
+	}
+
+	prefix := ""
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+
+	return scanner.Scan(ctx, prefix, func(entry limiter.Entry) bool {
+		expiration := "never"
+		if !entry.Expiration.IsZero() {
+			expiration = entry.Expiration.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(stdout, "key=%s count=%d expiration=%s\n", entry.Key, entry.Count, expiration)
+		return true
+	})
+}
+
+// setter is implemented by stores able to set the count of an identifier atomically, like redis.Store.
+type setter interface {
+	Set(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error)
+}
+
+// runSet sets the count of an identifier, which expires after the period of the rate.
+func runSet(ctx context.Context, store setter, cfg config, args []string, stdout io.Writer) error {
+	if len(args) != 2 {
+		return errors.New("usage: set <key> <count>")
+	}
+
+	rate, err := requireRate(cfg)
+	if err != nil {
+		return err
+	}
+
+	count, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || count <= 0 {
+		return errors.Errorf("count must be a positive integer, got %q", args[1])
+	}
+
+	context, err := store.Set(ctx, args[0], count, rate)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "key=%s limit=%d remaining=%d reset=%s reached=%t\n", args[0], context.Limit,
+		context.Remaining, time.Unix(context.Reset, 0).Format(time.RFC3339), context.Reached)
+	return nil
+}
+
+// runParse validates a rate, and prints its limit and period.
+func runParse(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: parse <rate>")
+	}
+
+	rate, err := limiter.NewRateFromFormatted(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "limit=%d period=%s\n", rate.Limit, rate.Period)
+	return nil
+}
+
+// runSimulate sends requests to a limiter using a memory store, at a constant rate, and prints the number of
+// admitted and rejected requests per second.
+func runSimulate(cfg config, args []string, stdout io.Writer) error {
+	if len(args) != 0 {
+		return errors.New("usage: simulate")
+	}
+
+	rate, err := requireRate(cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.rps <= 0 || cfg.keys <= 0 || cfg.duration <= 0 {
+		return errors.New("rps, keys and duration must be positive")
+	}
+
+	ctx := context.Background()
+	instance := limiter.New(memory.NewStore(), rate)
+
+	keys := make([]string, cfg.keys)
+	for i := range keys {
+		keys[i] = "simulate-" + strconv.Itoa(i)
+	}
+
+	seconds := int((cfg.duration + time.Second - 1) / time.Second)
+	admitted := make([]int64, seconds)
+	rejected := make([]int64, seconds)
+
+	// Each second is printed once it's over, so a long simulation shows its progress.
+	total := [2]int64{}
+	printed := 0
+	flush := func(until int) {
+		for ; printed < until; printed++ {
+			fmt.Fprintf(stdout, "second=%d admitted=%d rejected=%d\n", printed+1, admitted[printed], rejected[printed])
+			total[0] += admitted[printed]
+			total[1] += rejected[printed]
+		}
+	}
+
+	// Requests are sent on every tick, to catch up with the expected number of requests since the beginning.
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	start := time.Now()
+	sent := int64(0)
+	for {
+		elapsed := time.Since(start)
+		if elapsed > cfg.duration {
+			elapsed = cfg.duration
+		}
+
+		second := int(elapsed / time.Second)
+		if second >= seconds {
+			second = seconds - 1
+		}
+
+		expected := int64(elapsed) * int64(cfg.rps) / int64(time.Second)
+		for ; sent < expected; sent++ {
+			context, err := instance.Get(ctx, keys[sent%int64(len(keys))])
+			if err != nil {
+				return err
+			}
+
+			if context.Reached {
+				rejected[second]++
+			} else {
+				admitted[second]++
+			}
+		}
+
+		if elapsed == cfg.duration {
+			break
+		}
+		flush(second)
+		<-ticker.C
+	}
+
+	flush(seconds)
+	fmt.Fprintf(stdout, "total admitted=%d rejected=%d\n", total[0], total[1])
+
+	return nil
+}
+
+// requireRate returns the rate given with the -rate flag.
+func requireRate(cfg config) (limiter.Rate, error) {
+	if cfg.rate == "" {
+		return limiter.Rate{}, errors.New("a rate is required, like -rate 100-M")
+	}
+
+	return limiter.NewRateFromFormatted(cfg.rate)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	is := require.New(t)
+
+	code, stdout, _ := execute("parse", "100-M")
+	is.Equal(0, code)
+	is.Equal("limit=100 period=1m0s\n", stdout)
+
+	code, _, stderr := execute("parse", "100-W")
+	is.Equal(1, code)
+	is.Contains(stderr, "incorrect period 'W'")
+
+	code, _, _ = execute("parse")
+	is.Equal(1, code)
+
+	code, _, stderr = execute("unknown")
+	is.Equal(1, code)
+	is.Contains(stderr, `unknown command "unknown"`)
+
+	code, _, stderr = execute()
+	is.Equal(2, code)
+	is.Contains(stderr, "Usage: limiterctl")
+}
+
+func TestSimulate(t *testing.T) {
+	is := require.New(t)
+
+	code, stdout, _ := execute("-rate", "10-M", "-rps", "50", "-duration", "1s", "simulate")
+	is.Equal(0, code)
+	is.Equal("second=1 admitted=10 rejected=40\ntotal admitted=10 rejected=40\n", stdout)
+
+	// Each second is printed once it's over, before the end of the simulation.
+	writer := &timedWriter{start: time.Now()}
+	code = run([]string{"-rate", "10-M", "-rps", "40", "-keys", "2", "-duration", "1500ms", "simulate"},
+		writer, &bytes.Buffer{})
+	is.Equal(0, code)
+	stdout = writer.String()
+	is.True(strings.HasPrefix(stdout, "second=1 admitted=20 "), stdout)
+	is.True(strings.HasSuffix(stdout, "total admitted=20 rejected=40\n"), stdout)
+	is.Len(strings.Split(strings.TrimSpace(stdout), "\n"), 3)
+	is.Less(writer.first, 1400*time.Millisecond)
+
+	code, _, stderr := execute("simulate")
+	is.Equal(1, code)
+	is.Contains(stderr, "a rate is required")
+}
+
+func TestStoreCommands(t *testing.T) {
+	is := require.New(t)
+
+	uri := "redis://localhost:6379/0"
+	if os.Getenv("REDIS_URI") != "" {
+		uri = os.Getenv("REDIS_URI")
+	}
+
+	prefix := "limiter:limiterctl-test:" + time.Now().Format("150405.000000")
+	flags := []string{"-redis", uri, "-prefix", prefix, "-rate", "10-M"}
+
+	code, stdout, stderr := execute(append(flags, "set", "foo", "4")...)
+	is.Equal(0, code, stderr)
+	is.Contains(stdout, "key=foo limit=10 remaining=6")
+
+	code, stdout, _ = execute(append(flags, "peek", "foo")...)
+	is.Equal(0, code)
+	is.Contains(stdout, "key=foo limit=10 remaining=6")
+	is.Contains(stdout, "reached=false")
+
+	code, _, _ = execute(append(flags, "set", "bar", "12")...)
+	is.Equal(0, code)
+
+	code, stdout, _ = execute(append(flags, "list")...)
+	is.Equal(0, code)
+	is.Contains(stdout, "key=foo count=4")
+	is.Contains(stdout, "key=bar count=12")
+
+	code, stdout, _ = execute(append(flags, "list", "b")...)
+	is.Equal(0, code)
+	is.NotContains(stdout, "key=foo")
+	is.Contains(stdout, "key=bar count=12")
+
+	code, stdout, _ = execute(append(flags, "reset", "foo")...)
+	is.Equal(0, code)
+	is.Equal("key=foo reset\n", stdout)
+
+	code, stdout, _ = execute(append(flags, "peek", "foo")...)
+	is.Equal(0, code)
+	is.Contains(stdout, "remaining=10")
+
+	code, _, stderr = execute("-redis", uri, "-prefix", prefix, "reset", "foo")
+	is.Equal(1, code)
+	is.Contains(stderr, "a rate is required")
+
+	code, stdout, _ = execute(append(flags, "set", "bar", "3")...)
+	is.Equal(0, code)
+	is.Contains(stdout, "key=bar limit=10 remaining=7")
+
+	code, _, stderr = execute(append(flags, "set", "foo", "many")...)
+	is.Equal(1, code)
+	is.Contains(stderr, "count must be a positive integer")
+
+	_, _, _ = execute(append(flags, "reset", "bar")...)
+}
+
+// execute runs the command with given arguments, and returns its exit code and outputs.
+func execute(args ...string) (int, string, string) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := run(args, stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// timedWriter is a buffer recording when it's first written.
+type timedWriter struct {
+	bytes.Buffer
+	start time.Time
+	// first is the time elapsed since start when the buffer is first written.
+	first time.Duration
+}
+
+// Write records the time of the first write, and appends given data to the buffer.
+func (writer *timedWriter) Write(data []byte) (int, error) {
+	if writer.Len() == 0 {
+		writer.first = time.Since(writer.start)
+	}
+	return writer.Buffer.Write(data)
+}
@@ -12,4 +12,39 @@ const (
 
 	// DefaultCleanUpInterval is the default time duration for cleanup.
 	DefaultCleanUpInterval = 30 * time.Second
+
+	// DefaultBatchMaxSize is the default maximum number of calls coalesced into a single pipeline.
+	DefaultBatchMaxSize = 100
+
+	// DefaultMaxDrift is the default maximum number of increments counted locally by a hybrid store.
+	DefaultMaxDrift = 10
+
+	// DefaultSyncInterval is the default time duration between two flushes of a hybrid store.
+	DefaultSyncInterval = 100 * time.Millisecond
+
+	// DefaultTable is the default name of the table used by sql store.
+	DefaultTable = "limiter"
+
+	// DefaultPeerTimeout is the default timeout of a request forwarded by a peer store.
+	DefaultPeerTimeout = 100 * time.Millisecond
+
+	// DefaultPeerRetryInterval is the default time duration during which a peer store stops forwarding
+	// requests to a peer which has failed.
+	DefaultPeerRetryInterval = 5 * time.Second
+
+	// DefaultPenaltyMemory is the default time duration during which bans are remembered to grow the next one.
+	DefaultPenaltyMemory = 24 * time.Hour
+
+	// DefaultPenaltyCheckInterval is the default time duration during which an identifier found without ban in
+	// the store is not checked again.
+	DefaultPenaltyCheckInterval = time.Second
+
+	// DefaultLockoutDecay is the default time duration after a lockout during which its strike is remembered.
+	DefaultLockoutDecay = 24 * time.Hour
+
+	// DefaultRecordTimeout is the default timeout of the lockout middleware recording the outcome of a request.
+	DefaultRecordTimeout = 5 * time.Second
+
+	// DefaultAdaptiveInterval is the default minimum time duration between two adjustments of an adaptive limit.
+	DefaultAdaptiveInterval = time.Second
 )
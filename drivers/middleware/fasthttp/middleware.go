@@ -13,6 +13,8 @@ type Middleware struct {
 	OnLimitReached LimitReachedHandler
 	KeyGetter      KeyGetter
 	ExcludedKey    func(string) bool
+	Priority       *limiter.Priority
+	Classifier     Classifier
 }
 
 // NewMiddleware return a new instance of a fasthttp middleware.
@@ -41,6 +43,25 @@ func (middleware *Middleware) Handle(next fasthttp.RequestHandler) fasthttp.Requ
 			return
 		}
 
+		// The class of the request is checked first, so its key isn't counted if the class has no capacity left.
+		class := ""
+		if middleware.Priority != nil {
+			class = middleware.Classifier(ctx)
+			context, err := middleware.Priority.Peek(ctx, class)
+			if err != nil {
+				middleware.OnError(ctx, err)
+				return
+			}
+
+			if context.Reached {
+				ctx.Response.Header.Set("X-RateLimit-Class", class)
+				ctx.Response.Header.Set("X-RateLimit-Class-Limit", strconv.FormatInt(context.Limit, 10))
+				ctx.Response.Header.Set("X-RateLimit-Class-Remaining", strconv.FormatInt(context.Remaining, 10))
+				middleware.OnLimitReached(ctx)
+				return
+			}
+		}
+
 		context, err := middleware.Limiter.Get(ctx, key)
 		if err != nil {
 			middleware.OnError(ctx, err)
@@ -56,6 +77,23 @@ func (middleware *Middleware) Handle(next fasthttp.RequestHandler) fasthttp.Requ
 			return
 		}
 
+		if middleware.Priority != nil {
+			context, err = middleware.Priority.Get(ctx, class)
+			if err != nil {
+				middleware.OnError(ctx, err)
+				return
+			}
+
+			ctx.Response.Header.Set("X-RateLimit-Class", class)
+			ctx.Response.Header.Set("X-RateLimit-Class-Limit", strconv.FormatInt(context.Limit, 10))
+			ctx.Response.Header.Set("X-RateLimit-Class-Remaining", strconv.FormatInt(context.Remaining, 10))
+
+			if context.Reached {
+				middleware.OnLimitReached(ctx)
+				return
+			}
+		}
+
 		next(ctx)
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	libfasthttp "github.com/valyala/fasthttp"
@@ -179,6 +180,52 @@ func TestFasthttpMiddleware(t *testing.T) {
 	}
 }
 
+func TestFasthttpMiddlewarePriority(t *testing.T) {
+	is := require.New(t)
+
+	store := memory.NewStore()
+	priority, err := limiter.NewPriority(store, limiter.Rate{Limit: 4, Period: time.Minute},
+		limiter.PriorityClass{Name: "paid", Share: 0.5},
+		limiter.PriorityClass{Name: "free", Share: 0.5})
+	is.NoError(err)
+
+	middleware := fasthttp.NewMiddleware(limiter.New(store, limiter.Rate{Limit: 100, Period: time.Minute}),
+		fasthttp.WithPriority(priority, func(ctx *libfasthttp.RequestCtx) string {
+			return string(ctx.Request.Header.Peek("X-Plan"))
+		}))
+
+	handler := middleware.Handle(func(ctx *libfasthttp.RequestCtx) {
+		ctx.SetStatusCode(libfasthttp.StatusOK)
+	})
+
+	send := func(plan string) *libfasthttp.Response {
+		resp := libfasthttp.AcquireResponse()
+		req := libfasthttp.AcquireRequest()
+		req.Header.SetHost("localhost:8081")
+		req.Header.SetRequestURI("/")
+		req.Header.Set("X-Plan", plan)
+		is.NoError(serve(handler, req, resp))
+		return resp
+	}
+
+	resp := send("free")
+	is.Equal(libfasthttp.StatusOK, resp.StatusCode())
+	is.Equal("free", string(resp.Header.Peek("X-RateLimit-Class")))
+	is.Equal("2", string(resp.Header.Peek("X-RateLimit-Class-Limit")))
+	is.Equal("1", string(resp.Header.Peek("X-RateLimit-Class-Remaining")))
+
+	// Lower classes can't borrow the capacity of higher classes.
+	is.Equal(libfasthttp.StatusOK, send("free").StatusCode())
+	is.Equal(libfasthttp.StatusTooManyRequests, send("free").StatusCode())
+
+	// Requests rejected by their class aren't counted by the limiter.
+	resp = send("paid")
+	is.Equal(libfasthttp.StatusOK, resp.StatusCode())
+	is.Equal("97", string(resp.Header.Peek("X-RateLimit-Remaining")))
+	is.Equal(libfasthttp.StatusOK, send("paid").StatusCode())
+	is.Equal(libfasthttp.StatusTooManyRequests, send("paid").StatusCode())
+}
+
 func serve(handler libfasthttp.RequestHandler, req *libfasthttp.Request, res *libfasthttp.Response) error {
 	ln := fasthttputil.NewInmemoryListener()
 	defer func() {
@@ -0,0 +1,120 @@
+package fasthttp
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/ulule/limiter/v3/policy"
+)
+
+// PolicyMiddleware is the fasthttp middleware applying the routes of a policy.
+// Requests are limited by the first route they match, or by the default route of the policy if they don't
+// match any.
+type PolicyMiddleware struct {
+	Policy      *policy.Policy
+	middlewares map[*policy.Route]*Middleware
+}
+
+// NewPolicyMiddleware returns a new instance of a fasthttp middleware applying the routes of given policy.
+// Keys of the allowlist of the policy are not limited. Options are applied to every route.
+func NewPolicyMiddleware(p *policy.Policy, options ...Option) *PolicyMiddleware {
+	middlewares := make(map[*policy.Route]*Middleware, len(p.Routes)+1)
+	for i := range p.Routes {
+		middlewares[&p.Routes[i]] = newPolicyMiddleware(p, &p.Routes[i], options)
+	}
+	if p.Default != nil {
+		middlewares[p.Default] = newPolicyMiddleware(p, p.Default, options)
+	}
+
+	return &PolicyMiddleware{
+		Policy:      p,
+		middlewares: middlewares,
+	}
+}
+
+// Handle fasthttp request.
+func (middleware *PolicyMiddleware) Handle(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	handlers := make(map[*policy.Route]fasthttp.RequestHandler, len(middleware.middlewares))
+	for route, routeMiddleware := range middleware.middlewares {
+		handlers[route] = routeMiddleware.Handle(next)
+	}
+
+	return func(ctx *fasthttp.RequestCtx) {
+		route := middleware.Policy.Match(string(ctx.Method()), string(ctx.Path()))
+		if route == nil {
+			next(ctx)
+			return
+		}
+
+		handlers[route](ctx)
+	}
+}
+
+// newPolicyMiddleware returns the middleware of given route of a policy, whose keys are prefixed by its name.
+// Excluded keys are given without prefix.
+func newPolicyMiddleware(p *policy.Policy, route *policy.Route, options []Option) *Middleware {
+	keyGetter := policyKeyGetter(route)
+	prefix := route.Name + ":"
+
+	options = append(options[:len(options):len(options)], option(func(middleware *Middleware) {
+		middleware.KeyGetter = func(ctx *fasthttp.RequestCtx) string {
+			return prefix + keyGetter(ctx)
+		}
+
+		excluded := middleware.ExcludedKey
+		middleware.ExcludedKey = func(key string) bool {
+			key = strings.TrimPrefix(key, prefix)
+			return p.Excluded(key) || (excluded != nil && excluded(key))
+		}
+	}))
+
+	return NewMiddleware(route.Limiter, options...)
+}
+
+// policyKeyGetter returns the key getter of given route of a policy.
+func policyKeyGetter(route *policy.Route) KeyGetter {
+	name := route.Key.Name
+
+	switch route.Key.Source {
+	case policy.SourceHeader:
+		return func(ctx *fasthttp.RequestCtx) string {
+			return string(ctx.Request.Header.Peek(name))
+		}
+	case policy.SourceQuery:
+		return func(ctx *fasthttp.RequestCtx) string {
+			return string(ctx.QueryArgs().Peek(name))
+		}
+	case policy.SourceCookie:
+		return func(ctx *fasthttp.RequestCtx) string {
+			return string(ctx.Request.Header.Cookie(name))
+		}
+	default:
+		return ipKeyGetter(route)
+	}
+}
+
+// ipKeyGetter returns a key getter giving the client IP address, with the IP options of the limiter of given
+// route, so proxy headers and masks are handled like in other middlewares.
+func ipKeyGetter(route *policy.Route) KeyGetter {
+	headers := []string{"X-Forwarded-For", "X-Real-IP"}
+	if route.Limiter.Options.ClientIPHeader != "" {
+		headers = append(headers, route.Limiter.Options.ClientIPHeader)
+	}
+
+	return func(ctx *fasthttp.RequestCtx) string {
+		r := &http.Request{
+			RemoteAddr: ctx.RemoteAddr().String(),
+			Header:     http.Header{},
+		}
+		for _, header := range headers {
+			value := ctx.Request.Header.Peek(header)
+			if len(value) > 0 {
+				r.Header.Set(header, string(value))
+			}
+		}
+
+		return route.Limiter.GetIPKey(r)
+	}
+}
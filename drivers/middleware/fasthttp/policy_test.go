@@ -0,0 +1,68 @@
+package fasthttp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	libfasthttp "github.com/valyala/fasthttp"
+
+	"github.com/ulule/limiter/v3/drivers/middleware/fasthttp"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/policy"
+)
+
+func TestFasthttpPolicyMiddleware(t *testing.T) {
+	is := require.New(t)
+
+	p, err := policy.Load(strings.NewReader(`{
+		"trust_forward_header": true,
+		"ipv4_mask": 24,
+		"allowlist": ["internal"],
+		"default": {"rate": "3-M"},
+		"routes": [
+			{"name": "login", "method": "POST", "pattern": "/login", "rate": "1-M"},
+			{"pattern": "/api/", "rate": "2-M", "key": {"source": "header", "name": "X-API-Key"}},
+			{"pattern": "/search", "rate": "1-M", "key": {"source": "query", "name": "token"}},
+			{"pattern": "/account", "rate": "1-M", "key": {"source": "cookie", "name": "session"}}
+		]
+	}`), memory.NewStore())
+	is.NoError(err)
+
+	handler := fasthttp.NewPolicyMiddleware(p).Handle(func(ctx *libfasthttp.RequestCtx) {
+		ctx.SetStatusCode(libfasthttp.StatusOK)
+	})
+
+	// send sends given number of requests, and returns the number of successful ones.
+	send := func(count int, method string, uri string, headers ...string) int {
+		success := 0
+		for i := 0; i < count; i++ {
+			resp := libfasthttp.AcquireResponse()
+			req := libfasthttp.AcquireRequest()
+			req.Header.SetHost("localhost:8081")
+			req.Header.SetMethod(method)
+			req.Header.SetRequestURI(uri)
+			for j := 0; j+1 < len(headers); j += 2 {
+				req.Header.Set(headers[j], headers[j+1])
+			}
+			is.NoError(serve(handler, req, resp))
+			if resp.StatusCode() == libfasthttp.StatusOK {
+				success++
+			}
+		}
+		return success
+	}
+
+	is.Equal(1, send(3, "POST", "/login", "X-Real-IP", "192.168.1.1"))
+
+	// The client IP address is read from headers and masked, as configured by the policy.
+	is.Equal(0, send(1, "POST", "/login", "X-Real-IP", "192.168.1.2"))
+	is.Equal(1, send(1, "POST", "/login", "X-Real-IP", "192.168.2.1"))
+
+	is.Equal(3, send(4, "GET", "/login", "X-Real-IP", "192.168.1.1"))
+	is.Equal(2, send(3, "GET", "/api/orders", "X-API-Key", "foo"))
+	is.Equal(1, send(2, "GET", "/search?token=foo"))
+	is.Equal(1, send(2, "GET", "/account", "Cookie", "session=foo"))
+
+	is.Equal(5, send(5, "GET", "/api/orders", "X-API-Key", "internal"))
+}
@@ -15,6 +15,8 @@ type Middleware struct {
 	OnLimitReached LimitReachedHandler
 	KeyGetter      KeyGetter
 	ExcludedKey    func(string) bool
+	Priority       *limiter.Priority
+	Classifier     Classifier
 }
 
 // NewMiddleware return a new instance of a gin middleware.
@@ -44,6 +46,27 @@ func (middleware *Middleware) Handle(c *gin.Context) {
 		return
 	}
 
+	// The class of the request is checked first, so its key isn't counted if the class has no capacity left.
+	class := ""
+	if middleware.Priority != nil {
+		class = middleware.Classifier(c)
+		context, err := middleware.Priority.Peek(c, class)
+		if err != nil {
+			middleware.OnError(c, err)
+			c.Abort()
+			return
+		}
+
+		if context.Reached {
+			c.Header("X-RateLimit-Class", class)
+			c.Header("X-RateLimit-Class-Limit", strconv.FormatInt(context.Limit, 10))
+			c.Header("X-RateLimit-Class-Remaining", strconv.FormatInt(context.Remaining, 10))
+			middleware.OnLimitReached(c)
+			c.Abort()
+			return
+		}
+	}
+
 	context, err := middleware.Limiter.Get(c, key)
 	if err != nil {
 		middleware.OnError(c, err)
@@ -61,5 +84,24 @@ func (middleware *Middleware) Handle(c *gin.Context) {
 		return
 	}
 
+	if middleware.Priority != nil {
+		context, err = middleware.Priority.Get(c, class)
+		if err != nil {
+			middleware.OnError(c, err)
+			c.Abort()
+			return
+		}
+
+		c.Header("X-RateLimit-Class", class)
+		c.Header("X-RateLimit-Class-Limit", strconv.FormatInt(context.Limit, 10))
+		c.Header("X-RateLimit-Class-Remaining", strconv.FormatInt(context.Remaining, 10))
+
+		if context.Reached {
+			middleware.OnLimitReached(c)
+			c.Abort()
+			return
+		}
+	}
+
 	c.Next()
 }
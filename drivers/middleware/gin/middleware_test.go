@@ -7,6 +7,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	libgin "github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -159,3 +160,51 @@ func TestHTTPMiddleware(t *testing.T) {
 		}
 	}
 }
+
+func TestHTTPMiddlewarePriority(t *testing.T) {
+	is := require.New(t)
+	libgin.SetMode(libgin.TestMode)
+
+	store := memory.NewStore()
+	priority, err := limiter.NewPriority(store, limiter.Rate{Limit: 4, Period: time.Minute},
+		limiter.PriorityClass{Name: "paid", Share: 0.5},
+		limiter.PriorityClass{Name: "free", Share: 0.5})
+	is.NoError(err)
+
+	middleware := gin.NewMiddleware(limiter.New(store, limiter.Rate{Limit: 100, Period: time.Minute}),
+		gin.WithPriority(priority, func(c *libgin.Context) string {
+			return c.GetHeader("X-Plan")
+		}))
+
+	router := libgin.New()
+	router.Use(middleware)
+	router.GET("/", func(c *libgin.Context) {
+		c.String(http.StatusOK, "hello")
+	})
+
+	serve := func(plan string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header.Set("X-Plan", plan)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, request)
+		return resp
+	}
+
+	resp := serve("free")
+	is.Equal(http.StatusOK, resp.Code)
+	is.Equal("free", resp.Header().Get("X-RateLimit-Class"))
+	is.Equal("2", resp.Header().Get("X-RateLimit-Class-Limit"))
+	is.Equal("1", resp.Header().Get("X-RateLimit-Class-Remaining"))
+
+	// Lower classes can't borrow the capacity of higher classes.
+	is.Equal(http.StatusOK, serve("free").Code)
+	is.Equal(http.StatusTooManyRequests, serve("free").Code)
+
+	// Requests rejected by their class aren't counted by the limiter.
+	resp = serve("paid")
+	is.Equal(http.StatusOK, resp.Code)
+	is.Equal("97", resp.Header().Get("X-RateLimit-Remaining"))
+	is.Equal(http.StatusOK, serve("paid").Code)
+	is.Equal(http.StatusTooManyRequests, serve("paid").Code)
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/ulule/limiter/v3"
 )
 
 // Option is used to define Middleware configuration.
@@ -69,3 +71,16 @@ func WithExcludedKey(handler func(string) bool) Option {
 		middleware.ExcludedKey = handler
 	})
 }
+
+// Classifier returns the priority class of a request.
+type Classifier func(c *gin.Context) string
+
+// WithPriority will configure the Middleware to shed requests by priority class. A request is counted by its
+// class once its key is within its limit, and its key isn't counted if its class has no capacity left.
+// Each request is classified with given Classifier.
+func WithPriority(priority *limiter.Priority, classifier Classifier) Option {
+	return option(func(middleware *Middleware) {
+		middleware.Priority = priority
+		middleware.Classifier = classifier
+	})
+}
@@ -0,0 +1,78 @@
+package gin
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ulule/limiter/v3/policy"
+)
+
+// NewPolicyMiddleware returns a new instance of a gin middleware applying the routes of given policy.
+// Requests are limited by the first route they match, or by the default route of the policy if they don't
+// match any. Keys of the allowlist of the policy are not limited. Options are applied to every route.
+func NewPolicyMiddleware(p *policy.Policy, options ...Option) gin.HandlerFunc {
+	handlers := make(map[*policy.Route]gin.HandlerFunc, len(p.Routes)+1)
+	for i := range p.Routes {
+		handlers[&p.Routes[i]] = newPolicyHandler(p, &p.Routes[i], options)
+	}
+	if p.Default != nil {
+		handlers[p.Default] = newPolicyHandler(p, p.Default, options)
+	}
+
+	return func(c *gin.Context) {
+		route := p.Match(c.Request.Method, c.Request.URL.Path)
+		if route == nil {
+			c.Next()
+			return
+		}
+
+		handlers[route](c)
+	}
+}
+
+// newPolicyHandler returns the handler of given route of a policy, whose keys are prefixed by its name.
+// Excluded keys are given without prefix.
+func newPolicyHandler(p *policy.Policy, route *policy.Route, options []Option) gin.HandlerFunc {
+	keyGetter := policyKeyGetter(route)
+	prefix := route.Name + ":"
+
+	options = append(options[:len(options):len(options)], option(func(middleware *Middleware) {
+		middleware.KeyGetter = func(c *gin.Context) string {
+			return prefix + keyGetter(c)
+		}
+
+		excluded := middleware.ExcludedKey
+		middleware.ExcludedKey = func(key string) bool {
+			key = strings.TrimPrefix(key, prefix)
+			return p.Excluded(key) || (excluded != nil && excluded(key))
+		}
+	}))
+
+	return NewMiddleware(route.Limiter, options...)
+}
+
+// policyKeyGetter returns the key getter of given route of a policy.
+func policyKeyGetter(route *policy.Route) KeyGetter {
+	name := route.Key.Name
+
+	switch route.Key.Source {
+	case policy.SourceHeader:
+		return func(c *gin.Context) string {
+			return c.GetHeader(name)
+		}
+	case policy.SourceQuery:
+		return func(c *gin.Context) string {
+			return c.Query(name)
+		}
+	case policy.SourceCookie:
+		return func(c *gin.Context) string {
+			value, _ := c.Cookie(name)
+			return value
+		}
+	default:
+		return func(c *gin.Context) string {
+			return route.Limiter.GetIPKey(c.Request)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package gin_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	libgin "github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3/drivers/middleware/gin"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/policy"
+)
+
+func TestHTTPPolicyMiddleware(t *testing.T) {
+	is := require.New(t)
+	libgin.SetMode(libgin.TestMode)
+
+	p, err := policy.Load(strings.NewReader(`{
+		"trust_forward_header": true,
+		"allowlist": ["internal"],
+		"routes": [
+			{"name": "login", "method": "POST", "pattern": "/login", "rate": "1-M"},
+			{"pattern": "/api/", "rate": "2-M", "key": {"source": "header", "name": "X-API-Key"}},
+			{"pattern": "/search", "rate": "1-M", "key": {"source": "query", "name": "token"}},
+			{"pattern": "/account", "rate": "1-M", "key": {"source": "cookie", "name": "session"}}
+		]
+	}`), memory.NewStore())
+	is.NoError(err)
+
+	router := libgin.New()
+	router.Use(gin.NewPolicyMiddleware(p))
+	router.NoRoute(func(c *libgin.Context) {
+		c.String(http.StatusOK, "hello")
+	})
+
+	// serve sends given number of requests, and returns the number of successful ones.
+	serve := func(count int, request *http.Request) int {
+		success := 0
+		for i := 0; i < count; i++ {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, request)
+			if resp.Code == http.StatusOK {
+				success++
+			}
+		}
+		return success
+	}
+
+	request := httptest.NewRequest("POST", "/login", nil)
+	request.Header.Set("X-Real-IP", "192.168.1.1")
+	is.Equal(1, serve(3, request))
+
+	// The client IP address is read from headers, as configured by the policy.
+	request = httptest.NewRequest("POST", "/login", nil)
+	request.Header.Set("X-Real-IP", "192.168.1.2")
+	is.Equal(1, serve(3, request))
+
+	request = httptest.NewRequest("GET", "/api/orders", nil)
+	request.Header.Set("X-API-Key", "foo")
+	is.Equal(2, serve(3, request))
+
+	is.Equal(1, serve(2, httptest.NewRequest("GET", "/search?token=foo", nil)))
+
+	request = httptest.NewRequest("GET", "/account", nil)
+	request.AddCookie(&http.Cookie{Name: "session", Value: "foo"})
+	is.Equal(1, serve(2, request))
+
+	// Requests matching no route are not limited without a default route.
+	is.Equal(5, serve(5, httptest.NewRequest("GET", "/", nil)))
+
+	request = httptest.NewRequest("GET", "/api/orders", nil)
+	request.Header.Set("X-API-Key", "internal")
+	is.Equal(5, serve(5, request))
+}
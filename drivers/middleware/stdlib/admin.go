@@ -0,0 +1,331 @@
+package stdlib
+
+import (
+	"container/heap"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ulule/limiter/v3"
+)
+
+const (
+	// DefaultTopSize is the default number of identifiers returned by the "top" endpoint of AdminHandler.
+	DefaultTopSize = 10
+	// maxTopSize is the maximum number of identifiers returned by the "top" endpoint of AdminHandler.
+	maxTopSize = 1000
+	// maxAdminRequestSize is the maximum size of a request body received by AdminHandler.
+	maxAdminRequestSize = 64 * 1024
+)
+
+// Authorizer is a hook deciding whether a request can use AdminHandler.
+type Authorizer func(r *http.Request) bool
+
+// AdminHandler is an http.Handler allowing support staff to inspect and change the limits of a limiter.
+// It should be mounted with http.StripPrefix, and exposes the following endpoints, which respond in JSON:
+//
+//	GET    /keys/{key}       returns the limit of an identifier, without modification.
+//	DELETE /keys/{key}       resets the limit of an identifier.
+//	GET    /top              returns the identifiers with the most hits, if the store implements limiter.Scanner.
+//	                         Query parameters "prefix" and "size" filter the identifiers and limit their number.
+//	GET    /overrides        returns the overrides of the limiter.
+//	PUT    /overrides/{key}  overrides the rate of an identifier, with a body like {"rate": "100-M", "ttl": "1h"}.
+//	                         An empty or missing ttl means the override never expires.
+//	DELETE /overrides/{key}  removes the override of an identifier.
+//
+// Overrides endpoints require the limiter to be configured with limiter.WithOverrides.
+type AdminHandler struct {
+	Limiter *limiter.Limiter
+	// Authorize is called before every request. Requests are denied if it's nil.
+	Authorize Authorizer
+}
+
+// NewAdminHandler returns a new AdminHandler for given limiter, using given hook to authorize requests.
+func NewAdminHandler(limiter *limiter.Limiter, authorize Authorizer) *AdminHandler {
+	return &AdminHandler{
+		Limiter:   limiter,
+		Authorize: authorize,
+	}
+}
+
+// adminLimit is the JSON response of the "keys" endpoints.
+type adminLimit struct {
+	Key       string         `json:"key"`
+	Limit     int64          `json:"limit"`
+	Remaining int64          `json:"remaining"`
+	Reset     int64          `json:"reset"`
+	Reached   bool           `json:"reached"`
+	Override  *adminOverride `json:"override,omitempty"`
+}
+
+// adminEntry is an identifier in the JSON response of the "top" endpoint.
+type adminEntry struct {
+	Key        string `json:"key"`
+	Count      int64  `json:"count"`
+	Limit      int64  `json:"limit"`
+	Reached    bool   `json:"reached"`
+	Expiration int64  `json:"expiration,omitempty"`
+}
+
+// adminOverride is an override in the JSON responses of the handler.
+type adminOverride struct {
+	Key        string `json:"key,omitempty"`
+	Rate       string `json:"rate"`
+	Expiration int64  `json:"expiration,omitempty"`
+}
+
+// adminOverrideRequest is the JSON request of the "overrides" endpoint.
+type adminOverrideRequest struct {
+	Rate string `json:"rate"`
+	TTL  string `json:"ttl"`
+}
+
+// adminError is the JSON response of a failed request.
+type adminError struct {
+	Error string `json:"error"`
+}
+
+// ServeHTTP handles a request of support staff.
+func (handler *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler.Authorize == nil || !handler.Authorize(r) {
+		writeAdminError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+
+	switch {
+	case strings.HasPrefix(path, "keys/") && len(path) > len("keys/"):
+		handler.serveKey(w, r, strings.TrimPrefix(path, "keys/"))
+
+	case path == "top":
+		handler.serveTop(w, r)
+
+	case path == "overrides":
+		handler.serveOverrides(w, r)
+
+	case strings.HasPrefix(path, "overrides/") && len(path) > len("overrides/"):
+		handler.serveOverride(w, r, strings.TrimPrefix(path, "overrides/"))
+
+	default:
+		writeAdminError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// serveKey returns or resets the limit of given identifier.
+func (handler *AdminHandler) serveKey(w http.ResponseWriter, r *http.Request, key string) {
+	var (
+		context limiter.Context
+		err     error
+	)
+
+	switch r.Method {
+	case http.MethodGet:
+		context, err = handler.Limiter.Peek(r.Context(), key)
+	case http.MethodDelete:
+		context, err = handler.Limiter.Reset(r.Context(), key)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		return
+	}
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := adminLimit{
+		Key:       key,
+		Limit:     context.Limit,
+		Remaining: context.Remaining,
+		Reset:     context.Reset,
+		Reached:   context.Reached,
+	}
+
+	if handler.Limiter.Options.Overrides != nil {
+		override, ok := handler.Limiter.Options.Overrides.Get(key)
+		if ok {
+			response.Override = newAdminOverride("", override)
+		}
+	}
+
+	writeAdminResponse(w, http.StatusOK, response)
+}
+
+// serveTop returns the identifiers with the most hits.
+func (handler *AdminHandler) serveTop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	scanner, ok := handler.Limiter.Store.(limiter.Scanner)
+	if !ok {
+		writeAdminError(w, http.StatusNotImplemented, "store doesn't support scanning")
+		return
+	}
+
+	size := DefaultTopSize
+	if value := r.URL.Query().Get("size"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxTopSize {
+			writeAdminError(w, http.StatusBadRequest, "size must be between 1 and "+strconv.Itoa(maxTopSize))
+			return
+		}
+		size = parsed
+	}
+
+	top := &topEntries{}
+	err := scanner.Scan(r.Context(), r.URL.Query().Get("prefix"), func(entry limiter.Entry) bool {
+		if top.Len() < size {
+			heap.Push(top, entry)
+		} else if entry.Count > (*top)[0].Count {
+			(*top)[0] = entry
+			heap.Fix(top, 0)
+		}
+		return true
+	})
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]adminEntry, top.Len())
+	for i := len(response) - 1; i >= 0; i-- {
+		entry := heap.Pop(top).(limiter.Entry)
+		rate := handler.Limiter.GetRate(entry.Key)
+
+		response[i] = adminEntry{
+			Key:     entry.Key,
+			Count:   entry.Count,
+			Limit:   rate.Limit,
+			Reached: entry.Count > rate.Limit,
+		}
+		if !entry.Expiration.IsZero() {
+			response[i].Expiration = entry.Expiration.Unix()
+		}
+	}
+
+	writeAdminResponse(w, http.StatusOK, response)
+}
+
+// serveOverrides returns every override.
+func (handler *AdminHandler) serveOverrides(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	overrides := handler.Limiter.Options.Overrides
+	if overrides == nil {
+		writeAdminError(w, http.StatusNotImplemented, "limiter doesn't support overrides")
+		return
+	}
+
+	response := []*adminOverride{}
+	for key, override := range overrides.List() {
+		response = append(response, newAdminOverride(key, override))
+	}
+
+	writeAdminResponse(w, http.StatusOK, response)
+}
+
+// serveOverride sets or removes the override of given identifier.
+func (handler *AdminHandler) serveOverride(w http.ResponseWriter, r *http.Request, key string) {
+	overrides := handler.Limiter.Options.Overrides
+	if overrides == nil {
+		writeAdminError(w, http.StatusNotImplemented, "limiter doesn't support overrides")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		request := adminOverrideRequest{}
+		err := json.NewDecoder(io.LimitReader(r.Body, maxAdminRequestSize)).Decode(&request)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		rate, err := limiter.NewRateFromFormatted(request.Rate)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ttl := time.Duration(0)
+		if request.TTL != "" {
+			ttl, err = time.ParseDuration(request.TTL)
+			if err != nil || ttl <= 0 {
+				writeAdminError(w, http.StatusBadRequest, "ttl must be a positive duration, like \"1h\"")
+				return
+			}
+		}
+
+		override := overrides.Set(key, rate, ttl)
+		writeAdminResponse(w, http.StatusOK, newAdminOverride(key, override))
+
+	case http.MethodDelete:
+		overrides.Delete(key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeMethodNotAllowed(w, http.MethodPut, http.MethodDelete)
+	}
+}
+
+// newAdminOverride returns the JSON representation of given override.
+func newAdminOverride(key string, override limiter.Override) *adminOverride {
+	response := &adminOverride{
+		Key:  key,
+		Rate: override.Rate.Formatted,
+	}
+
+	if response.Rate == "" {
+		response.Rate = strconv.FormatInt(override.Rate.Limit, 10) + "/" + override.Rate.Period.String()
+	}
+
+	if !override.Expiration.IsZero() {
+		response.Expiration = override.Expiration.Unix()
+	}
+
+	return response
+}
+
+// writeAdminResponse writes given value as JSON, with given status code.
+func writeAdminResponse(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeAdminError writes given error message as JSON, with given status code.
+func writeAdminError(w http.ResponseWriter, status int, message string) {
+	writeAdminResponse(w, status, adminError{Error: message})
+}
+
+// writeMethodNotAllowed responds that the method is not allowed, with the allowed ones.
+func writeMethodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeAdminError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+// topEntries is a min-heap of entries by count, used to keep the entries with the most hits.
+type topEntries []limiter.Entry
+
+func (entries topEntries) Len() int           { return len(entries) }
+func (entries topEntries) Less(i, j int) bool { return entries[i].Count < entries[j].Count }
+func (entries topEntries) Swap(i, j int)      { entries[i], entries[j] = entries[j], entries[i] }
+
+func (entries *topEntries) Push(value interface{}) {
+	*entries = append(*entries, value.(limiter.Entry))
+}
+
+func (entries *topEntries) Pop() interface{} {
+	old := *entries
+	value := old[len(old)-1]
+	*entries = old[:len(old)-1]
+	return value
+}
@@ -0,0 +1,189 @@
+package stdlib_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/middleware/stdlib"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+)
+
+func TestAdminHandlerAuthorization(t *testing.T) {
+	is := require.New(t)
+
+	instance := limiter.New(memory.NewStore(), limiter.Rate{Limit: 10, Period: time.Minute})
+
+	// Requests are denied without hook.
+	code, _ := serveAdmin(stdlib.NewAdminHandler(instance, nil), "GET", "/keys/foo", "")
+	is.Equal(http.StatusForbidden, code)
+
+	handler := stdlib.NewAdminHandler(instance, func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer secret"
+	})
+
+	code, _ = serveAdmin(handler, "GET", "/keys/foo", "")
+	is.Equal(http.StatusForbidden, code)
+
+	request := httptest.NewRequest("GET", "/keys/foo", nil)
+	request.Header.Set("Authorization", "Bearer secret")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	is.Equal(http.StatusOK, recorder.Code)
+}
+
+func TestAdminHandlerKeys(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	instance := limiter.New(memory.NewStore(), limiter.Rate{Limit: 10, Period: time.Minute})
+	handler := stdlib.NewAdminHandler(instance, allowAll)
+
+	for i := 0; i < 3; i++ {
+		_, err := instance.Get(ctx, "10.0.0.1")
+		is.NoError(err)
+	}
+
+	code, body := serveAdmin(handler, "GET", "/keys/10.0.0.1", "")
+	is.Equal(http.StatusOK, code)
+	is.Equal("10.0.0.1", body["key"])
+	is.Equal(float64(10), body["limit"])
+	is.Equal(float64(7), body["remaining"])
+	is.Equal(false, body["reached"])
+	is.NotContains(body, "override")
+
+	// Peek doesn't change the limit.
+	lctx, err := instance.Peek(ctx, "10.0.0.1")
+	is.NoError(err)
+	is.Equal(int64(7), lctx.Remaining)
+
+	code, body = serveAdmin(handler, "DELETE", "/keys/10.0.0.1", "")
+	is.Equal(http.StatusOK, code)
+	is.Equal(float64(10), body["remaining"])
+
+	lctx, err = instance.Peek(ctx, "10.0.0.1")
+	is.NoError(err)
+	is.Equal(int64(10), lctx.Remaining)
+
+	code, _ = serveAdmin(handler, "POST", "/keys/10.0.0.1", "")
+	is.Equal(http.StatusMethodNotAllowed, code)
+
+	code, _ = serveAdmin(handler, "GET", "/keys/", "")
+	is.Equal(http.StatusNotFound, code)
+
+	code, _ = serveAdmin(handler, "GET", "/unknown", "")
+	is.Equal(http.StatusNotFound, code)
+}
+
+func TestAdminHandlerTop(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	instance := limiter.New(memory.NewStore(), limiter.Rate{Limit: 3, Period: time.Minute})
+	handler := stdlib.NewAdminHandler(instance, allowAll)
+
+	counts := map[string]int64{"a:1": 5, "a:2": 1, "a:3": 3, "b:1": 10}
+	for key, count := range counts {
+		_, err := instance.Increment(ctx, key, count)
+		is.NoError(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/top?prefix=a:&size=2", nil))
+	is.Equal(http.StatusOK, recorder.Code)
+
+	entries := []map[string]interface{}{}
+	is.NoError(json.Unmarshal(recorder.Body.Bytes(), &entries))
+	is.Len(entries, 2)
+	is.Equal("a:1", entries[0]["key"])
+	is.Equal(float64(5), entries[0]["count"])
+	is.Equal(true, entries[0]["reached"])
+	is.Equal("a:3", entries[1]["key"])
+	is.Equal(float64(3), entries[1]["count"])
+	is.Equal(false, entries[1]["reached"])
+	is.Greater(entries[1]["expiration"], float64(time.Now().Unix()))
+
+	code, _ := serveAdmin(handler, "GET", "/top?size=0", "")
+	is.Equal(http.StatusBadRequest, code)
+
+	// Stores which can't be scanned are not supported.
+	handler = stdlib.NewAdminHandler(limiter.New(struct{ limiter.Store }{memory.NewStore()}, instance.Rate), allowAll)
+	code, _ = serveAdmin(handler, "GET", "/top", "")
+	is.Equal(http.StatusNotImplemented, code)
+}
+
+func TestAdminHandlerOverrides(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	overrides := limiter.NewOverrides()
+	instance := limiter.New(memory.NewStore(), limiter.Rate{Limit: 1, Period: time.Minute},
+		limiter.WithOverrides(overrides))
+	handler := stdlib.NewAdminHandler(instance, allowAll)
+
+	code, body := serveAdmin(handler, "PUT", "/overrides/customer-42", `{"rate": "100-M", "ttl": "1h"}`)
+	is.Equal(http.StatusOK, code)
+	is.Equal("customer-42", body["key"])
+	is.Equal("100-M", body["rate"])
+	is.InDelta(float64(time.Now().Add(time.Hour).Unix()), body["expiration"], 5)
+
+	lctx, err := instance.Get(ctx, "customer-42")
+	is.NoError(err)
+	is.Equal(int64(100), lctx.Limit)
+
+	code, body = serveAdmin(handler, "GET", "/keys/customer-42", "")
+	is.Equal(http.StatusOK, code)
+	is.Equal(float64(99), body["remaining"])
+	is.Equal("100-M", body["override"].(map[string]interface{})["rate"])
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/overrides", nil))
+	is.Equal(http.StatusOK, recorder.Code)
+
+	list := []map[string]interface{}{}
+	is.NoError(json.Unmarshal(recorder.Body.Bytes(), &list))
+	is.Len(list, 1)
+	is.Equal("customer-42", list[0]["key"])
+	is.Equal("100-M", list[0]["rate"])
+
+	for _, payload := range []string{`{`, `{"rate": "100"}`, `{"rate": "100-M", "ttl": "soon"}`} {
+		code, _ = serveAdmin(handler, "PUT", "/overrides/customer-42", payload)
+		is.Equal(http.StatusBadRequest, code, payload)
+	}
+
+	code, _ = serveAdmin(handler, "DELETE", "/overrides/customer-42", "")
+	is.Equal(http.StatusNoContent, code)
+
+	lctx, err = instance.Get(ctx, "customer-42")
+	is.NoError(err)
+	is.Equal(int64(1), lctx.Limit)
+
+	// Overrides require a limiter configured with a registry.
+	handler = stdlib.NewAdminHandler(limiter.New(memory.NewStore(), instance.Rate), allowAll)
+	code, _ = serveAdmin(handler, "GET", "/overrides", "")
+	is.Equal(http.StatusNotImplemented, code)
+	code, _ = serveAdmin(handler, "PUT", "/overrides/customer-42", `{"rate": "100-M"}`)
+	is.Equal(http.StatusNotImplemented, code)
+}
+
+// allowAll authorizes every request.
+func allowAll(r *http.Request) bool {
+	return true
+}
+
+// serveAdmin sends a request to given handler, and returns its status code and JSON object body, if any.
+func serveAdmin(handler http.Handler, method string, target string, body string) (int, map[string]interface{}) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+	response := map[string]interface{}{}
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	return recorder.Code, response
+}
@@ -0,0 +1,195 @@
+package stdlib
+
+import (
+	"bufio"
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ulule/limiter/v3"
+)
+
+// UsernameGetter returns the username given by an authentication request.
+type UsernameGetter func(r *http.Request) string
+
+// RecordErrorHandler is an handler used to inform when an error has occurred once the response is sent.
+type RecordErrorHandler func(r *http.Request, err error)
+
+// LockoutMiddleware is the middleware protecting authentication handlers with a limiter.Lockout.
+// Requests are identified by their username and client IP: the response of the handler is a failure if
+// IsFailure returns true for its status code, and a success for any 2xx status code.
+type LockoutMiddleware struct {
+	Lockout        *limiter.Lockout
+	OnError        ErrorHandler
+	OnLockedOut    LimitReachedHandler
+	UsernameGetter UsernameGetter
+	// OnRecordError is called with the errors of the lockout while recording the outcome of a request, after
+	// the handler has responded. They're ignored if it's nil.
+	OnRecordError RecordErrorHandler
+	// RecordTimeout is the timeout of recording the outcome of a request, which isn't canceled with the request:
+	// a client closing its connection once the handler has responded is still counted. Default is 5 seconds.
+	RecordTimeout time.Duration
+	// IsFailure returns true if given status code is a failed authentication.
+	IsFailure func(status int) bool
+	// Options define how the client IP is obtained.
+	Options limiter.Options
+}
+
+// NewLockoutMiddleware returns a new instance of a lockout middleware, using given hook to get the username.
+func NewLockoutMiddleware(lockout *limiter.Lockout, username UsernameGetter) *LockoutMiddleware {
+	return &LockoutMiddleware{
+		Lockout:        lockout,
+		OnError:        DefaultErrorHandler,
+		OnLockedOut:    DefaultLimitReachedHandler,
+		UsernameGetter: username,
+		IsFailure:      DefaultIsFailure,
+		RecordTimeout:  limiter.DefaultRecordTimeout,
+		Options: limiter.Options{
+			IPv4Mask: limiter.DefaultIPv4Mask,
+			IPv6Mask: limiter.DefaultIPv6Mask,
+		},
+	}
+}
+
+// DefaultIsFailure is the default IsFailure used by a new LockoutMiddleware.
+// It considers 401 Unauthorized and 403 Forbidden responses as failures.
+func DefaultIsFailure(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+// Handler handles a HTTP request.
+// Errors of the lockout after the handler has responded are given to OnRecordError, since the response can't be
+// changed anymore. The outcome of a request whose connection is hijacked by the handler is not recorded.
+func (middleware *LockoutMiddleware) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := middleware.UsernameGetter(r) + ":" + limiter.GetIPWithMask(r, middleware.Options).String()
+
+		context, err := middleware.Lockout.Get(r.Context(), key)
+		if err != nil {
+			middleware.OnError(w, r, err)
+			return
+		}
+
+		if context.Lockout > 0 {
+			seconds := int64(math.Ceil(context.Lockout.Seconds()))
+			w.Header().Add("Retry-After", strconv.FormatInt(seconds, 10))
+			middleware.OnLockedOut(w, r)
+			return
+		}
+
+		writer := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(writer, r)
+
+		if writer.hijacked {
+			return
+		}
+
+		// An empty response has a 200 OK status code.
+		if writer.status == 0 {
+			writer.status = http.StatusOK
+		}
+
+		err = middleware.record(r, key, writer.status)
+		if err != nil && middleware.OnRecordError != nil {
+			middleware.OnRecordError(r, err)
+		}
+	})
+}
+
+// record records the outcome of a request from the status code of its response, with a context which isn't
+// canceled with the request.
+func (middleware *LockoutMiddleware) record(r *http.Request, key string, status int) error {
+	timeout := middleware.RecordTimeout
+	if timeout <= 0 {
+		timeout = limiter.DefaultRecordTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(detachedContext{Context: r.Context()}, timeout)
+	defer cancel()
+
+	switch {
+	case middleware.IsFailure(status):
+		_, err := middleware.Lockout.Fail(ctx, key)
+		return err
+	case status >= 200 && status < 300:
+		return middleware.Lockout.Succeed(ctx, key)
+	default:
+		return nil
+	}
+}
+
+// detachedContext is a context keeping the values of its parent, but neither its deadline nor its cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+// Deadline returns no deadline.
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done returns nil, since the context is never canceled.
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err returns nil, since the context is never canceled.
+func (detachedContext) Err() error {
+	return nil
+}
+
+// statusWriter is a http.ResponseWriter capturing the status code of the response.
+// It implements http.Flusher and http.Hijacker, using the underlying http.ResponseWriter.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	// hijacked is true if the connection has been taken over by the handler.
+	hijacked bool
+}
+
+// WriteHeader captures the status code, and sends the header of the response.
+func (writer *statusWriter) WriteHeader(status int) {
+	if writer.status == 0 {
+		writer.status = status
+	}
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes data of the response, which has a 200 OK status code if none was sent.
+func (writer *statusWriter) Write(data []byte) (int, error) {
+	if writer.status == 0 {
+		writer.status = http.StatusOK
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+// Flush sends buffered data to the client, if the underlying http.ResponseWriter supports it.
+func (writer *statusWriter) Flush() {
+	flusher, ok := writer.ResponseWriter.(http.Flusher)
+	if ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the handler take over the connection, if the underlying http.ResponseWriter supports it.
+func (writer *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := writer.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, buffer, err := hijacker.Hijack()
+	if err == nil {
+		writer.hijacked = true
+	}
+
+	return conn, buffer, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter, so http.ResponseController can reach its other features.
+func (writer *statusWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
@@ -0,0 +1,238 @@
+package stdlib_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/middleware/stdlib"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+)
+
+func TestLockoutMiddleware(t *testing.T) {
+	is := require.New(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("password") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("welcome"))
+	})
+
+	lockout := limiter.NewLockout(memory.NewStore(), limiter.Rate{Limit: 2, Period: time.Minute},
+		limiter.LockoutOptions{Durations: []time.Duration{time.Minute, time.Hour}})
+
+	middleware := stdlib.NewLockoutMiddleware(lockout, func(r *http.Request) string {
+		return r.FormValue("username")
+	}).Handler(handler)
+
+	login := func(username string, password string, ip string) *httptest.ResponseRecorder {
+		form := url.Values{"username": {username}, "password": {password}}
+		request := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.RemoteAddr = ip + ":1234"
+
+		recorder := httptest.NewRecorder()
+		middleware.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// A success resets failures.
+	is.Equal(http.StatusUnauthorized, login("alice", "guess", "10.0.0.1").Code)
+	is.Equal(http.StatusUnauthorized, login("alice", "guess", "10.0.0.1").Code)
+	is.Equal(http.StatusOK, login("alice", "secret", "10.0.0.1").Code)
+
+	for i := 0; i < 3; i++ {
+		is.Equal(http.StatusUnauthorized, login("alice", "guess", "10.0.0.1").Code)
+	}
+
+	// The handler is not called during a lockout, even with the right password.
+	recorder := login("alice", "secret", "10.0.0.1")
+	is.Equal(http.StatusTooManyRequests, recorder.Code)
+	is.Equal("60", recorder.Header().Get("Retry-After"))
+
+	// Lockouts are keyed by username and client IP.
+	is.Equal(http.StatusOK, login("alice", "secret", "10.0.0.2").Code)
+	is.Equal(http.StatusOK, login("bob", "secret", "10.0.0.1").Code)
+
+	_, err := lockout.Reset(context.Background(), "alice:10.0.0.1")
+	is.NoError(err)
+	is.Equal(http.StatusOK, login("alice", "secret", "10.0.0.1").Code)
+}
+
+func TestLockoutMiddlewareRecordError(t *testing.T) {
+	is := require.New(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	store := &failingStore{Store: memory.NewStore()}
+	lockout := limiter.NewLockout(store, limiter.Rate{Limit: 2, Period: time.Minute}, limiter.LockoutOptions{})
+
+	middleware := stdlib.NewLockoutMiddleware(lockout, func(r *http.Request) string {
+		return "alice"
+	})
+
+	// Errors once the handler has responded are ignored by default, instead of being given to OnError.
+	recorder := httptest.NewRecorder()
+	middleware.Handler(handler).ServeHTTP(recorder, httptest.NewRequest("POST", "/login", nil))
+	is.Equal(http.StatusUnauthorized, recorder.Code)
+
+	var recorded error
+	middleware.OnRecordError = func(r *http.Request, err error) {
+		recorded = err
+	}
+
+	recorder = httptest.NewRecorder()
+	middleware.Handler(handler).ServeHTTP(recorder, httptest.NewRequest("POST", "/login", nil))
+	is.Equal(http.StatusUnauthorized, recorder.Code)
+	is.Equal(errIncrement, recorded)
+}
+
+func TestLockoutMiddlewareResponseWriter(t *testing.T) {
+	is := require.New(t)
+
+	lockout := limiter.NewLockout(memory.NewStore(), limiter.Rate{Limit: 1, Period: time.Minute},
+		limiter.LockoutOptions{})
+
+	middleware := stdlib.NewLockoutMiddleware(lockout, func(r *http.Request) string {
+		return r.URL.Query().Get("username")
+	})
+
+	// Every recorded response is a failure.
+	middleware.IsFailure = func(status int) bool {
+		return true
+	}
+
+	flushed := false
+	server := httptest.NewServer(middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stream" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.(http.Flusher).Flush()
+			flushed = true
+			return
+		}
+
+		conn, buffer, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+
+		_, _ = buffer.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+		_ = buffer.Flush()
+	})))
+	defer server.Close()
+
+	// Flushed responses are still recorded.
+	res, err := http.Get(server.URL + "/stream?username=alice")
+	is.NoError(err)
+	is.NoError(res.Body.Close())
+	is.Equal(http.StatusUnauthorized, res.StatusCode)
+	is.True(flushed)
+
+	// Hijacked connections, like websockets, are not recorded.
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		is.NoError(err)
+
+		_, err = conn.Write([]byte("GET /socket?username=bob HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		is.NoError(err)
+
+		status, err := bufio.NewReader(conn).ReadString('\n')
+		is.NoError(err)
+		is.Equal("HTTP/1.1 101 Switching Protocols\r\n", status)
+		is.NoError(conn.Close())
+	}
+
+	lctx, err := lockout.Get(context.Background(), "alice:127.0.0.1")
+	is.NoError(err)
+	is.Equal(int64(0), lctx.Remaining)
+
+	lctx, err = lockout.Get(context.Background(), "bob:127.0.0.1")
+	is.NoError(err)
+	is.Equal(int64(1), lctx.Remaining)
+	is.Zero(lctx.Lockout)
+}
+
+func TestLockoutMiddlewareCanceled(t *testing.T) {
+	is := require.New(t)
+
+	lockout := limiter.NewLockout(&contextStore{Store: memory.NewStore()}, limiter.Rate{Limit: 2, Period: time.Minute},
+		limiter.LockoutOptions{Durations: []time.Duration{time.Minute}})
+
+	// The client disconnects as soon as the handler has responded.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		r.Context().Value(cancelKey{}).(context.CancelFunc)()
+	})
+
+	middleware := stdlib.NewLockoutMiddleware(lockout, func(r *http.Request) string {
+		return "alice"
+	})
+	middleware.OnRecordError = func(r *http.Request, err error) {
+		is.NoError(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	request := httptest.NewRequest("POST", "/login", nil)
+	request = request.WithContext(context.WithValue(ctx, cancelKey{}, context.CancelFunc(cancel)))
+	request.RemoteAddr = "10.0.0.1:1234"
+
+	recorder := httptest.NewRecorder()
+	middleware.Handler(handler).ServeHTTP(recorder, request)
+	is.Equal(http.StatusUnauthorized, recorder.Code)
+	is.Error(request.Context().Err())
+
+	lctx, err := lockout.Get(context.Background(), "alice:10.0.0.1")
+	is.NoError(err)
+	is.Equal(int64(1), lctx.Remaining)
+}
+
+// cancelKey is the key of the function canceling the context of a request.
+type cancelKey struct{}
+
+// contextStore is a store whose increments fail once their context is canceled, like a network store.
+type contextStore struct {
+	limiter.Store
+}
+
+// Increment returns the error of given context if it's done, or increments given identifier.
+func (store *contextStore) Increment(ctx context.Context, key string, count int64,
+	rate limiter.Rate) (limiter.Context, error) {
+
+	if ctx.Err() != nil {
+		return limiter.Context{}, ctx.Err()
+	}
+	return store.Store.Increment(ctx, key, count, rate)
+}
+
+// errIncrement is returned by failingStore.
+var errIncrement = errors.New("cannot increment")
+
+// failingStore is a store whose increments fail.
+type failingStore struct {
+	limiter.Store
+}
+
+// Increment returns errIncrement.
+func (store *failingStore) Increment(ctx context.Context, key string, count int64,
+	rate limiter.Rate) (limiter.Context, error) {
+
+	return limiter.Context{}, errIncrement
+}
@@ -3,6 +3,7 @@ package stdlib
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/ulule/limiter/v3"
 )
@@ -14,6 +15,8 @@ type Middleware struct {
 	OnLimitReached LimitReachedHandler
 	KeyGetter      KeyGetter
 	ExcludedKey    func(string) bool
+	Priority       *limiter.Priority
+	Classifier     Classifier
 }
 
 // NewMiddleware return a new instance of a basic HTTP middleware.
@@ -34,6 +37,9 @@ func NewMiddleware(limiter *limiter.Limiter, options ...Option) *Middleware {
 }
 
 // Handler handles a HTTP request.
+// If the limiter is configured with limiter.WithAdaptive, the latency and status code of the handler are given to
+// the limiter as a signal: a 5xx status code is an overload. No signal is given if the handler hijacks the
+// connection, like a websocket, since its latency is the lifetime of the connection.
 func (middleware *Middleware) Handler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		key := middleware.KeyGetter(r)
@@ -42,6 +48,25 @@ func (middleware *Middleware) Handler(h http.Handler) http.Handler {
 			return
 		}
 
+		// The class of the request is checked first, so its key isn't counted if the class has no capacity left.
+		class := ""
+		if middleware.Priority != nil {
+			class = middleware.Classifier(r)
+			context, err := middleware.Priority.Peek(r.Context(), class)
+			if err != nil {
+				middleware.OnError(w, r, err)
+				return
+			}
+
+			if context.Reached {
+				w.Header().Add("X-RateLimit-Class", class)
+				w.Header().Add("X-RateLimit-Class-Limit", strconv.FormatInt(context.Limit, 10))
+				w.Header().Add("X-RateLimit-Class-Remaining", strconv.FormatInt(context.Remaining, 10))
+				middleware.OnLimitReached(w, r)
+				return
+			}
+		}
+
 		context, err := middleware.Limiter.Get(r.Context(), key)
 		if err != nil {
 			middleware.OnError(w, r, err)
@@ -57,6 +82,39 @@ func (middleware *Middleware) Handler(h http.Handler) http.Handler {
 			return
 		}
 
-		h.ServeHTTP(w, r)
+		if middleware.Priority != nil {
+			context, err = middleware.Priority.Get(r.Context(), class)
+			if err != nil {
+				middleware.OnError(w, r, err)
+				return
+			}
+
+			w.Header().Add("X-RateLimit-Class", class)
+			w.Header().Add("X-RateLimit-Class-Limit", strconv.FormatInt(context.Limit, 10))
+			w.Header().Add("X-RateLimit-Class-Remaining", strconv.FormatInt(context.Remaining, 10))
+
+			if context.Reached {
+				middleware.OnLimitReached(w, r)
+				return
+			}
+		}
+
+		if middleware.Limiter.Options.Adaptive == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		writer := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(writer, r)
+
+		if writer.hijacked {
+			return
+		}
+
+		middleware.Limiter.Signal(limiter.Signal{
+			Latency:  time.Since(start),
+			Overload: writer.status >= http.StatusInternalServerError,
+		})
 	})
 }
@@ -1,11 +1,13 @@
 package stdlib_test
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -89,3 +91,121 @@ func TestHTTPMiddleware(t *testing.T) {
 	is.Equal(success, atomic.LoadInt64(&counter))
 
 }
+
+func TestHTTPMiddlewareAdaptive(t *testing.T) {
+	is := require.New(t)
+
+	status := int32(http.StatusOK)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	})
+
+	instance := limiter.New(memory.NewStore(), limiter.Rate{Limit: 8, Period: time.Minute},
+		limiter.WithAdaptive(limiter.Adaptive{
+			Min:      2,
+			Interval: time.Nanosecond,
+		}))
+	middleware := stdlib.NewMiddleware(instance).Handler(handler)
+
+	// serve sends a request from a new client, and returns the limit given by the response.
+	clients := 0
+	serve := func() string {
+		clients++
+		request := httptest.NewRequest("GET", "/", nil)
+		request.RemoteAddr = fmt.Sprintf("10.0.0.%d:1234", clients)
+
+		resp := httptest.NewRecorder()
+		middleware.ServeHTTP(resp, request)
+		is.Equal(int(atomic.LoadInt32(&status)), resp.Code)
+		return resp.Header().Get("X-RateLimit-Limit")
+	}
+
+	is.Equal("8", serve())
+	is.Equal("8", serve())
+
+	// Server errors decrease the limit.
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	is.Equal("8", serve())
+	time.Sleep(time.Millisecond)
+	is.Equal("4", serve())
+	time.Sleep(time.Millisecond)
+	is.Equal("2", serve())
+	time.Sleep(time.Millisecond)
+	is.Equal("2", serve())
+
+	// Successes increase it again.
+	atomic.StoreInt32(&status, http.StatusOK)
+	time.Sleep(time.Millisecond)
+	is.Equal("2", serve())
+	time.Sleep(time.Millisecond)
+	is.Equal("3", serve())
+}
+
+func TestHTTPMiddlewareAdaptiveResponseWriter(t *testing.T) {
+	is := require.New(t)
+
+	instance := limiter.New(memory.NewStore(), limiter.Rate{Limit: 8, Period: time.Minute},
+		limiter.WithAdaptive(limiter.Adaptive{Min: 2}))
+
+	// Streaming responses and hijacked connections, like websockets, use the underlying http.ResponseWriter.
+	var hijackErr error
+	middleware := stdlib.NewMiddleware(instance).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		is.True(ok)
+		flusher.Flush()
+
+		hijacker, ok := w.(http.Hijacker)
+		is.True(ok)
+		_, _, hijackErr = hijacker.Hijack()
+	}))
+
+	resp := httptest.NewRecorder()
+	middleware.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+	is.True(resp.Flushed)
+	is.Equal(http.ErrNotSupported, hijackErr)
+}
+
+func TestHTTPMiddlewarePriority(t *testing.T) {
+	is := require.New(t)
+
+	store := memory.NewStore()
+	priority, err := limiter.NewPriority(store, limiter.Rate{Limit: 4, Period: time.Minute},
+		limiter.PriorityClass{Name: "paid", Share: 0.5},
+		limiter.PriorityClass{Name: "free", Share: 0.5})
+	is.NoError(err)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	middleware := stdlib.NewMiddleware(limiter.New(store, limiter.Rate{Limit: 100, Period: time.Minute}),
+		stdlib.WithPriority(priority, func(r *http.Request) string {
+			return r.Header.Get("X-Plan")
+		})).Handler(handler)
+
+	serve := func(plan string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header.Set("X-Plan", plan)
+
+		resp := httptest.NewRecorder()
+		middleware.ServeHTTP(resp, request)
+		return resp
+	}
+
+	resp := serve("free")
+	is.Equal(http.StatusOK, resp.Code)
+	is.Equal("free", resp.Header().Get("X-RateLimit-Class"))
+	is.Equal("2", resp.Header().Get("X-RateLimit-Class-Limit"))
+	is.Equal("1", resp.Header().Get("X-RateLimit-Class-Remaining"))
+
+	// Lower classes can't borrow the capacity of higher classes.
+	is.Equal(http.StatusOK, serve("free").Code)
+	is.Equal(http.StatusTooManyRequests, serve("free").Code)
+
+	// Requests rejected by their class aren't counted by the limiter.
+	resp = serve("paid")
+	is.Equal(http.StatusOK, resp.Code)
+	is.Equal("97", resp.Header().Get("X-RateLimit-Remaining"))
+	is.Equal(http.StatusOK, serve("paid").Code)
+	is.Equal(http.StatusTooManyRequests, serve("paid").Code)
+}
@@ -0,0 +1,73 @@
+package stdlib
+
+import (
+	"net/http"
+
+	"github.com/ulule/limiter/v3/policy"
+)
+
+// NewPolicyMiddleware returns a new instance of a HTTP middleware applying the routes of given policy.
+// Keys of the allowlist of the policy are not limited. Options are applied to every route.
+func NewPolicyMiddleware(p *policy.Policy, options ...Option) (*RulesMiddleware, error) {
+	rules := make([]Rule, len(p.Routes))
+	for i := range p.Routes {
+		rules[i] = newPolicyRule(&p.Routes[i])
+	}
+
+	var fallback *Rule
+	if p.Default != nil {
+		rule := newPolicyRule(p.Default)
+		fallback = &rule
+	}
+
+	options = append(options[:len(options):len(options)], withAllowlist(p.Excluded))
+
+	return NewRulesMiddleware(rules, fallback, options...)
+}
+
+// newPolicyRule returns the rule of given route of a policy.
+func newPolicyRule(route *policy.Route) Rule {
+	return Rule{
+		Name:      route.Name,
+		Method:    route.Method,
+		Pattern:   route.Pattern,
+		Limiter:   route.Limiter,
+		KeyGetter: policyKeyGetter(route),
+	}
+}
+
+// policyKeyGetter returns the key getter of given route of a policy.
+func policyKeyGetter(route *policy.Route) KeyGetter {
+	name := route.Key.Name
+
+	switch route.Key.Source {
+	case policy.SourceHeader:
+		return func(r *http.Request) string {
+			return r.Header.Get(name)
+		}
+	case policy.SourceQuery:
+		return func(r *http.Request) string {
+			return r.URL.Query().Get(name)
+		}
+	case policy.SourceCookie:
+		return func(r *http.Request) string {
+			cookie, err := r.Cookie(name)
+			if err != nil {
+				return ""
+			}
+			return cookie.Value
+		}
+	default:
+		return DefaultKeyGetter(route.Limiter)
+	}
+}
+
+// withAllowlist excludes the keys of an allowlist, in addition to the keys already excluded by the middleware.
+func withAllowlist(allowed func(string) bool) Option {
+	return option(func(middleware *Middleware) {
+		excluded := middleware.ExcludedKey
+		middleware.ExcludedKey = func(key string) bool {
+			return allowed(key) || (excluded != nil && excluded(key))
+		}
+	})
+}
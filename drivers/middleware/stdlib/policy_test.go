@@ -0,0 +1,98 @@
+package stdlib_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3/drivers/middleware/stdlib"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/policy"
+)
+
+func TestPolicyMiddleware(t *testing.T) {
+	is := require.New(t)
+
+	p, err := policy.Load(strings.NewReader(`{
+		"allowlist": ["10.0.0.0/8", "internal"],
+		"default": {"rate": "3-M"},
+		"routes": [
+			{"name": "login", "method": "POST", "pattern": "/login", "rate": "1-M"},
+			{
+				"pattern": "/api/",
+				"rate": "2-M",
+				"key": {"source": "header", "name": "X-API-Key"},
+				"overrides": {"premium": "4-M"}
+			},
+			{"pattern": "/search", "rate": "1-M", "key": {"source": "query", "name": "token"}},
+			{"pattern": "/account", "rate": "1-M", "key": {"source": "cookie", "name": "session"}}
+		]
+	}`), memory.NewStore())
+	is.NoError(err)
+
+	middleware, err := stdlib.NewPolicyMiddleware(p)
+	is.NoError(err)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	// serve sends given number of requests, and returns the number of successful ones and the last limit.
+	serve := func(count int, request *http.Request) (int, string) {
+		success := 0
+		limit := ""
+		for i := 0; i < count; i++ {
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, request)
+			if resp.Code == http.StatusOK {
+				success++
+			}
+			limit = resp.Header().Get("X-RateLimit-Limit")
+		}
+		return success, limit
+	}
+
+	success, limit := serve(3, httptest.NewRequest("POST", "/login", nil))
+	is.Equal(1, success)
+	is.Equal("1", limit)
+
+	success, limit = serve(4, httptest.NewRequest("GET", "/login", nil))
+	is.Equal(3, success)
+	is.Equal("3", limit)
+
+	request := httptest.NewRequest("GET", "/api/orders", nil)
+	request.Header.Set("X-API-Key", "foo")
+	success, _ = serve(3, request)
+	is.Equal(2, success)
+
+	request = httptest.NewRequest("GET", "/api/orders", nil)
+	request.Header.Set("X-API-Key", "premium")
+	success, limit = serve(5, request)
+	is.Equal(4, success)
+	is.Equal("4", limit)
+
+	success, _ = serve(2, httptest.NewRequest("GET", "/search?token=foo", nil))
+	is.Equal(1, success)
+	success, _ = serve(1, httptest.NewRequest("GET", "/search?token=bar", nil))
+	is.Equal(1, success)
+
+	request = httptest.NewRequest("GET", "/account", nil)
+	request.AddCookie(&http.Cookie{Name: "session", Value: "foo"})
+	success, _ = serve(2, request)
+	is.Equal(1, success)
+
+	// Keys and IP ranges of the allowlist are not limited.
+	request = httptest.NewRequest("GET", "/api/orders", nil)
+	request.Header.Set("X-API-Key", "internal")
+	success, limit = serve(5, request)
+	is.Equal(5, success)
+	is.Empty(limit)
+
+	request = httptest.NewRequest("POST", "/login", nil)
+	request.RemoteAddr = "10.1.2.3:1234"
+	success, _ = serve(5, request)
+	is.Equal(5, success)
+}
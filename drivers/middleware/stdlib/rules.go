@@ -0,0 +1,142 @@
+package stdlib
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/internal/pattern"
+)
+
+// Rule applies a limiter to the requests matching a method and a path pattern.
+//
+// A pattern ending with a slash matches every path starting with it, like "/api/", and a pattern with
+// wildcards is matched with path.Match, like "/users/*/avatar". Other patterns match a single path.
+type Rule struct {
+	// Name prefixes the keys of the rule, so rules sharing a store don't share their limits.
+	// If empty, the method and the pattern are used.
+	Name string
+	// Method matched by the rule. If empty, every method is matched.
+	Method string
+	// Pattern matched by the path of the request.
+	Pattern string
+	// Limiter used for the requests matching the rule.
+	Limiter *limiter.Limiter
+	// KeyGetter defines the key of a request. If nil, the client IP address is used.
+	KeyGetter KeyGetter
+}
+
+// RulesMiddleware is the middleware applying a different limiter to each endpoint of a http.Handler.
+// Requests are limited by the first rule they match, or by the default rule if they don't match any.
+type RulesMiddleware struct {
+	Rules   []Rule
+	Default *Rule
+	options []Option
+}
+
+// NewRulesMiddleware returns a new instance of a HTTP middleware applying given rules, with given default rule.
+// Requests matching no rule are not limited if the default rule is nil. Options are applied to every rule.
+func NewRulesMiddleware(rules []Rule, fallback *Rule, options ...Option) (*RulesMiddleware, error) {
+	for i := range rules {
+		err := validateRule(rules[i], false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid rule %d", i)
+		}
+	}
+
+	if fallback != nil {
+		err := validateRule(*fallback, true)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid default rule")
+		}
+	}
+
+	return &RulesMiddleware{
+		Rules:   rules,
+		Default: fallback,
+		options: options,
+	}, nil
+}
+
+// Handler handles a HTTP request.
+func (middleware *RulesMiddleware) Handler(h http.Handler) http.Handler {
+	handlers := make([]http.Handler, len(middleware.Rules))
+	for i := range middleware.Rules {
+		handlers[i] = middleware.newRuleMiddleware(middleware.Rules[i], "").Handler(h)
+	}
+
+	fallback := h
+	if middleware.Default != nil {
+		fallback = middleware.newRuleMiddleware(*middleware.Default, "default").Handler(h)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := range middleware.Rules {
+			if middleware.Rules[i].match(r) {
+				handlers[i].ServeHTTP(w, r)
+				return
+			}
+		}
+
+		fallback.ServeHTTP(w, r)
+	})
+}
+
+// newRuleMiddleware returns the middleware of given rule, whose keys are prefixed by its name, or given name.
+func (middleware *RulesMiddleware) newRuleMiddleware(rule Rule, name string) *Middleware {
+	options := append([]Option{}, middleware.options...)
+
+	keyGetter := rule.KeyGetter
+	if keyGetter == nil {
+		keyGetter = DefaultKeyGetter(rule.Limiter)
+	}
+
+	if rule.Name != "" {
+		name = rule.Name
+	}
+	if name == "" {
+		name = strings.TrimSpace(rule.Method + " " + rule.Pattern)
+	}
+
+	prefix := name + ":"
+	options = append(options, WithKeyGetter(func(r *http.Request) string {
+		return prefix + keyGetter(r)
+	}))
+
+	ruleMiddleware := NewMiddleware(rule.Limiter, options...)
+
+	// Excluded keys are given without prefix.
+	excluded := ruleMiddleware.ExcludedKey
+	if excluded != nil {
+		ruleMiddleware.ExcludedKey = func(key string) bool {
+			return excluded(strings.TrimPrefix(key, prefix))
+		}
+	}
+
+	return ruleMiddleware
+}
+
+// match returns true if given request matches the method and the pattern of the rule.
+func (rule Rule) match(r *http.Request) bool {
+	if rule.Method != "" && rule.Method != r.Method {
+		return false
+	}
+
+	return pattern.Match(rule.Pattern, r.URL.Path)
+}
+
+// validateRule returns an error if given rule has no limiter or an invalid pattern.
+// A default rule has no pattern.
+func validateRule(rule Rule, fallback bool) error {
+	if rule.Limiter == nil {
+		return errors.New("limiter is required")
+	}
+
+	if fallback {
+		return nil
+	}
+
+	return pattern.Validate(rule.Pattern)
+}
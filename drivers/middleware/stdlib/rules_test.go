@@ -0,0 +1,118 @@
+package stdlib_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/middleware/stdlib"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+)
+
+func TestRulesMiddleware(t *testing.T) {
+	is := require.New(t)
+
+	store := memory.NewStore()
+	newLimiter := func(limit int64) *limiter.Limiter {
+		return limiter.New(store, limiter.Rate{Limit: limit, Period: time.Minute})
+	}
+
+	middleware, err := stdlib.NewRulesMiddleware([]stdlib.Rule{
+		{Method: "POST", Pattern: "/login", Limiter: newLimiter(1)},
+		{Pattern: "/users/*/avatar", Limiter: newLimiter(2)},
+		{Name: "api", Pattern: "/api/", Limiter: newLimiter(3), KeyGetter: func(r *http.Request) string {
+			return r.Header.Get("X-API-Key")
+		}},
+	}, &stdlib.Rule{Limiter: newLimiter(4)}, stdlib.WithExcludedKey(func(key string) bool {
+		return key == "internal"
+	}))
+	is.NoError(err)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	// serve sends given number of requests, and returns the number of successful ones and the last limit.
+	serve := func(count int, method string, target string, apiKey string) (int, string) {
+		success := 0
+		limit := ""
+		for i := 0; i < count; i++ {
+			request := httptest.NewRequest(method, target, nil)
+			request.Header.Set("X-API-Key", apiKey)
+
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, request)
+			if resp.Code == http.StatusOK {
+				success++
+			}
+			limit = resp.Header().Get("X-RateLimit-Limit")
+		}
+		return success, limit
+	}
+
+	success, limit := serve(3, "POST", "/login", "")
+	is.Equal(1, success)
+	is.Equal("1", limit)
+
+	// Rules don't share their limits, and unmatched requests use the default rule.
+	success, limit = serve(5, "GET", "/login", "")
+	is.Equal(4, success)
+	is.Equal("4", limit)
+
+	success, _ = serve(3, "GET", "/users/42/avatar", "")
+	is.Equal(2, success)
+
+	// Wildcards don't match slashes.
+	_, limit = serve(1, "GET", "/users/42/avatar/large", "")
+	is.Equal("4", limit)
+
+	// Each rule uses its key getter.
+	success, limit = serve(4, "GET", "/api/orders", "foo")
+	is.Equal(3, success)
+	is.Equal("3", limit)
+
+	success, _ = serve(1, "DELETE", "/api/orders/1", "bar")
+	is.Equal(1, success)
+
+	// Excluded keys are not limited.
+	success, limit = serve(5, "GET", "/api/orders", "internal")
+	is.Equal(5, success)
+	is.Empty(limit)
+}
+
+func TestRulesMiddlewareWithoutDefault(t *testing.T) {
+	is := require.New(t)
+
+	instance := limiter.New(memory.NewStore(), limiter.Rate{Limit: 1, Period: time.Minute})
+
+	middleware, err := stdlib.NewRulesMiddleware([]stdlib.Rule{
+		{Pattern: "/login", Limiter: instance},
+	}, nil)
+	is.NoError(err)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for i := 0; i < 3; i++ {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+		is.Equal(http.StatusNoContent, resp.Code)
+	}
+
+	_, err = stdlib.NewRulesMiddleware([]stdlib.Rule{{Pattern: "/login"}}, nil)
+	is.EqualError(err, "invalid rule 0: limiter is required")
+
+	_, err = stdlib.NewRulesMiddleware([]stdlib.Rule{{Pattern: "login", Limiter: instance}}, nil)
+	is.EqualError(err, "invalid rule 0: pattern 'login' must start with a slash")
+
+	_, err = stdlib.NewRulesMiddleware([]stdlib.Rule{{Pattern: "/users/[", Limiter: instance}}, nil)
+	is.EqualError(err, "invalid rule 0: pattern '/users/[' is malformed")
+
+	_, err = stdlib.NewRulesMiddleware(nil, &stdlib.Rule{})
+	is.EqualError(err, "invalid default rule: limiter is required")
+}
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter/v3"
+)
+
+// CheckIncrementAll returns an error if given identifiers of limiter.AtomicStore.IncrementAll don't have a rate
+// each, or if an identifier is given twice.
+func CheckIncrementAll(keys []string, rates []limiter.Rate) error {
+	if len(keys) != len(rates) {
+		return errors.Errorf("%d rates were expected, got %d", len(keys), len(rates))
+	}
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			return errors.Errorf("identifier '%s' is given twice", key)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
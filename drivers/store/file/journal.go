@@ -0,0 +1,236 @@
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// journalVersion is the version of the journal format.
+const journalVersion = 1
+
+// journalHeader is the first line of a journal.
+type journalHeader struct {
+	Version int `json:"version"`
+}
+
+// record is a line of a journal: the state of a counter after an operation.
+// Replaying records in order, the last record of a key gives its current state.
+type record struct {
+	Key   string `json:"key"`
+	Value int64  `json:"value"`
+	// Expiration is a wall clock time, in nanoseconds since epoch. Zero means that the counter has been deleted.
+	Expiration int64 `json:"expiration"`
+}
+
+// counter is a counter with an expiration.
+type counter struct {
+	value      int64
+	expiration time.Time
+}
+
+// expired returns true if the counter has expired at given time.
+func (counter counter) expired(now time.Time) bool {
+	return now.After(counter.expiration)
+}
+
+// journal is an append-only log file of records.
+type journal struct {
+	path string
+	file *os.File
+}
+
+// openJournal opens the journal at given path, creating it if required, and returns the counters it contains.
+// A partial record at the end of the journal, left by an interrupted write, is discarded.
+func openJournal(path string) (*journal, map[string]counter, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot open journal")
+	}
+
+	counters, size, err := replay(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	// Remove any partial record, then append new records after the last complete one.
+	err = file.Truncate(size)
+	if err != nil {
+		file.Close()
+		return nil, nil, errors.Wrap(err, "cannot truncate journal")
+	}
+
+	_, err = file.Seek(size, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return nil, nil, errors.Wrap(err, "cannot seek journal")
+	}
+
+	journal := &journal{
+		path: path,
+		file: file,
+	}
+
+	if size == 0 {
+		err = writeHeader(file)
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+	}
+
+	return journal, counters, nil
+}
+
+// replay reads every record of given journal and returns the counters it contains,
+// with the size of its complete records.
+func replay(reader io.Reader) (map[string]counter, int64, error) {
+	counters := map[string]counter{}
+	buffer := bufio.NewReader(reader)
+	size := int64(0)
+
+	for line := 0; ; line++ {
+		data, err := buffer.ReadBytes('\n')
+		if err == io.EOF {
+			// A line without a trailing newline is a partial record.
+			return counters, size, nil
+		}
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "cannot read journal")
+		}
+
+		if line == 0 {
+			header := journalHeader{}
+			err = json.Unmarshal(data, &header)
+			if err != nil {
+				return nil, 0, errors.Wrap(err, "cannot read journal header")
+			}
+			if header.Version != journalVersion {
+				return nil, 0, errors.Errorf("unsupported journal version: %d", header.Version)
+			}
+		} else {
+			value := record{}
+			err = json.Unmarshal(data, &value)
+			if err != nil {
+				return nil, 0, errors.Wrapf(err, "cannot read journal record at line %d", line+1)
+			}
+
+			if value.Expiration == 0 {
+				delete(counters, value.Key)
+			} else {
+				counters[value.Key] = counter{
+					value:      value.Value,
+					expiration: time.Unix(0, value.Expiration),
+				}
+			}
+		}
+
+		size += int64(len(data))
+	}
+}
+
+// writeHeader writes the journal header to given file.
+func writeHeader(file *os.File) error {
+	data, err := json.Marshal(journalHeader{Version: journalVersion})
+	if err != nil {
+		return errors.Wrap(err, "cannot write journal header")
+	}
+
+	_, err = file.Write(append(data, '\n'))
+	if err != nil {
+		return errors.Wrap(err, "cannot write journal header")
+	}
+
+	return nil
+}
+
+// append writes the state of given counter to the journal.
+func (journal *journal) append(key string, counter counter) error {
+	expiration := int64(0)
+	if !counter.expiration.IsZero() {
+		expiration = counter.expiration.UnixNano()
+	}
+
+	data, err := json.Marshal(record{
+		Key:        key,
+		Value:      counter.value,
+		Expiration: expiration,
+	})
+	if err != nil {
+		return errors.Wrap(err, "cannot write journal record")
+	}
+
+	_, err = journal.file.Write(append(data, '\n'))
+	if err != nil {
+		return errors.Wrap(err, "cannot write journal record")
+	}
+
+	return nil
+}
+
+// compact replaces the journal with a new one, which only contains given counters.
+func (journal *journal) compact(counters map[string]counter) error {
+	file, err := os.CreateTemp(filepath.Dir(journal.path), filepath.Base(journal.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "cannot create journal")
+	}
+	defer os.Remove(file.Name())
+
+	err = writeHeader(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	buffer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(buffer)
+	for key, counter := range counters {
+		err = encoder.Encode(record{
+			Key:        key,
+			Value:      counter.value,
+			Expiration: counter.expiration.UnixNano(),
+		})
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = buffer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		file.Close()
+		return errors.Wrap(err, "cannot write journal")
+	}
+
+	err = os.Rename(file.Name(), journal.path)
+	if err != nil {
+		file.Close()
+		return errors.Wrap(err, "cannot replace journal")
+	}
+
+	// The previous journal is now unlinked: new records are appended to the compacted one.
+	_ = journal.file.Close()
+	journal.file = file
+
+	return nil
+}
+
+// close flushes the journal to disk and closes it.
+func (journal *journal) close() error {
+	err := journal.file.Sync()
+	if err != nil {
+		journal.file.Close()
+		return errors.Wrap(err, "cannot sync journal")
+	}
+
+	return journal.file.Close()
+}
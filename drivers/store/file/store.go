@@ -0,0 +1,242 @@
+package file
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/common"
+)
+
+// Store is a single-node persistent store: it keeps counters in memory and journals every change to an
+// append-only log file, which is replayed on startup so that counters survive restarts.
+//
+// The journal is compacted in the background every CleanUpInterval, to drop expired counters.
+// Records are written to the operating system on every change, and flushed to disk on compaction and Close.
+type Store struct {
+	// Prefix used for the key.
+	Prefix string
+	// log contains the counters and their journal.
+	log *log
+}
+
+// log is a collection of counters journaled to a file.
+type log struct {
+	// mutex is used to keep the journal in the same order as the changes of counters.
+	mutex    sync.Mutex
+	counters map[string]counter
+	journal  *journal
+	clock    limiter.Clock
+	cleaner  *cleaner
+}
+
+// NewStore returns an instance of file store with defaults, journaled to given path.
+func NewStore(path string) (limiter.Store, error) {
+	return NewStoreWithOptions(path, limiter.StoreOptions{
+		Prefix:          limiter.DefaultPrefix,
+		CleanUpInterval: limiter.DefaultCleanUpInterval,
+	})
+}
+
+// NewStoreWithOptions returns an instance of file store with options, journaled to given path.
+// The journal is created if required, or replayed otherwise.
+func NewStoreWithOptions(path string, options limiter.StoreOptions) (limiter.Store, error) {
+	journal, counters, err := openJournal(path)
+	if err != nil {
+		return nil, err
+	}
+
+	log := &log{
+		counters: counters,
+		journal:  journal,
+		clock:    options.Clock,
+	}
+
+	if log.clock == nil {
+		log.clock = limiter.SystemClock
+	}
+
+	store := &Store{
+		Prefix: options.Prefix,
+		log:    log,
+	}
+
+	if options.CleanUpInterval > 0 {
+		startCleaner(log, options.CleanUpInterval)
+		runtime.SetFinalizer(store, stopCleaner)
+	}
+
+	return store, nil
+}
+
+// Get returns the limit for given identifier.
+func (store *Store) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return store.Increment(ctx, key, 1, rate)
+}
+
+// Increment increments the limit by given count & returns the new limit value for given identifier.
+func (store *Store) Increment(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
+	now, value, err := store.log.increment(store.getCacheKey(key), count, rate.Period)
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	return common.GetContextFromState(now, rate, value.expiration, value.value), nil
+}
+
+// Peek returns the limit for given identifier, without modification on current values.
+func (store *Store) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	now, value := store.log.get(store.getCacheKey(key), rate.Period)
+
+	return common.GetContextFromState(now, rate, value.expiration, value.value), nil
+}
+
+// Reset returns the limit for given identifier which is set to zero.
+func (store *Store) Reset(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	now, err := store.log.reset(store.getCacheKey(key))
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	return common.GetContextFromState(now, rate, now.Add(rate.Period), 0), nil
+}
+
+// Compact rewrites the journal with the counters which have not expired yet.
+func (store *Store) Compact() error {
+	return store.log.compact()
+}
+
+// Close stops the background compaction, and flushes the journal to disk before closing it.
+// The store must not be used after Close.
+func (store *Store) Close() error {
+	if store.log.cleaner != nil {
+		runtime.SetFinalizer(store, nil)
+		stopCleaner(store)
+	}
+
+	store.log.mutex.Lock()
+	defer store.log.mutex.Unlock()
+
+	return store.log.journal.close()
+}
+
+// getCacheKey returns the full path for an identifier.
+func (store *Store) getCacheKey(key string) string {
+	buffer := strings.Builder{}
+	buffer.WriteString(store.Prefix)
+	buffer.WriteString(":")
+	buffer.WriteString(key)
+	return buffer.String()
+}
+
+// increment increments given value on key, journals its new state and returns it with the current time.
+// If key is undefined or expired, it will create it.
+func (log *log) increment(key string, value int64, duration time.Duration) (time.Time, counter, error) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	now := log.clock.Now()
+	current, ok := log.counters[key]
+	if !ok || current.expired(now) {
+		current = counter{expiration: now.Add(duration)}
+	}
+	current.value += value
+
+	err := log.journal.append(key, current)
+	if err != nil {
+		return now, counter{}, err
+	}
+
+	log.counters[key] = current
+	return now, current, nil
+}
+
+// get returns the state of given key with the current time.
+func (log *log) get(key string, duration time.Duration) (time.Time, counter) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	now := log.clock.Now()
+	current, ok := log.counters[key]
+	if !ok || current.expired(now) {
+		return now, counter{expiration: now.Add(duration)}
+	}
+
+	return now, current
+}
+
+// reset deletes given key, journals its deletion and returns the current time.
+func (log *log) reset(key string) (time.Time, error) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	now := log.clock.Now()
+	_, ok := log.counters[key]
+	if !ok {
+		return now, nil
+	}
+
+	err := log.journal.append(key, counter{})
+	if err != nil {
+		return now, err
+	}
+
+	delete(log.counters, key)
+	return now, nil
+}
+
+// compact deletes any expired keys, and rewrites the journal with the remaining ones.
+func (log *log) compact() error {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	now := log.clock.Now()
+	for key, counter := range log.counters {
+		if counter.expired(now) {
+			delete(log.counters, key)
+		}
+	}
+
+	return log.journal.compact(log.counters)
+}
+
+// A cleaner will periodically compact the journal of a log.
+type cleaner struct {
+	interval time.Duration
+	stop     chan bool
+}
+
+// Run will periodically compact given log until GC notify that it should stop.
+func (cleaner *cleaner) Run(log *log) {
+	ticker := time.NewTicker(cleaner.interval)
+	for {
+		select {
+		case <-ticker.C:
+			// On failure, the previous journal is kept until the next attempt.
+			_ = log.compact()
+		case <-cleaner.stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// stopCleaner is a callback from GC used to stop cleaner goroutine.
+func stopCleaner(store *Store) {
+	store.log.cleaner.stop <- true
+	store.log.cleaner = nil
+}
+
+// startCleaner will start a cleaner goroutine for given log.
+func startCleaner(log *log, interval time.Duration) {
+	cleaner := &cleaner{
+		interval: interval,
+		stop:     make(chan bool),
+	}
+
+	log.cleaner = cleaner
+	go cleaner.Run(log)
+}
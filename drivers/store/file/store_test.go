@@ -0,0 +1,230 @@
+package file_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/file"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
+)
+
+func TestFileStoreSequentialAccess(t *testing.T) {
+	tests.TestStoreSequentialAccess(t, newStore(t, limiter.StoreOptions{
+		Prefix:          "limiter:file:sequential-test",
+		CleanUpInterval: 30 * time.Second,
+	}))
+}
+
+func TestFileStoreConcurrentAccess(t *testing.T) {
+	tests.TestStoreConcurrentAccess(t, newStore(t, limiter.StoreOptions{
+		Prefix:          "limiter:file:concurrent-test",
+		CleanUpInterval: 1 * time.Millisecond,
+	}))
+}
+
+func TestFileStoreBatchAccess(t *testing.T) {
+	tests.TestStoreBatchAccess(t, newStore(t, limiter.StoreOptions{
+		Prefix:          "limiter:file:batch-test",
+		CleanUpInterval: 30 * time.Second,
+	}))
+}
+
+func TestFileStoreOverAdmission(t *testing.T) {
+	tests.TestStoreOverAdmission(t, []limiter.Store{newStore(t, limiter.StoreOptions{
+		Prefix: "limiter:file:over-admission-test",
+	})}, 0)
+}
+
+func TestFileStoreWindowRollover(t *testing.T) {
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	tests.TestStoreWindowRollover(t, newStore(t, limiter.StoreOptions{
+		Prefix: "limiter:file:window-rollover-test",
+		Clock:  clock,
+	}), clock)
+}
+
+func TestFileStoreReplay(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	path := filepath.Join(t.TempDir(), "limiter.log")
+	options := limiter.StoreOptions{
+		Prefix: "limiter:file:replay-test",
+		Clock:  clock,
+	}
+
+	minute := limiter.Rate{Limit: 10, Period: time.Minute}
+	second := limiter.Rate{Limit: 10, Period: time.Second}
+
+	store, err := file.NewStoreWithOptions(path, options)
+	is.NoError(err)
+
+	_, err = store.Increment(ctx, "foo", 3, minute)
+	is.NoError(err)
+	_, err = store.Increment(ctx, "bar", 5, minute)
+	is.NoError(err)
+	_, err = store.Reset(ctx, "bar", minute)
+	is.NoError(err)
+	_, err = store.Increment(ctx, "baz", 1, second)
+	is.NoError(err)
+
+	err = store.(*file.Store).Close()
+	is.NoError(err)
+
+	// An interrupted write leaves a partial record, which is discarded.
+	journal, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	is.NoError(err)
+	_, err = journal.WriteString(`{"key":"limiter:file:replay-test:foo","val`)
+	is.NoError(err)
+	is.NoError(journal.Close())
+
+	clock.Advance(10 * time.Second)
+
+	store, err = file.NewStoreWithOptions(path, options)
+	is.NoError(err)
+
+	lctx, err := store.Get(ctx, "foo", minute)
+	is.NoError(err)
+	is.Equal(int64(6), lctx.Remaining)
+	is.Equal(clock.Now().Add(50*time.Second).Unix(), lctx.Reset)
+
+	lctx, err = store.Peek(ctx, "bar", minute)
+	is.NoError(err)
+	is.Equal(int64(10), lctx.Remaining)
+
+	lctx, err = store.Peek(ctx, "baz", second)
+	is.NoError(err)
+	is.Equal(int64(10), lctx.Remaining)
+
+	err = store.(*file.Store).Close()
+	is.NoError(err)
+
+	// Records written after a partial one are replayed too.
+	store, err = file.NewStoreWithOptions(path, options)
+	is.NoError(err)
+
+	lctx, err = store.Peek(ctx, "foo", minute)
+	is.NoError(err)
+	is.Equal(int64(6), lctx.Remaining)
+
+	err = store.(*file.Store).Close()
+	is.NoError(err)
+}
+
+func TestFileStoreCompact(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	path := filepath.Join(t.TempDir(), "limiter.log")
+	options := limiter.StoreOptions{
+		Prefix: "limiter:file:compact-test",
+		Clock:  clock,
+	}
+
+	minute := limiter.Rate{Limit: 1000, Period: time.Minute}
+	second := limiter.Rate{Limit: 1000, Period: time.Second}
+
+	store, err := file.NewStoreWithOptions(path, options)
+	is.NoError(err)
+
+	for i := 0; i < 100; i++ {
+		_, err = store.Get(ctx, "foo", minute)
+		is.NoError(err)
+		_, err = store.Get(ctx, "bar", second)
+		is.NoError(err)
+	}
+
+	before := countLines(t, path)
+	is.Equal(201, before)
+
+	clock.Advance(2 * time.Second)
+
+	err = store.(*file.Store).Compact()
+	is.NoError(err)
+
+	// Only the header and the last state of "foo" are kept.
+	is.Equal(2, countLines(t, path))
+
+	// New records are appended to the compacted journal.
+	_, err = store.Get(ctx, "foo", minute)
+	is.NoError(err)
+	is.Equal(3, countLines(t, path))
+
+	err = store.(*file.Store).Close()
+	is.NoError(err)
+
+	store, err = file.NewStoreWithOptions(path, options)
+	is.NoError(err)
+
+	lctx, err := store.Peek(ctx, "foo", minute)
+	is.NoError(err)
+	is.Equal(int64(899), lctx.Remaining)
+
+	lctx, err = store.Peek(ctx, "bar", second)
+	is.NoError(err)
+	is.Equal(int64(1000), lctx.Remaining)
+
+	err = store.(*file.Store).Close()
+	is.NoError(err)
+}
+
+func TestFileStoreInvalidJournal(t *testing.T) {
+	is := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "limiter.log")
+
+	err := os.WriteFile(path, []byte("{\"version\":2}\n"), 0600)
+	is.NoError(err)
+
+	_, err = file.NewStore(path)
+	is.Error(err)
+
+	err = os.WriteFile(path, []byte("{\"version\":1}\nnot a record\n{\"key\":\"foo\"}\n"), 0600)
+	is.NoError(err)
+
+	_, err = file.NewStore(path)
+	is.Error(err)
+}
+
+func BenchmarkFileStoreSequentialAccess(b *testing.B) {
+	tests.BenchmarkStoreSequentialAccess(b, newStore(b, limiter.StoreOptions{
+		Prefix:          "limiter:file:sequential-benchmark",
+		CleanUpInterval: 1 * time.Hour,
+	}))
+}
+
+func BenchmarkFileStoreConcurrentAccess(b *testing.B) {
+	tests.BenchmarkStoreConcurrentAccess(b, newStore(b, limiter.StoreOptions{
+		Prefix:          "limiter:file:concurrent-benchmark",
+		CleanUpInterval: 1 * time.Hour,
+	}))
+}
+
+// newStore returns a file store journaled in a temporary directory, closed at the end of the test.
+func newStore(tb testing.TB, options limiter.StoreOptions) limiter.Store {
+	store, err := file.NewStoreWithOptions(filepath.Join(tb.TempDir(), "limiter.log"), options)
+	require.NoError(tb, err)
+
+	tb.Cleanup(func() {
+		require.NoError(tb, store.(*file.Store).Close())
+	})
+
+	return store
+}
+
+// countLines returns the number of lines of given file.
+func countLines(t *testing.T, path string) int {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Count(string(data), "\n")
+}
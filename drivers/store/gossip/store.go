@@ -0,0 +1,356 @@
+package gossip
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/common"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+)
+
+// Store is an eventually consistent store: every node counts its own increments in memory and periodically
+// broadcasts the counts it has changed to the other nodes, using a transport. Each node enforces the sum of
+// its own count and of the last counts received from the other nodes.
+//
+// Counts form a G-counter per key and window: each node is the single writer of its own count, and a count
+// received from another node only replaces an older one. Windows are aligned on the period, so that every
+// node agrees on them, which requires synchronized clocks. Until counts are exchanged, each node may admit
+// requests the others don't know about yet: the over-admission error is bounded by the rate of each node
+// during SyncInterval. An identifier must always be used with the same rate.
+type Store struct {
+	// Prefix used for the key.
+	Prefix string
+	// state shared with the transport and the syncer.
+	state *state
+}
+
+// NewStore returns an instance of gossip store with defaults.
+func NewStore(node string, transport Transport) (limiter.Store, error) {
+	return NewStoreWithOptions(node, transport, limiter.StoreOptions{
+		Prefix:          limiter.DefaultPrefix,
+		CleanUpInterval: limiter.DefaultCleanUpInterval,
+		SyncInterval:    limiter.DefaultSyncInterval,
+	})
+}
+
+// NewStoreWithOptions returns an instance of gossip store with options.
+// Node must be unique in the cluster. Options are also used by the memory cache keeping the counts of this node.
+func NewStoreWithOptions(node string, transport Transport, options limiter.StoreOptions) (limiter.Store, error) {
+	if node == "" {
+		return nil, errors.New("gossip store requires a node identifier")
+	}
+	if transport == nil {
+		return nil, errors.New("gossip store requires a transport")
+	}
+
+	clock := options.Clock
+	if clock == nil {
+		clock = limiter.SystemClock
+	}
+
+	state := &state{
+		node:      node,
+		prefix:    options.Prefix,
+		transport: transport,
+		clock:     clock,
+		local:     memory.NewCacheWithOptions(options),
+		remote:    map[string]*remoteCounts{},
+		changed:   map[string]window{},
+		// The sequence starts at the current time, so that the messages of a restarted node
+		// are not ignored.
+		sequence: clock.Now().UnixNano(),
+	}
+
+	transport.Subscribe(state.Receive)
+
+	store := &Store{
+		Prefix: options.Prefix,
+		state:  state,
+	}
+
+	if options.SyncInterval > 0 {
+		startSyncer(state, options.SyncInterval)
+		runtime.SetFinalizer(store, stopSyncer)
+	}
+
+	return store, nil
+}
+
+// Get returns the limit for given identifier.
+func (store *Store) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return store.Increment(ctx, key, 1, rate)
+}
+
+// Increment increments the limit by given count & gives back the new limit for given identifier.
+func (store *Store) Increment(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
+	now, current, expiration := store.state.getWindow(rate)
+
+	value, _ := store.state.local.Increment(store.state.getCacheKey(key, current.index), count, expiration.Sub(now))
+	store.state.change(key, current)
+
+	total := value + store.state.getRemote(key, current.index)
+	return common.GetContextFromState(now, rate, expiration, total), nil
+}
+
+// Peek returns the limit for given identifier, without modification on current values.
+func (store *Store) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	now, current, expiration := store.state.getWindow(rate)
+
+	value, _ := store.state.local.Get(store.state.getCacheKey(key, current.index), expiration.Sub(now))
+
+	total := value + store.state.getRemote(key, current.index)
+	return common.GetContextFromState(now, rate, expiration, total), nil
+}
+
+// Reset returns the limit for given identifier which is set to zero.
+// The count of this node is set to the opposite of the counts of the other nodes, so that the sum is zero
+// once it has been broadcast. Increments the other nodes haven't sent yet are kept.
+func (store *Store) Reset(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	now, current, expiration := store.state.getWindow(rate)
+	cacheKey := store.state.getCacheKey(key, current.index)
+
+	value, _ := store.state.local.Get(cacheKey, expiration.Sub(now))
+	total := value + store.state.getRemote(key, current.index)
+	store.state.local.Increment(cacheKey, -total, expiration.Sub(now))
+	store.state.change(key, current)
+
+	return common.GetContextFromState(now, rate, expiration, 0), nil
+}
+
+// Sync broadcasts the counts of this node changed since the previous call.
+func (store *Store) Sync(ctx context.Context) error {
+	return store.state.Sync(ctx)
+}
+
+// A syncer will periodically broadcast the counts of a node.
+type syncer struct {
+	interval time.Duration
+	stop     chan bool
+}
+
+// Run will periodically broadcast the counts of given state until GC notify that it should stop.
+func (syncer *syncer) Run(state *state) {
+	ticker := time.NewTicker(syncer.interval)
+	for {
+		select {
+		case <-ticker.C:
+			_ = state.Sync(context.Background())
+		case <-syncer.stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// stopSyncer is a callback from GC used to stop syncer goroutine.
+func stopSyncer(store *Store) {
+	store.state.syncer.stop <- true
+	store.state.syncer = nil
+}
+
+// startSyncer will start a syncer goroutine for given state.
+func startSyncer(state *state, interval time.Duration) {
+	syncer := &syncer{
+		interval: interval,
+		stop:     make(chan bool),
+	}
+
+	state.syncer = syncer
+	go syncer.Run(state)
+}
+
+// window is a window of a rate, aligned on its period.
+type window struct {
+	index  int64
+	period int64
+}
+
+// remoteCounts are the last counts received from the other nodes for a key, during a window.
+type remoteCounts struct {
+	window int64
+	// expiration is the end of the window.
+	expiration time.Time
+	// values contains the count of each node.
+	values map[string]remoteValue
+}
+
+// remoteValue is the count of a node, with the sequence of the message it was received in.
+type remoteValue struct {
+	value    int64
+	sequence int64
+}
+
+// state is the state of a node.
+type state struct {
+	node      string
+	prefix    string
+	transport Transport
+	clock     limiter.Clock
+	syncer    *syncer
+	// local contains the counts of this node, by key and window.
+	local *memory.CacheWrapper
+	// mutex is used to avoid concurrent access on remote, changed and sequence.
+	mutex sync.Mutex
+	// remote contains the counts of the other nodes, by key.
+	remote map[string]*remoteCounts
+	// changed contains the window of every key incremented since the last broadcast.
+	changed map[string]window
+	// sequence is the sequence of the last broadcast message.
+	sequence int64
+}
+
+// getWindow returns the current time, with the current window of given rate and its end.
+func (state *state) getWindow(rate limiter.Rate) (time.Time, window, time.Time) {
+	now := state.clock.Now()
+	current := window{
+		index:  now.UnixNano() / int64(rate.Period),
+		period: int64(rate.Period),
+	}
+
+	return now, current, current.end()
+}
+
+// end returns the end of the window.
+func (window window) end() time.Time {
+	return time.Unix(0, (window.index+1)*window.period)
+}
+
+// getCacheKey returns the full path of the count of this node for given identifier and window.
+func (state *state) getCacheKey(key string, window int64) string {
+	buffer := strings.Builder{}
+	buffer.WriteString(state.prefix)
+	buffer.WriteString(":")
+	buffer.WriteString(key)
+	buffer.WriteString(":")
+	buffer.WriteString(strconv.FormatInt(window, 10))
+	return buffer.String()
+}
+
+// getRemote returns the sum of the counts of the other nodes for given identifier and window.
+func (state *state) getRemote(key string, window int64) int64 {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	counts, ok := state.remote[key]
+	if !ok || counts.window != window {
+		return 0
+	}
+
+	total := int64(0)
+	for _, value := range counts.values {
+		total += value.value
+	}
+
+	return total
+}
+
+// change marks given identifier as changed during given window, so it's broadcast on next sync.
+func (state *state) change(key string, current window) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	state.changed[key] = current
+}
+
+// Sync broadcasts the counts of this node changed since the previous call, and removes the counts of the
+// other nodes for expired windows.
+func (state *state) Sync(ctx context.Context) error {
+	now := state.clock.Now()
+
+	state.mutex.Lock()
+	changed := state.changed
+	state.changed = map[string]window{}
+	state.sequence++
+	sequence := state.sequence
+
+	for key, counts := range state.remote {
+		if !now.Before(counts.expiration) {
+			delete(state.remote, key)
+		}
+	}
+	state.mutex.Unlock()
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	message := Message{
+		Node:     state.node,
+		Sequence: sequence,
+		Counts:   make([]Count, 0, len(changed)),
+	}
+
+	for key, current := range changed {
+		value, _ := state.local.Get(state.getCacheKey(key, current.index), 0)
+		message.Counts = append(message.Counts, Count{
+			Key:    key,
+			Window: current.index,
+			Period: current.period,
+			Value:  value,
+		})
+	}
+
+	err := state.transport.Broadcast(ctx, message)
+	if err != nil {
+		// Counts are sent again on next sync, unless they have been changed since.
+		state.mutex.Lock()
+		for key, current := range changed {
+			_, ok := state.changed[key]
+			if !ok {
+				state.changed[key] = current
+			}
+		}
+		state.mutex.Unlock()
+
+		return errors.Wrap(err, "cannot broadcast counts")
+	}
+
+	return nil
+}
+
+// Receive merges the counts of another node: a count replaces the one from an older message, during
+// the same window, or the counts of a previous window.
+func (state *state) Receive(message Message) {
+	if message.Node == state.node {
+		return
+	}
+
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	for _, count := range message.Counts {
+		counts, ok := state.remote[count.Key]
+		if count.Period <= 0 {
+			continue
+		}
+
+		if !ok || counts.window < count.Window {
+			counts = &remoteCounts{
+				window:     count.Window,
+				expiration: window{index: count.Window, period: count.Period}.end(),
+				values:     map[string]remoteValue{},
+			}
+			state.remote[count.Key] = counts
+		}
+		if counts.window != count.Window {
+			continue
+		}
+
+		value, ok := counts.values[message.Node]
+		if ok && value.sequence >= message.Sequence {
+			continue
+		}
+
+		counts.values[message.Node] = remoteValue{
+			value:    count.Value,
+			sequence: message.Sequence,
+		}
+	}
+}
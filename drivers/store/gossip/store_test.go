@@ -0,0 +1,403 @@
+package gossip_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/gossip"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
+)
+
+func TestGossipStoreSequentialAccess(t *testing.T) {
+	tests.TestStoreSequentialAccess(t, newStore(t, "node", gossip.NewMemoryNetwork().Join(), limiter.StoreOptions{
+		Prefix:          "limiter:gossip:sequential-test",
+		CleanUpInterval: 30 * time.Second,
+		// Windows are aligned, so the test must not run across two of them.
+		Clock: tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)),
+	}))
+}
+
+func TestGossipStoreConcurrentAccess(t *testing.T) {
+	tests.TestStoreConcurrentAccess(t, newStore(t, "node", gossip.NewMemoryNetwork().Join(), limiter.StoreOptions{
+		Prefix:          "limiter:gossip:concurrent-test",
+		CleanUpInterval: 1 * time.Millisecond,
+		SyncInterval:    1 * time.Millisecond,
+	}))
+}
+
+func TestGossipStoreBatchAccess(t *testing.T) {
+	tests.TestStoreBatchAccess(t, newStore(t, "node", gossip.NewMemoryNetwork().Join(), limiter.StoreOptions{
+		Prefix: "limiter:gossip:batch-test",
+		Clock:  tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)),
+	}))
+}
+
+func TestGossipStoreConvergence(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	stores := newCluster(t, 3, limiter.StoreOptions{
+		Prefix: "limiter:gossip:convergence-test",
+		Clock:  clock,
+	})
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Minute,
+	}
+
+	// Each node only knows its own count until counts are exchanged.
+	for i, store := range stores {
+		for j := 0; j <= i; j++ {
+			_, err := store.Get(ctx, "foo", rate)
+			is.NoError(err)
+		}
+
+		lctx, err := store.Peek(ctx, "foo", rate)
+		is.NoError(err)
+		is.Equal(int64(10-i-1), lctx.Remaining)
+	}
+
+	syncAll(t, stores)
+
+	for _, store := range stores {
+		lctx, err := store.Peek(ctx, "foo", rate)
+		is.NoError(err)
+		is.Equal(int64(4), lctx.Remaining)
+	}
+
+	// Counts are replaced, not added: syncing again doesn't change anything.
+	syncAll(t, stores)
+
+	lctx, err := stores[0].Get(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(3), lctx.Remaining)
+
+	syncAll(t, stores)
+
+	for _, store := range stores {
+		lctx, err = store.Peek(ctx, "foo", rate)
+		is.NoError(err)
+		is.Equal(int64(3), lctx.Remaining)
+	}
+
+	// Reset is propagated to every node.
+	lctx, err = stores[1].Reset(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(10), lctx.Remaining)
+
+	syncAll(t, stores)
+
+	for _, store := range stores {
+		lctx, err = store.Peek(ctx, "foo", rate)
+		is.NoError(err)
+		is.Equal(int64(10), lctx.Remaining)
+	}
+}
+
+func TestGossipStoreWindows(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := tests.NewManualClock(start.Add(20 * time.Second))
+	stores := newCluster(t, 2, limiter.StoreOptions{
+		Prefix: "limiter:gossip:windows-test",
+		Clock:  clock,
+	})
+
+	rate := limiter.Rate{
+		Limit:  3,
+		Period: time.Minute,
+	}
+
+	// Windows are aligned on the period.
+	for i := 1; i <= 4; i++ {
+		lctx, err := stores[0].Get(ctx, "foo", rate)
+		is.NoError(err)
+		is.Equal(start.Add(time.Minute).Unix(), lctx.Reset)
+		is.Equal(i > 3, lctx.Reached)
+	}
+
+	syncAll(t, stores)
+
+	lctx, err := stores[1].Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.True(lctx.Reached)
+
+	// Counts of the previous window are ignored once it's over, and removed on next sync.
+	clock.Advance(40 * time.Second)
+
+	lctx, err = stores[1].Get(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(2), lctx.Remaining)
+	is.Equal(start.Add(2*time.Minute).Unix(), lctx.Reset)
+
+	syncAll(t, stores)
+
+	lctx, err = stores[0].Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(2), lctx.Remaining)
+}
+
+func TestGossipStoreMessages(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	transport := &recordingTransport{}
+	store := newStore(t, "node-0", transport, limiter.StoreOptions{
+		Prefix: "limiter:gossip:messages-test",
+		Clock:  clock,
+	})
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Minute,
+	}
+	window := clock.Now().UnixNano() / int64(time.Minute)
+
+	newMessage := func(sequence int64, window int64, value int64) gossip.Message {
+		return gossip.Message{
+			Node:     "node-1",
+			Sequence: sequence,
+			Counts: []gossip.Count{
+				{Key: "foo", Window: window, Period: int64(time.Minute), Value: value},
+			},
+		}
+	}
+
+	// An older message doesn't replace a count.
+	transport.deliver(newMessage(2, window, 5))
+	transport.deliver(newMessage(1, window, 3))
+
+	lctx, err := store.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(5), lctx.Remaining)
+
+	// A count for a previous window is ignored.
+	transport.deliver(newMessage(3, window-1, 8))
+
+	lctx, err = store.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(5), lctx.Remaining)
+
+	// Messages sent by this node are ignored.
+	message := newMessage(4, window, 8)
+	message.Node = "node-0"
+	transport.deliver(message)
+
+	lctx, err = store.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(5), lctx.Remaining)
+
+	// Only changed counts are broadcast, with the count of this node.
+	_, err = store.Increment(ctx, "foo", 2, rate)
+	is.NoError(err)
+	_, err = store.Increment(ctx, "bar", 1, rate)
+	is.NoError(err)
+
+	err = store.(*gossip.Store).Sync(ctx)
+	is.NoError(err)
+
+	messages := transport.sent()
+	is.Len(messages, 1)
+	is.Equal("node-0", messages[0].Node)
+	is.ElementsMatch([]gossip.Count{
+		{Key: "foo", Window: window, Period: int64(time.Minute), Value: 2},
+		{Key: "bar", Window: window, Period: int64(time.Minute), Value: 1},
+	}, messages[0].Counts)
+
+	err = store.(*gossip.Store).Sync(ctx)
+	is.NoError(err)
+	is.Len(transport.sent(), 1)
+
+	// Counts are sent again if the broadcast has failed.
+	_, err = store.Increment(ctx, "foo", 1, rate)
+	is.NoError(err)
+
+	transport.fail(true)
+	err = store.(*gossip.Store).Sync(ctx)
+	is.Error(err)
+
+	transport.fail(false)
+	err = store.(*gossip.Store).Sync(ctx)
+	is.NoError(err)
+
+	messages = transport.sent()
+	is.Len(messages, 2)
+	is.Greater(messages[1].Sequence, messages[0].Sequence)
+	is.Equal([]gossip.Count{
+		{Key: "foo", Window: window, Period: int64(time.Minute), Value: 3},
+	}, messages[1].Counts)
+}
+
+func TestGossipStoreSyncer(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	network := gossip.NewMemoryNetwork()
+	stores := []limiter.Store{}
+	for _, node := range []string{"node-0", "node-1"} {
+		stores = append(stores, newStore(t, node, network.Join(), limiter.StoreOptions{
+			Prefix:       "limiter:gossip:syncer-test",
+			SyncInterval: 5 * time.Millisecond,
+		}))
+	}
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Hour,
+	}
+
+	_, err := stores[0].Increment(ctx, "foo", 3, rate)
+	is.NoError(err)
+	_, err = stores[1].Increment(ctx, "foo", 2, rate)
+	is.NoError(err)
+
+	is.Eventually(func() bool {
+		for _, store := range stores {
+			lctx, err := store.Peek(ctx, "foo", rate)
+			if err != nil || lctx.Remaining != 5 {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 5*time.Millisecond)
+}
+
+func TestGossipStoreLeave(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	network := gossip.NewMemoryNetwork()
+	transports := []gossip.Transport{network.Join(), network.Join()}
+	stores := []limiter.Store{
+		newStore(t, "node-0", transports[0], limiter.StoreOptions{Prefix: "limiter:gossip:leave-test"}),
+		newStore(t, "node-1", transports[1], limiter.StoreOptions{Prefix: "limiter:gossip:leave-test"}),
+	}
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Hour,
+	}
+
+	network.Leave(transports[1])
+
+	_, err := stores[0].Get(ctx, "foo", rate)
+	is.NoError(err)
+	_, err = stores[1].Get(ctx, "foo", rate)
+	is.NoError(err)
+
+	syncAll(t, stores)
+
+	for _, store := range stores {
+		lctx, err := store.Peek(ctx, "foo", rate)
+		is.NoError(err)
+		is.Equal(int64(9), lctx.Remaining)
+	}
+}
+
+func BenchmarkGossipStoreSequentialAccess(b *testing.B) {
+	tests.BenchmarkStoreSequentialAccess(b, newStore(b, "node", gossip.NewMemoryNetwork().Join(), limiter.StoreOptions{
+		Prefix:          "limiter:gossip:sequential-benchmark",
+		CleanUpInterval: 30 * time.Second,
+		SyncInterval:    limiter.DefaultSyncInterval,
+	}))
+}
+
+func BenchmarkGossipStoreConcurrentAccess(b *testing.B) {
+	tests.BenchmarkStoreConcurrentAccess(b, newStore(b, "node", gossip.NewMemoryNetwork().Join(), limiter.StoreOptions{
+		Prefix:          "limiter:gossip:concurrent-benchmark",
+		CleanUpInterval: 30 * time.Second,
+		SyncInterval:    limiter.DefaultSyncInterval,
+	}))
+}
+
+// newStore returns a gossip store for given node, using given transport.
+func newStore(tb testing.TB, node string, transport gossip.Transport, options limiter.StoreOptions) limiter.Store {
+	store, err := gossip.NewStoreWithOptions(node, transport, options)
+	require.NoError(tb, err)
+	return store
+}
+
+// newCluster returns gossip stores of given number of nodes, connected by an in-memory network.
+// Counts are only exchanged on Sync.
+func newCluster(tb testing.TB, size int, options limiter.StoreOptions) []limiter.Store {
+	network := gossip.NewMemoryNetwork()
+
+	stores := make([]limiter.Store, size)
+	for i := range stores {
+		stores[i] = newStore(tb, "node-"+string(rune('a'+i)), network.Join(), options)
+	}
+
+	return stores
+}
+
+// syncAll broadcasts the counts of every store.
+func syncAll(tb testing.TB, stores []limiter.Store) {
+	for _, store := range stores {
+		require.NoError(tb, store.(*gossip.Store).Sync(context.Background()))
+	}
+}
+
+// recordingTransport keeps the messages it broadcasts, and delivers messages on demand.
+type recordingTransport struct {
+	mutex    sync.Mutex
+	messages []gossip.Message
+	handlers []func(message gossip.Message)
+	failing  bool
+}
+
+func (transport *recordingTransport) Broadcast(ctx context.Context, message gossip.Message) error {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+
+	if transport.failing {
+		return errors.New("network is unreachable")
+	}
+
+	transport.messages = append(transport.messages, message)
+	return nil
+}
+
+func (transport *recordingTransport) Subscribe(handler func(message gossip.Message)) {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+
+	transport.handlers = append(transport.handlers, handler)
+}
+
+// deliver calls every handler with given message.
+func (transport *recordingTransport) deliver(message gossip.Message) {
+	transport.mutex.Lock()
+	handlers := transport.handlers
+	transport.mutex.Unlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+}
+
+// sent returns the messages broadcast so far.
+func (transport *recordingTransport) sent() []gossip.Message {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+
+	return append([]gossip.Message{}, transport.messages...)
+}
+
+// fail makes the broadcasts fail, or succeed.
+func (transport *recordingTransport) fail(failing bool) {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+
+	transport.failing = failing
+}
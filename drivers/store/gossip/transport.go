@@ -0,0 +1,123 @@
+package gossip
+
+import (
+	"context"
+	"sync"
+)
+
+// Message is the state of the counters of a node, for the keys incremented since its previous message.
+type Message struct {
+	// Node is the identifier of the sending node.
+	Node string `json:"node"`
+	// Sequence orders the messages of a node: a count is only replaced by a more recent one.
+	Sequence int64 `json:"sequence"`
+	// Counts are the counts of the sending node.
+	Counts []Count `json:"counts"`
+}
+
+// Count is the count of a node for a key, during a window.
+type Count struct {
+	// Key is the identifier, without prefix.
+	Key string `json:"key"`
+	// Window is the index of the window, ie: the unix time in nanoseconds divided by the period.
+	Window int64 `json:"window"`
+	// Period is the period of the rate, in nanoseconds.
+	Period int64 `json:"period"`
+	// Value is the number of increments counted by the node.
+	Value int64 `json:"value"`
+}
+
+// Transport exchanges messages between the nodes of a cluster.
+// It doesn't have to be reliable: a lost count is replaced by the next one for the same key.
+type Transport interface {
+	// Broadcast sends given message to every other node.
+	Broadcast(ctx context.Context, message Message) error
+	// Subscribe registers the handler called with every message received from another node.
+	Subscribe(handler func(message Message))
+}
+
+// MemoryNetwork connects transports in the same process, mainly for tests.
+type MemoryNetwork struct {
+	mutex      sync.RWMutex
+	transports []*memoryTransport
+}
+
+// NewMemoryNetwork returns an empty in-memory network.
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{}
+}
+
+// Join returns a new transport connected to every other transport of the network.
+func (network *MemoryNetwork) Join() Transport {
+	transport := &memoryTransport{network: network}
+
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	network.transports = append(network.transports, transport)
+	return transport
+}
+
+// Leave disconnects given transport from the network: it doesn't send nor receive any message anymore.
+func (network *MemoryNetwork) Leave(transport Transport) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	for i := range network.transports {
+		if network.transports[i] == transport {
+			network.transports = append(network.transports[:i], network.transports[i+1:]...)
+			return
+		}
+	}
+}
+
+// memoryTransport is a transport of an in-memory network.
+// Messages are delivered synchronously to the handlers of the other transports.
+type memoryTransport struct {
+	network  *MemoryNetwork
+	mutex    sync.RWMutex
+	handlers []func(message Message)
+}
+
+// Broadcast sends given message to every other transport of the network.
+func (transport *memoryTransport) Broadcast(ctx context.Context, message Message) error {
+	transport.network.mutex.RLock()
+	transports := make([]*memoryTransport, 0, len(transport.network.transports))
+	joined := false
+	for _, other := range transport.network.transports {
+		if other == transport {
+			joined = true
+			continue
+		}
+		transports = append(transports, other)
+	}
+	transport.network.mutex.RUnlock()
+
+	if !joined {
+		return nil
+	}
+
+	for _, other := range transports {
+		other.receive(message)
+	}
+
+	return nil
+}
+
+// Subscribe registers the handler called with every message received from another transport.
+func (transport *memoryTransport) Subscribe(handler func(message Message)) {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+
+	transport.handlers = append(transport.handlers, handler)
+}
+
+// receive calls every handler with given message.
+func (transport *memoryTransport) receive(message Message) {
+	transport.mutex.RLock()
+	defer transport.mutex.RUnlock()
+
+	for _, handler := range transport.handlers {
+		handler(message)
+	}
+}
@@ -0,0 +1,237 @@
+package hybrid
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ulule/limiter/v3"
+)
+
+// A syncer will periodically flush pending increments of a cache to its remote store.
+type syncer struct {
+	interval time.Duration
+	stop     chan bool
+}
+
+// Run will periodically flush given cache until GC notify that it should stop.
+func (syncer *syncer) Run(cache *cache) {
+	ticker := time.NewTicker(syncer.interval)
+	for {
+		select {
+		case <-ticker.C:
+			cache.Sync(context.Background())
+		case <-syncer.stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// stopSyncer is a callback from GC used to stop syncer goroutine.
+func stopSyncer(store *Store) {
+	store.cache.syncer.stop <- true
+	store.cache.syncer = nil
+}
+
+// startSyncer will start a syncer goroutine for given cache.
+func startSyncer(cache *cache, interval time.Duration) {
+	syncer := &syncer{
+		interval: interval,
+		stop:     make(chan bool),
+	}
+
+	cache.syncer = syncer
+	go syncer.Run(cache)
+}
+
+// entry is the local state of a key.
+type entry struct {
+	mutex sync.Mutex
+	// global is the last count returned by the remote store.
+	global int64
+	// pending is the number of increments that are not flushed yet to the remote store.
+	pending int64
+	// expiration is the last expiration returned by the remote store.
+	expiration time.Time
+	// rate is the last rate used with this key.
+	rate limiter.Rate
+	// deleted is true if the entry has been removed from the cache.
+	deleted bool
+}
+
+// expired returns true if the entry has expired.
+// The caller must hold the entry mutex.
+func (entry *entry) expired(now time.Time) bool {
+	return entry.expiration.IsZero() || now.After(entry.expiration)
+}
+
+// cache counts increments locally and flushes them in batch to a remote store.
+type cache struct {
+	entries  sync.Map
+	remote   limiter.Store
+	maxDrift int64
+	syncer   *syncer
+	clock    limiter.Clock
+}
+
+// load returns the entry for given key, locked.
+// If the entry is missing, it will be created.
+func (cache *cache) load(key string) *entry {
+	for {
+		val, ok := cache.entries.Load(key)
+		if !ok {
+			val, _ = cache.entries.LoadOrStore(key, &entry{})
+		}
+
+		entry := val.(*entry)
+		entry.mutex.Lock()
+		if !entry.deleted {
+			return entry
+		}
+
+		// The entry has been removed by a concurrent sync or reset, try again.
+		entry.mutex.Unlock()
+	}
+}
+
+// remove deletes given locked entry from the cache.
+func (cache *cache) remove(key string, entry *entry) {
+	entry.deleted = true
+	cache.entries.Delete(key)
+}
+
+// flush sends pending increments of given locked entry to the remote store,
+// and keeps the global count and expiration returned by the remote store.
+func (cache *cache) flush(ctx context.Context, key string, entry *entry) error {
+	lctx, err := cache.remote.Increment(ctx, key, entry.pending, entry.rate)
+	if err != nil {
+		return err
+	}
+
+	entry.global = entry.rate.Limit - lctx.Remaining
+	if lctx.Reached {
+		// Remaining is clamped to zero when the limit is reached, so the exact count is lost.
+		// Use the highest count we know of: it's still a lower bound of the global count.
+		entry.global = maxInt64(entry.global+entry.pending, entry.rate.Limit+1)
+	}
+	entry.pending = 0
+	entry.expiration = time.Unix(lctx.Reset, 0)
+
+	return nil
+}
+
+// expire ends the window of given locked entry, which has expired at given time. Its pending increments are
+// flushed first if the window of the remote store may still be running, since the expiration returned by the
+// remote store is truncated to the second. Otherwise, they're dropped with their window.
+func (cache *cache) expire(ctx context.Context, key string, entry *entry, now time.Time) error {
+	if entry.pending > 0 && now.Before(entry.expiration.Add(time.Second)) {
+		err := cache.flush(ctx, key, entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	entry.global = 0
+	entry.pending = 0
+	entry.expiration = time.Time{}
+
+	return nil
+}
+
+// Increment increments given value on key and returns the estimated global count and its expiration.
+func (cache *cache) Increment(ctx context.Context, key string, value int64,
+	rate limiter.Rate) (int64, time.Time, error) {
+
+	entry := cache.load(key)
+	defer entry.mutex.Unlock()
+
+	now := cache.clock.Now()
+	if entry.expired(now) {
+		err := cache.expire(ctx, key, entry, now)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+	}
+
+	entry.rate = rate
+	entry.pending += value
+
+	// The first increment of a window must reach the remote store to obtain its expiration.
+	if entry.expiration.IsZero() || entry.pending >= cache.maxDrift {
+		err := cache.flush(ctx, key, entry)
+		if err != nil {
+			entry.pending -= value
+			return 0, time.Time{}, err
+		}
+	}
+
+	return entry.global + entry.pending, entry.expiration, nil
+}
+
+// Pending returns the number of increments of given key that are not flushed yet.
+func (cache *cache) Pending(key string) int64 {
+	val, ok := cache.entries.Load(key)
+	if !ok {
+		return 0
+	}
+
+	entry := val.(*entry)
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+
+	if entry.deleted || entry.expired(cache.clock.Now()) {
+		return 0
+	}
+
+	return entry.pending
+}
+
+// Reset removes the local state of given key, and resets it on the remote store.
+func (cache *cache) Reset(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	entry := cache.load(key)
+	defer entry.mutex.Unlock()
+
+	cache.remove(key, entry)
+
+	return cache.remote.Reset(ctx, key, rate)
+}
+
+// Sync flushes every pending increments to the remote store and deletes any expired keys.
+func (cache *cache) Sync(ctx context.Context) {
+	now := cache.clock.Now()
+	cache.entries.Range(func(k interface{}, v interface{}) bool {
+		key := k.(string)
+		entry := v.(*entry)
+
+		entry.mutex.Lock()
+		defer entry.mutex.Unlock()
+
+		if entry.deleted {
+			return true
+		}
+
+		if entry.expired(now) {
+			// On failure, the entry is kept for the next attempt.
+			err := cache.expire(ctx, key, entry, now)
+			if err == nil {
+				cache.remove(key, entry)
+			}
+			return true
+		}
+
+		if entry.pending > 0 {
+			// On failure, pending increments are kept for the next attempt.
+			_ = cache.flush(ctx, key, entry)
+		}
+
+		return true
+	})
+}
+
+func maxInt64(a int64, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
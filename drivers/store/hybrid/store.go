@@ -0,0 +1,98 @@
+package hybrid
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/common"
+)
+
+// Store is a two-tier store: it counts increments in memory and flushes them in batch to a remote store,
+// such as the redis store, reading back the global count.
+//
+// Every instance sharing the same remote store may admit up to MaxDrift requests per key and window
+// that are not yet known by the other instances, so N instances can over-admit by at most N * MaxDrift.
+type Store struct {
+	// cache used to count increments locally.
+	cache *cache
+}
+
+// NewStore returns an instance of hybrid store with defaults.
+func NewStore(remote limiter.Store) limiter.Store {
+	return NewStoreWithOptions(remote, limiter.StoreOptions{
+		MaxDrift:     limiter.DefaultMaxDrift,
+		SyncInterval: limiter.DefaultSyncInterval,
+	})
+}
+
+// NewStoreWithOptions returns an instance of hybrid store with options.
+// Keys are given as is to the remote store, which is responsible for their prefix.
+func NewStoreWithOptions(remote limiter.Store, options limiter.StoreOptions) limiter.Store {
+	store := &Store{
+		cache: &cache{
+			remote:   remote,
+			maxDrift: options.MaxDrift,
+			clock:    options.Clock,
+		},
+	}
+
+	if store.cache.clock == nil {
+		store.cache.clock = limiter.SystemClock
+	}
+
+	if options.SyncInterval > 0 {
+		startSyncer(store.cache, options.SyncInterval)
+		runtime.SetFinalizer(store, stopSyncer)
+	}
+
+	return store
+}
+
+// Get returns the limit for given identifier.
+func (store *Store) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return store.Increment(ctx, key, 1, rate)
+}
+
+// Increment increments the limit by given count & returns the new limit value for given identifier.
+func (store *Store) Increment(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
+	newCount, expiration, err := store.cache.Increment(ctx, key, count, rate)
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	lctx := common.GetContextFromState(store.cache.clock.Now(), rate, expiration, newCount)
+	return lctx, nil
+}
+
+// Peek returns the limit for given identifier, without modification on current values.
+// Increments that are not flushed yet are included.
+func (store *Store) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	lctx, err := store.cache.remote.Peek(ctx, key, rate)
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	pending := store.cache.Pending(key)
+	if pending == 0 {
+		return lctx, nil
+	}
+
+	count := rate.Limit - lctx.Remaining + pending
+	if lctx.Reached {
+		count = rate.Limit + 1
+	}
+
+	return common.GetContextFromState(store.cache.clock.Now(), rate, time.Unix(lctx.Reset, 0), count), nil
+}
+
+// Reset returns the limit for given identifier which is set to zero.
+func (store *Store) Reset(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return store.cache.Reset(ctx, key, rate)
+}
+
+// Sync flushes every pending increments to the remote store.
+func (store *Store) Sync(ctx context.Context) {
+	store.cache.Sync(ctx)
+}
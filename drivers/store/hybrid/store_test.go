@@ -0,0 +1,162 @@
+package hybrid_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/hybrid"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
+)
+
+func TestHybridStoreSequentialAccess(t *testing.T) {
+	tests.TestStoreSequentialAccess(t, hybrid.NewStoreWithOptions(newRemoteStore("sequential-test"), limiter.StoreOptions{
+		MaxDrift:     10,
+		SyncInterval: 1 * time.Second,
+	}))
+}
+
+func TestHybridStoreConcurrentAccess(t *testing.T) {
+	tests.TestStoreConcurrentAccess(t, hybrid.NewStoreWithOptions(newRemoteStore("concurrent-test"), limiter.StoreOptions{
+		MaxDrift:     10,
+		SyncInterval: 10 * time.Millisecond,
+	}))
+}
+
+func TestHybridStoreOverAdmission(t *testing.T) {
+	maxDrift := int64(10)
+
+	for _, instances := range []int{1, 2, 4} {
+		remote := newRemoteStore("over-admission-test")
+		stores := make([]limiter.Store, instances)
+		for i := range stores {
+			stores[i] = hybrid.NewStoreWithOptions(remote, limiter.StoreOptions{
+				MaxDrift: maxDrift,
+			})
+		}
+
+		tests.TestStoreOverAdmission(t, stores, int64(instances)*maxDrift)
+	}
+}
+
+func TestHybridStoreWindowRollover(t *testing.T) {
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	remote := memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix: "limiter:hybrid:window-rollover-test",
+		Clock:  clock,
+	})
+
+	tests.TestStoreWindowRollover(t, hybrid.NewStoreWithOptions(remote, limiter.StoreOptions{
+		MaxDrift: 10,
+		Clock:    clock,
+	}), clock)
+}
+
+func TestHybridStoreSync(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	rate := limiter.Rate{
+		Limit:  100,
+		Period: time.Minute,
+	}
+
+	remote := newRemoteStore("sync-test")
+	store := hybrid.NewStoreWithOptions(remote, limiter.StoreOptions{
+		MaxDrift: 10,
+	})
+
+	// The first increment is flushed to obtain the window expiration.
+	for i := 0; i < 5; i++ {
+		_, err := store.Get(ctx, "foo", rate)
+		is.NoError(err)
+	}
+
+	lctx, err := remote.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(99), lctx.Remaining)
+
+	lctx, err = store.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(95), lctx.Remaining)
+
+	store.(*hybrid.Store).Sync(ctx)
+
+	lctx, err = remote.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(95), lctx.Remaining)
+
+	// Reaching the maximum drift flushes synchronously.
+	for i := 0; i < 10; i++ {
+		_, err = store.Get(ctx, "foo", rate)
+		is.NoError(err)
+	}
+
+	lctx, err = remote.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(85), lctx.Remaining)
+}
+
+func TestHybridStoreExpiredPending(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	rate := limiter.Rate{
+		Limit:  100,
+		Period: time.Minute,
+	}
+
+	for _, sync := range []bool{false, true} {
+		clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 500000000, time.UTC))
+		remote := memory.NewStoreWithOptions(limiter.StoreOptions{
+			Prefix: "limiter:hybrid:expired-pending-test",
+			Clock:  clock,
+		})
+		store := hybrid.NewStoreWithOptions(remote, limiter.StoreOptions{
+			MaxDrift: 10,
+			Clock:    clock,
+		})
+
+		for i := 0; i < 4; i++ {
+			_, err := store.Get(ctx, "foo", rate)
+			is.NoError(err)
+		}
+
+		// The local window expires first, since its expiration is truncated to the second: increments which
+		// are not flushed yet still reach the remote store.
+		clock.Advance(time.Minute)
+
+		expected := int64(96)
+		if sync {
+			store.(*hybrid.Store).Sync(ctx)
+		} else {
+			_, err := store.Get(ctx, "foo", rate)
+			is.NoError(err)
+			expected--
+		}
+
+		lctx, err := remote.Peek(ctx, "foo", rate)
+		is.NoError(err)
+		is.Equal(expected, lctx.Remaining)
+	}
+}
+
+func BenchmarkHybridStoreSequentialAccess(b *testing.B) {
+	tests.BenchmarkStoreSequentialAccess(b, hybrid.NewStore(newRemoteStore("sequential-benchmark")))
+}
+
+func BenchmarkHybridStoreConcurrentAccess(b *testing.B) {
+	tests.BenchmarkStoreConcurrentAccess(b, hybrid.NewStore(newRemoteStore("concurrent-benchmark")))
+}
+
+func newRemoteStore(name string) limiter.Store {
+	return memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:          "limiter:hybrid:" + name,
+		CleanUpInterval: 30 * time.Second,
+	})
+}
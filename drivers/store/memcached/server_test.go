@@ -0,0 +1,223 @@
+package memcached_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ulule/limiter/v3"
+)
+
+// fakeItem is an item stored by a fake server.
+type fakeItem struct {
+	value      []byte
+	flags      uint32
+	expiration time.Time
+}
+
+// fakeServer is a tiny in-process memcached server, which understands the subset of the text protocol
+// used by the store.
+type fakeServer struct {
+	mutex    sync.Mutex
+	items    map[string]fakeItem
+	clock    limiter.Clock
+	listener net.Listener
+}
+
+// newFakeServer starts a fake server on a random port, using given clock to expire items.
+func newFakeServer(clock limiter.Clock) (*fakeServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	server := &fakeServer{
+		items:    map[string]fakeItem{},
+		clock:    clock,
+		listener: listener,
+	}
+
+	go server.serve()
+
+	return server, nil
+}
+
+// Addr returns the address of the server.
+func (server *fakeServer) Addr() string {
+	return server.listener.Addr().String()
+}
+
+// Close stops the server.
+func (server *fakeServer) Close() error {
+	return server.listener.Close()
+}
+
+func (server *fakeServer) serve() {
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go server.handle(conn)
+	}
+}
+
+func (server *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		err = server.execute(rw, strings.Fields(line))
+		if err != nil {
+			return
+		}
+
+		err = rw.Flush()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// execute runs given command and writes its response.
+func (server *fakeServer) execute(rw *bufio.ReadWriter, fields []string) error {
+	if len(fields) == 0 {
+		_, err := rw.WriteString("ERROR\r\n")
+		return err
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	now := server.clock.Now()
+
+	switch fields[0] {
+	case "get", "gets":
+		for _, key := range fields[1:] {
+			item, ok := server.load(key, now)
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(rw, "VALUE %s %d %d 0\r\n%s\r\n", key, item.flags, len(item.value), item.value)
+		}
+		_, err := rw.WriteString("END\r\n")
+		return err
+
+	case "set", "add", "replace":
+		if len(fields) < 5 {
+			_, err := rw.WriteString("ERROR\r\n")
+			return err
+		}
+
+		flags, _ := strconv.ParseUint(fields[2], 10, 32)
+		exptime, _ := strconv.ParseInt(fields[3], 10, 64)
+		size, _ := strconv.Atoi(fields[4])
+
+		data := make([]byte, size+2)
+		_, err := io.ReadFull(rw, data)
+		if err != nil {
+			return err
+		}
+
+		_, exists := server.load(fields[1], now)
+		if (fields[0] == "add" && exists) || (fields[0] == "replace" && !exists) {
+			_, err = rw.WriteString("NOT_STORED\r\n")
+			return err
+		}
+
+		server.items[fields[1]] = fakeItem{
+			value:      data[:size],
+			flags:      uint32(flags),
+			expiration: getExpiration(now, exptime),
+		}
+		_, err = rw.WriteString("STORED\r\n")
+		return err
+
+	case "incr", "decr":
+		item, ok := server.load(fields[1], now)
+		if !ok {
+			_, err := rw.WriteString("NOT_FOUND\r\n")
+			return err
+		}
+
+		value, err := strconv.ParseUint(string(item.value), 10, 64)
+		if err != nil {
+			_, err = rw.WriteString("CLIENT_ERROR cannot increment or decrement non-numeric value\r\n")
+			return err
+		}
+
+		delta, _ := strconv.ParseUint(fields[2], 10, 64)
+		if fields[0] == "incr" {
+			value += delta
+		} else if delta > value {
+			value = 0
+		} else {
+			value -= delta
+		}
+
+		item.value = []byte(strconv.FormatUint(value, 10))
+		server.items[fields[1]] = item
+		_, err = fmt.Fprintf(rw, "%d\r\n", value)
+		return err
+
+	case "delete":
+		_, ok := server.load(fields[1], now)
+		if !ok {
+			_, err := rw.WriteString("NOT_FOUND\r\n")
+			return err
+		}
+
+		delete(server.items, fields[1])
+		_, err := rw.WriteString("DELETED\r\n")
+		return err
+
+	case "flush_all":
+		server.items = map[string]fakeItem{}
+		_, err := rw.WriteString("OK\r\n")
+		return err
+
+	default:
+		_, err := rw.WriteString("ERROR\r\n")
+		return err
+	}
+}
+
+// load returns the item of given key, if it exists and has not expired.
+// The caller must hold the server mutex.
+func (server *fakeServer) load(key string, now time.Time) (fakeItem, bool) {
+	item, ok := server.items[key]
+	if !ok {
+		return fakeItem{}, false
+	}
+
+	if !item.expiration.IsZero() && !now.Before(item.expiration) {
+		delete(server.items, key)
+		return fakeItem{}, false
+	}
+
+	return item, true
+}
+
+// getExpiration returns the expiration of an item, given as a number of seconds or a unix timestamp
+// like memcached does.
+func getExpiration(now time.Time, exptime int64) time.Time {
+	switch {
+	case exptime == 0:
+		return time.Time{}
+	case exptime > 30*24*3600:
+		return time.Unix(exptime, 0)
+	default:
+		return now.Add(time.Duration(exptime) * time.Second)
+	}
+}
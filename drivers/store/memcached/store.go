@@ -0,0 +1,271 @@
+package memcached
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/common"
+)
+
+// maxRelativeExpiration is the longest expiration memcached accepts as a number of seconds:
+// longer ones must be given as a unix timestamp.
+const maxRelativeExpiration = 30 * 24 * time.Hour
+
+// Client is an interface thats allows to use a memcached client, such as *memcache.Client.
+type Client interface {
+	Get(key string) (*memcache.Item, error)
+	GetMulti(keys []string) (map[string]*memcache.Item, error)
+	Set(item *memcache.Item) error
+	Add(item *memcache.Item) error
+	Increment(key string, delta uint64) (uint64, error)
+	Decrement(key string, delta uint64) (uint64, error)
+	Delete(key string) error
+}
+
+// Store is the memcached store.
+//
+// Memcached can't read back the expiration of a key, so the start of each window is stored in another key,
+// alongside the counter. A window is started by the caller which adds its counter, then sets its start.
+// Memcached expirations have a one second resolution, so periods are rounded up to the second.
+type Store struct {
+	// Prefix used for the key.
+	Prefix string
+	// MaxRetry is the maximum number of attempts to increment a counter, when a concurrent caller starts
+	// the same window.
+	MaxRetry int
+	// client used to communicate with memcached server.
+	client Client
+	// clock used to compute windows.
+	clock limiter.Clock
+}
+
+// NewStore returns an instance of memcached store with defaults.
+func NewStore(client Client) (limiter.Store, error) {
+	return NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix:   limiter.DefaultPrefix,
+		MaxRetry: limiter.DefaultMaxRetry,
+	})
+}
+
+// NewStoreWithOptions returns an instance of memcached store with options.
+func NewStoreWithOptions(client Client, options limiter.StoreOptions) (limiter.Store, error) {
+	store := &Store{
+		Prefix:   options.Prefix,
+		MaxRetry: options.MaxRetry,
+		client:   client,
+		clock:    options.Clock,
+	}
+
+	if store.MaxRetry <= 0 {
+		store.MaxRetry = limiter.DefaultMaxRetry
+	}
+
+	if store.clock == nil {
+		store.clock = limiter.SystemClock
+	}
+
+	return store, nil
+}
+
+// Get returns the limit for given identifier.
+func (store *Store) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return store.Increment(ctx, key, 1, rate)
+}
+
+// Increment increments the limit by given count & gives back the new limit for given identifier.
+func (store *Store) Increment(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
+	counterKey, windowKey := store.getCacheKeys(key)
+
+	for i := 0; i < store.MaxRetry; i++ {
+		now := store.clock.Now()
+
+		value, err := store.increment(counterKey, count)
+		if err == nil {
+			expiration, err := store.getExpiration(windowKey, now, rate)
+			if err != nil {
+				return limiter.Context{}, err
+			}
+
+			return common.GetContextFromState(now, rate, expiration, value), nil
+		}
+		if err != memcache.ErrCacheMiss {
+			return limiter.Context{}, errors.Wrap(err, "cannot increment counter")
+		}
+
+		// The counter has expired: try to start a new window.
+		started, err := store.startWindow(counterKey, windowKey, count, now, rate)
+		if err != nil {
+			return limiter.Context{}, err
+		}
+		if started {
+			return common.GetContextFromState(now, rate, now.Add(rate.Period), count), nil
+		}
+
+		// A concurrent caller has started the window first: increment its counter.
+	}
+
+	return limiter.Context{}, errors.New("cannot increment counter: too many concurrent windows started")
+}
+
+// Peek returns the limit for given identifier, without modification on current values.
+func (store *Store) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	counterKey, windowKey := store.getCacheKeys(key)
+	now := store.clock.Now()
+
+	items, err := store.client.GetMulti([]string{counterKey, windowKey})
+	if err != nil {
+		return limiter.Context{}, errors.Wrap(err, "cannot get counter")
+	}
+
+	item, ok := items[counterKey]
+	if !ok {
+		return common.GetContextFromState(now, rate, now.Add(rate.Period), 0), nil
+	}
+
+	count, err := parseInt(item)
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	expiration, err := getExpirationFromItem(items[windowKey], now, rate)
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	return common.GetContextFromState(now, rate, expiration, count), nil
+}
+
+// Reset returns the limit for given identifier which is set to zero.
+func (store *Store) Reset(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	counterKey, windowKey := store.getCacheKeys(key)
+
+	for _, key := range []string{counterKey, windowKey} {
+		err := store.client.Delete(key)
+		if err != nil && err != memcache.ErrCacheMiss {
+			return limiter.Context{}, errors.Wrap(err, "cannot reset counter")
+		}
+	}
+
+	now := store.clock.Now()
+	return common.GetContextFromState(now, rate, now.Add(rate.Period), 0), nil
+}
+
+// increment increments the counter of given key by given count, which may be negative.
+// A decrement never goes below zero.
+func (store *Store) increment(counterKey string, count int64) (int64, error) {
+	if count < 0 {
+		value, err := store.client.Decrement(counterKey, uint64(-count))
+		return int64(value), err
+	}
+
+	value, err := store.client.Increment(counterKey, uint64(count))
+	return int64(value), err
+}
+
+// startWindow adds the counter of a new window with given count, and then sets its start.
+// It returns false if the counter has been added by a concurrent caller.
+func (store *Store) startWindow(counterKey string, windowKey string, count int64, now time.Time,
+	rate limiter.Rate) (bool, error) {
+
+	if count < 0 {
+		count = 0
+	}
+
+	expiration := getItemExpiration(now, rate.Period)
+
+	err := store.client.Add(&memcache.Item{
+		Key:        counterKey,
+		Value:      []byte(strconv.FormatInt(count, 10)),
+		Expiration: expiration,
+	})
+	if err == memcache.ErrNotStored {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "cannot start window")
+	}
+
+	// The start is set after the counter, so that it never expires before it and overwrites any stale one.
+	err = store.client.Set(&memcache.Item{
+		Key:        windowKey,
+		Value:      []byte(strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10)),
+		Expiration: expiration,
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "cannot start window")
+	}
+
+	return true, nil
+}
+
+// getExpiration returns the expiration of the current window, using its start.
+func (store *Store) getExpiration(windowKey string, now time.Time, rate limiter.Rate) (time.Time, error) {
+	item, err := store.client.Get(windowKey)
+	if err == memcache.ErrCacheMiss {
+		return getExpirationFromItem(nil, now, rate)
+	}
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "cannot get window")
+	}
+
+	return getExpirationFromItem(item, now, rate)
+}
+
+// getCacheKeys returns the full path of the counter and of the window start for an identifier.
+// Counters are separated from their prefix by a colon, and window starts by a tilde, so an identifier can't
+// have the same key as the window start of another one.
+func (store *Store) getCacheKeys(key string) (string, string) {
+	return store.Prefix + ":" + key, store.Prefix + "~" + key
+}
+
+// getExpirationFromItem returns the expiration of the window which started at the time given by item.
+// If the start is missing, or stale because the window has just been started by a concurrent caller,
+// the window is assumed to start now.
+func getExpirationFromItem(item *memcache.Item, now time.Time, rate limiter.Rate) (time.Time, error) {
+	if item == nil {
+		return now.Add(rate.Period), nil
+	}
+
+	start, err := parseInt(item)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	expiration := time.Unix(0, start*int64(time.Millisecond)).Add(rate.Period)
+	if expiration.Before(now) {
+		return now.Add(rate.Period), nil
+	}
+
+	return expiration, nil
+}
+
+// getItemExpiration returns the expiration of an item which lives for given period, as expected by memcached:
+// a number of seconds, rounded up, or a unix timestamp for periods longer than 30 days.
+func getItemExpiration(now time.Time, period time.Duration) int32 {
+	seconds := int64((period + time.Second - 1) / time.Second)
+	if seconds <= 0 {
+		seconds = 1
+	}
+
+	if period > maxRelativeExpiration {
+		return int32(now.Unix() + seconds)
+	}
+
+	return int32(seconds)
+}
+
+// parseInt returns the value of given item as an integer.
+func parseInt(item *memcache.Item) (int64, error) {
+	value, err := strconv.ParseInt(strings.TrimSpace(string(item.Value)), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid value for key '%s'", item.Key)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,174 @@
+package memcached_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memcached"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
+)
+
+func TestMemcachedStoreSequentialAccess(t *testing.T) {
+	tests.TestStoreSequentialAccess(t, newStore(t, limiter.StoreOptions{
+		Prefix: "limiter:memcached:sequential-test",
+	}))
+}
+
+func TestMemcachedStoreConcurrentAccess(t *testing.T) {
+	tests.TestStoreConcurrentAccess(t, newStore(t, limiter.StoreOptions{
+		Prefix: "limiter:memcached:concurrent-test",
+	}))
+}
+
+func TestMemcachedStoreBatchAccess(t *testing.T) {
+	tests.TestStoreBatchAccess(t, newStore(t, limiter.StoreOptions{
+		Prefix: "limiter:memcached:batch-test",
+	}))
+}
+
+func TestMemcachedStoreOverAdmission(t *testing.T) {
+	tests.TestStoreOverAdmission(t, []limiter.Store{newStore(t, limiter.StoreOptions{
+		Prefix: "limiter:memcached:over-admission-test",
+	})}, 0)
+}
+
+func TestMemcachedStoreWindowRollover(t *testing.T) {
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	tests.TestStoreWindowRollover(t, newStoreWithClock(t, clock, limiter.StoreOptions{
+		Prefix: "limiter:memcached:window-rollover-test",
+	}), clock)
+}
+
+func TestMemcachedStoreWindowStart(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	_, client := newServer(t, clock)
+
+	store, err := memcached.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix: "limiter:memcached:window-start-test",
+		Clock:  clock,
+	})
+	is.NoError(err)
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Minute,
+	}
+
+	start := clock.Now()
+
+	lctx, err := store.Increment(ctx, "foo", 2, rate)
+	is.NoError(err)
+	is.Equal(int64(8), lctx.Remaining)
+
+	// The window start is stored alongside the counter.
+	item, err := client.Get("limiter:memcached:window-start-test~foo")
+	is.NoError(err)
+	is.Equal([]byte("1609502400000"), item.Value)
+
+	item, err = client.Get("limiter:memcached:window-start-test:foo")
+	is.NoError(err)
+	is.Equal([]byte("2"), item.Value)
+
+	// The expiration is read back from the window start.
+	clock.Advance(20 * time.Second)
+
+	lctx, err = store.Get(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(7), lctx.Remaining)
+	is.Equal(start.Add(time.Minute).Unix(), lctx.Reset)
+
+	lctx, err = store.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(7), lctx.Remaining)
+	is.Equal(start.Add(time.Minute).Unix(), lctx.Reset)
+
+	// A negative count is a decrement, which never goes below zero.
+	lctx, err = store.Increment(ctx, "foo", -1, rate)
+	is.NoError(err)
+	is.Equal(int64(8), lctx.Remaining)
+
+	lctx, err = store.Increment(ctx, "foo", -10, rate)
+	is.NoError(err)
+	is.Equal(int64(10), lctx.Remaining)
+
+	// A missing window start is assumed to start now.
+	err = client.Delete("limiter:memcached:window-start-test~foo")
+	is.NoError(err)
+
+	lctx, err = store.Get(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(9), lctx.Remaining)
+	is.Equal(clock.Now().Add(time.Minute).Unix(), lctx.Reset)
+
+	// Both keys are deleted on reset.
+	_, err = store.Reset(ctx, "foo", rate)
+	is.NoError(err)
+
+	_, err = client.Get("limiter:memcached:window-start-test:foo")
+	is.Equal(memcache.ErrCacheMiss, err)
+
+	// An identifier never shares its key with the window start of another one.
+	_, err = store.Get(ctx, "foo", rate)
+	is.NoError(err)
+
+	lctx, err = store.Increment(ctx, "foo:start", 3, rate)
+	is.NoError(err)
+	is.Equal(int64(7), lctx.Remaining)
+
+	lctx, err = store.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(9), lctx.Remaining)
+	is.Equal(clock.Now().Add(time.Minute).Unix(), lctx.Reset)
+}
+
+func BenchmarkMemcachedStoreSequentialAccess(b *testing.B) {
+	tests.BenchmarkStoreSequentialAccess(b, newStore(b, limiter.StoreOptions{
+		Prefix: "limiter:memcached:sequential-benchmark",
+	}))
+}
+
+func BenchmarkMemcachedStoreConcurrentAccess(b *testing.B) {
+	tests.BenchmarkStoreConcurrentAccess(b, newStore(b, limiter.StoreOptions{
+		Prefix: "limiter:memcached:concurrent-benchmark",
+	}))
+}
+
+// newStore returns a memcached store using a new fake server.
+func newStore(tb testing.TB, options limiter.StoreOptions) limiter.Store {
+	return newStoreWithClock(tb, limiter.SystemClock, options)
+}
+
+// newStoreWithClock returns a memcached store using a new fake server, both using given clock.
+func newStoreWithClock(tb testing.TB, clock limiter.Clock, options limiter.StoreOptions) limiter.Store {
+	_, client := newServer(tb, clock)
+
+	options.Clock = clock
+	store, err := memcached.NewStoreWithOptions(client, options)
+	require.NoError(tb, err)
+
+	return store
+}
+
+// newServer starts a fake server using given clock, stopped at the end of the test, and returns a client for it.
+func newServer(tb testing.TB, clock limiter.Clock) (*fakeServer, *memcache.Client) {
+	server, err := newFakeServer(clock)
+	require.NoError(tb, err)
+	tb.Cleanup(func() {
+		require.NoError(tb, server.Close())
+	})
+
+	client := memcache.New(server.Addr())
+	client.MaxIdleConns = 512
+	client.Timeout = time.Second
+
+	return server, client
+}
@@ -0,0 +1,223 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/common"
+)
+
+// maxIncrementAllAttempts is the number of attempts of an atomic increment on a cache whose counters keep being
+// deleted before being locked.
+const maxIncrementAllAttempts = 8
+
+// IncrementAll increments the limit of given identifiers of given group by given count, each one with the rate at
+// the same index, only if none of them would exceed its limit. Counters are locked in the order of their keys, so
+// concurrent calls sharing identifiers never deadlock, and they exclude the increments of a single identifier.
+func (store *Store) IncrementAll(ctx context.Context, group string, keys []string, count int64,
+	rates []limiter.Rate) ([]limiter.Context, error) {
+
+	err := common.CheckIncrementAll(keys, rates)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKeys := make([]string, len(keys))
+	durations := make([]time.Duration, len(keys))
+	limits := make([]int64, len(keys))
+	for i := range keys {
+		cacheKeys[i] = store.getCacheKey(group + ":" + keys[i])
+		durations[i] = rates[i].Period
+		limits[i] = rates[i].Limit
+	}
+
+	values, expirations, admitted, err := store.cache.incrementAll(cacheKeys, count, durations, limits)
+	if err != nil {
+		contexts := make([]limiter.Context, len(keys))
+		for i := range keys {
+			lctx, overflow := store.onOverflow(rates[i], err)
+			if overflow != nil {
+				return nil, overflow
+			}
+			contexts[i] = lctx
+		}
+		return contexts, nil
+	}
+
+	now := store.clock.Now()
+	contexts := make([]limiter.Context, len(keys))
+	for i := range keys {
+		contexts[i] = common.GetContextFromState(now, rates[i], expirations[i], values[i])
+		if !admitted && values[i]+count > rates[i].Limit {
+			contexts[i].Reached = true
+		}
+	}
+
+	return contexts, nil
+}
+
+// incrementAll increments given value on every key, with the duration at the same index, only if none of them
+// would exceed the limit at the same index. It returns the value and expiration of every key, and whether they
+// have been incremented. Keys must be unique.
+func (cache *Cache) incrementAll(keys []string, value int64, durations []time.Duration,
+	limits []int64) ([]int64, []time.Time, bool, error) {
+
+	now, wall := cache.clock.Monotonic(), cache.clock.Now()
+	order := lockOrder(keys)
+
+	// Counters deleted before being locked must be loaded again. If the cache is too small to hold all of them,
+	// they keep evicting each other, so the cache is considered full after a few attempts.
+	for attempt := 1; ; attempt++ {
+		counters := make([]*Counter, len(keys))
+		for i := range keys {
+			// A missing counter is created with its expiration, so it can't be cleaned before being locked.
+			counter, _, err := cache.loadOrInsert(keys[i], &Counter{
+				expiration: now + durations[i].Nanoseconds(),
+			}, true)
+			if err != nil {
+				return nil, nil, false, err
+			}
+			cache.touch(counter)
+			counters[i] = counter
+		}
+
+		deleted := false
+		for _, i := range order {
+			counters[i].mutex.Lock()
+			deleted = deleted || counters[i].deleted
+		}
+
+		if deleted {
+			for _, i := range order {
+				counters[i].mutex.Unlock()
+			}
+			if attempt >= maxIncrementAllAttempts {
+				return nil, nil, false, limiter.ErrStoreFull
+			}
+			continue
+		}
+
+		values, expirations, admitted := incrementLocked(now, value, durations, limits,
+			func(i int) (*int64, *int64) {
+				return &counters[i].value, &counters[i].expiration
+			})
+
+		for _, i := range order {
+			counters[i].mutex.Unlock()
+		}
+
+		return values, wallTimes(now, wall, expirations), admitted, nil
+	}
+}
+
+// incrementAll increments given value on every key, with the duration at the same index, only if none of them
+// would exceed the limit at the same index. It returns the value and expiration of every key, and whether they
+// have been incremented. Keys must be unique. A sharded cache is never full, so it never returns an error.
+func (cache *ShardedCache) incrementAll(keys []string, value int64, durations []time.Duration,
+	limits []int64) ([]int64, []time.Time, bool, error) {
+
+	now, wall := cache.clock.Monotonic(), cache.clock.Now()
+	order := lockOrder(keys)
+
+	// Counters deleted before being locked must be loaded again.
+	for {
+		counters := make([]*atomicCounter, len(keys))
+		for i := range keys {
+			// A missing counter is created with its expiration, so it can't be cleaned before being locked.
+			counters[i], _ = cache.getShard(keys[i]).loadOrCreate(keys[i], 0, now+durations[i].Nanoseconds())
+		}
+
+		deleted := false
+		for _, i := range order {
+			counters[i].mutex.Lock()
+			deleted = deleted || counters[i].deleted
+		}
+
+		if deleted {
+			for _, i := range order {
+				counters[i].mutex.Unlock()
+			}
+			continue
+		}
+
+		// Counters are read and written atomically, since they're loaded without lock.
+		values, expirations, admitted := incrementLocked(now, value, durations, limits,
+			func(i int) (*int64, *int64) {
+				return &counters[i].value, &counters[i].expiration
+			})
+
+		for _, i := range order {
+			counters[i].mutex.Unlock()
+		}
+
+		return values, wallTimes(now, wall, expirations), admitted, nil
+	}
+}
+
+// incrementLocked increments given value on every counter, whose value and expiration are given by counter,
+// with the duration at the same index, only if none of them would exceed the limit at the same index.
+// Every counter must be locked. It returns their value and expiration, and whether they have been incremented.
+func incrementLocked(now int64, value int64, durations []time.Duration, limits []int64,
+	counter func(i int) (*int64, *int64)) ([]int64, []int64, bool) {
+
+	values := make([]int64, len(durations))
+	expirations := make([]int64, len(durations))
+	expired := make([]bool, len(durations))
+	admitted := true
+
+	for i := range durations {
+		current, expiration := counter(i)
+		expirations[i] = atomic.LoadInt64(expiration)
+		expired[i] = expirations[i] == 0 || now > expirations[i]
+
+		if expired[i] {
+			expirations[i] = now + durations[i].Nanoseconds()
+		} else {
+			values[i] = atomic.LoadInt64(current)
+		}
+
+		if values[i]+value > limits[i] {
+			admitted = false
+		}
+	}
+
+	if !admitted {
+		return values, expirations, false
+	}
+
+	for i := range durations {
+		current, expiration := counter(i)
+		values[i] += value
+		atomic.StoreInt64(current, values[i])
+		atomic.StoreInt64(expiration, expirations[i])
+	}
+
+	return values, expirations, true
+}
+
+// lockOrder returns the indexes of given keys in the order of the keys, which is the order used to lock their
+// counters.
+func lockOrder(keys []string) []int {
+	indexes := make([]int, len(keys))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	sort.Slice(indexes, func(i, j int) bool {
+		return keys[indexes[i]] < keys[indexes[j]]
+	})
+
+	return indexes
+}
+
+// wallTimes converts given monotonic expirations to wall clock times.
+func wallTimes(now int64, wall time.Time, expirations []int64) []time.Time {
+	times := make([]time.Time, len(expirations))
+	for i := range expirations {
+		times[i] = wallTime(now, wall, expirations[i])
+	}
+	return times
+}
@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+)
+
+func TestIncrementAllWhileCleaning(t *testing.T) {
+	caches := map[string]counters{
+		"cache":   newCache(0, nil, newFakeClock()),
+		"sharded": newShardedCache(1, 0, newFakeClock()),
+	}
+
+	for name, cache := range caches {
+		is := require.New(t)
+		keys := []string{"foo", "bar"}
+		durations := []time.Duration{time.Minute, time.Minute}
+		limits := []int64{1000000, 1000000}
+
+		// Counters created by a rejected call keep their expiration, so they're not cleaned.
+		_, _, admitted, err := cache.incrementAll(keys, 1, durations, []int64{10, 0})
+		is.NoError(err)
+		is.False(admitted, name)
+
+		clean(cache)
+
+		values, _, admitted, err := cache.incrementAll(keys, 1, durations, limits)
+		is.NoError(err)
+		is.True(admitted, name)
+		is.Equal([]int64{1, 1}, values, name)
+
+		// The cleaner never drops the hits of new counters created concurrently.
+		done := make(chan struct{})
+		cleaned := make(chan struct{})
+		go func() {
+			defer close(cleaned)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					clean(cache)
+				}
+			}
+		}()
+
+		goroutines := 4
+		wg := &sync.WaitGroup{}
+		wg.Add(goroutines)
+		for i := 0; i < goroutines; i++ {
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 500; j++ {
+					keys := []string{fmt.Sprint("foo-", j), fmt.Sprint("bar-", j)}
+					_, _, _, err := cache.incrementAll(keys, 1, durations, limits)
+					is.NoError(err)
+				}
+			}()
+		}
+		wg.Wait()
+		close(done)
+		<-cleaned
+
+		for j := 0; j < 500; j++ {
+			for _, key := range []string{fmt.Sprint("foo-", j), fmt.Sprint("bar-", j)} {
+				value, _ := cache.Get(key, time.Minute)
+				is.Equal(int64(goroutines), value, name)
+			}
+		}
+	}
+}
+
+func TestCacheIncrementAllEviction(t *testing.T) {
+	is := require.New(t)
+
+	cache := newCache(0, newEvictor(limiter.StoreOptions{
+		MaxKeys:  1,
+		Overflow: limiter.OverflowEvict,
+	}), newFakeClock())
+
+	// Counters evicting each other are given up, instead of being created again forever.
+	_, _, _, err := cache.incrementAll([]string{"foo", "bar"}, 1, []time.Duration{time.Minute, time.Minute},
+		[]int64{10, 10})
+	is.Equal(limiter.ErrStoreFull, err)
+
+	values, _, admitted, err := cache.incrementAll([]string{"foo"}, 1, []time.Duration{time.Minute}, []int64{10})
+	is.NoError(err)
+	is.True(admitted)
+	is.Equal([]int64{1}, values)
+}
+
+func TestShardedCacheIncrementAllWithIncrement(t *testing.T) {
+	is := require.New(t)
+
+	cache := newShardedCache(1, 0, newFakeClock())
+	keys := []string{"foo", "bar"}
+	durations := []time.Duration{time.Minute, time.Minute}
+	limits := []int64{100, 1000000}
+
+	// Increments of a single identifier can't be interleaved with an atomic increment, which never counts a hit
+	// above its limit.
+	goroutines := 4
+	admitted := int64(0)
+	wg := &sync.WaitGroup{}
+	wg.Add(2 * goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				values, _, ok, err := cache.incrementAll(keys, 1, durations, limits)
+				is.NoError(err)
+				if ok {
+					is.LessOrEqual(values[0], limits[0])
+					atomic.AddInt64(&admitted, 1)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				cache.Increment("foo", 1, time.Minute)
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, _ := cache.Get("foo", time.Minute)
+	is.Equal(int64(goroutines*50)+admitted, value)
+
+	value, _ = cache.Get("bar", time.Minute)
+	is.Equal(admitted, value)
+}
+
+// clean deletes the expired counters of given cache.
+func clean(cache counters) {
+	switch cache := cache.(type) {
+	case *CacheWrapper:
+		cache.Clean()
+	case *ShardedCacheWrapper:
+		cache.Clean()
+	}
+}
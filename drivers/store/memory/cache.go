@@ -2,8 +2,11 @@ package memory
 
 import (
 	"runtime"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/ulule/limiter/v3"
 )
 
 // Forked from https://github.com/patrickmn/go-cache
@@ -52,10 +55,15 @@ func startCleaner(cache *Cache, interval time.Duration) {
 }
 
 // Counter is a simple counter with an expiration.
+// Its expiration is kept as a monotonic clock value, using the clock of its cache, but its exported methods use
+// wall clock times in nanoseconds since the Unix epoch.
 type Counter struct {
 	mutex      sync.RWMutex
 	value      int64
 	expiration int64
+	referenced uint32
+	deleted    bool
+	clock      clock
 }
 
 // Value returns the counter current value.
@@ -68,58 +76,146 @@ func (counter *Counter) Value() int64 {
 // Expiration returns the counter expiration.
 func (counter *Counter) Expiration() int64 {
 	counter.mutex.RLock()
-	defer counter.mutex.RUnlock()
-	return counter.expiration
+	expiration := counter.expiration
+	counter.mutex.RUnlock()
+
+	if expiration == 0 {
+		return 0
+	}
+
+	clock := counter.getClock()
+	now, wall := clock.Monotonic(), clock.Now()
+	return wallTime(now, wall, expiration).UnixNano()
 }
 
 // Expired returns true if the counter has expired.
 func (counter *Counter) Expired() bool {
+	return counter.expired(counter.getClock().Monotonic())
+}
+
+// Load returns the value and the expiration of this counter.
+// If the counter is expired, it will use the given expiration.
+func (counter *Counter) Load(expiration int64) (int64, int64) {
+	clock := counter.getClock()
+	now, wall := clock.Monotonic(), clock.Now()
+
+	value, expiration := counter.load(now, monotonicTime(now, wall, expiration))
+	return value, wallTime(now, wall, expiration).UnixNano()
+}
+
+// Increment increments given value on this counter.
+// If the counter is expired, it will use the given expiration.
+// It returns its current value and expiration.
+// If the counter has expired and has been deleted from its cache, it's not restarted and given value and
+// expiration are returned.
+func (counter *Counter) Increment(value int64, expiration int64) (int64, int64) {
+	clock := counter.getClock()
+	now, wall := clock.Monotonic(), clock.Now()
+
+	current, next, ok := counter.increment(now, value, monotonicTime(now, wall, expiration))
+	if !ok {
+		return value, expiration
+	}
+
+	return current, wallTime(now, wall, next).UnixNano()
+}
+
+// getClock returns the clock of the cache of this counter, or the default clock if it's not in a cache.
+func (counter *Counter) getClock() clock {
+	if counter.clock == nil {
+		return fastClock{}
+	}
+	return counter.clock
+}
+
+// expired returns true if the counter has expired at given monotonic time.
+func (counter *Counter) expired(now int64) bool {
 	counter.mutex.RLock()
 	defer counter.mutex.RUnlock()
 
-	return counter.expiration == 0 || time.Now().UnixNano() > counter.expiration
+	return counter.expiration == 0 || now > counter.expiration
 }
 
-// Load returns the value and the expiration of this counter.
+// markDeleted marks the counter as deleted from its cache if it has expired at given monotonic time, or
+// unconditionally if force is true, so it can't be used to start a new window anymore.
+// It returns true if the counter has been marked.
+func (counter *Counter) markDeleted(now int64, force bool) bool {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	if !force && counter.expiration != 0 && now <= counter.expiration {
+		return false
+	}
+
+	counter.deleted = true
+	return true
+}
+
+// load returns the value and the expiration of this counter at given monotonic time.
 // If the counter is expired, it will use the given expiration.
-func (counter *Counter) Load(expiration int64) (int64, int64) {
+func (counter *Counter) load(now int64, expiration int64) (int64, int64) {
 	counter.mutex.RLock()
 	defer counter.mutex.RUnlock()
 
-	if counter.expiration == 0 || time.Now().UnixNano() > counter.expiration {
+	if counter.expiration == 0 || now > counter.expiration {
 		return 0, expiration
 	}
 
 	return counter.value, counter.expiration
 }
 
-// Increment increments given value on this counter.
+// increment increments given value on this counter at given monotonic time.
 // If the counter is expired, it will use the given expiration.
-// It returns its current value and expiration.
-func (counter *Counter) Increment(value int64, expiration int64) (int64, int64) {
+// It returns its current value and expiration, or false if the counter has expired and has been deleted from
+// its cache, in which case it must be loaded again.
+func (counter *Counter) increment(now int64, value int64, expiration int64) (int64, int64, bool) {
 	counter.mutex.Lock()
 	defer counter.mutex.Unlock()
 
-	if counter.expiration == 0 || time.Now().UnixNano() > counter.expiration {
+	if counter.expiration == 0 || now > counter.expiration {
+		if counter.deleted {
+			return 0, 0, false
+		}
+
 		counter.value = value
 		counter.expiration = expiration
-		return counter.value, counter.expiration
+		return counter.value, counter.expiration, true
 	}
 
 	counter.value += value
-	return counter.value, counter.expiration
+	return counter.value, counter.expiration, true
 }
 
 // Cache contains a collection of counters.
 type Cache struct {
 	counters sync.Map
 	cleaner  *cleaner
+	evictor  *evictor
+	clock    clock
 }
 
 // NewCache returns a new cache.
 func NewCache(cleanInterval time.Duration) *CacheWrapper {
+	return newCache(cleanInterval, nil, fastClock{})
+}
+
+// NewCacheWithOptions returns a new cache with options.
+// If MaxKeys or MaxBytes is defined, the cache is bounded and handles new keys according to Overflow.
+func NewCacheWithOptions(options limiter.StoreOptions) *CacheWrapper {
+	var evictor *evictor
+	if options.MaxKeys > 0 || options.MaxBytes > 0 {
+		evictor = newEvictor(options)
+	}
+
+	return newCache(options.CleanUpInterval, evictor, newClock(options))
+}
 
-	cache := &Cache{}
+// newCache returns a new cache using given clock, bounded if given evictor is defined.
+func newCache(cleanInterval time.Duration, evictor *evictor, clock clock) *CacheWrapper {
+	cache := &Cache{
+		evictor: evictor,
+		clock:   clock,
+	}
 	wrapper := &CacheWrapper{Cache: cache}
 
 	if cleanInterval > 0 {
@@ -131,9 +227,16 @@ func NewCache(cleanInterval time.Duration) *CacheWrapper {
 }
 
 // LoadOrStore returns the existing counter for the key if present.
-// Otherwise, it stores and returns the given counter.
+// Otherwise, it stores and returns the given counter, even if the cache is full.
 // The loaded result is true if the counter was loaded, false if stored.
 func (cache *Cache) LoadOrStore(key string, counter *Counter) (*Counter, bool) {
+	if cache.evictor != nil {
+		actual, loaded, _ := cache.loadOrInsert(key, counter, false)
+		return actual, loaded
+	}
+
+	cache.adopt(counter)
+
 	val, loaded := cache.counters.LoadOrStore(key, counter)
 	if val == nil {
 		return counter, false
@@ -154,14 +257,51 @@ func (cache *Cache) Load(key string) (*Counter, bool) {
 	return actual, true
 }
 
-// Store sets the counter for a key.
+// Store sets the counter for a key, even if the cache is full.
 func (cache *Cache) Store(key string, counter *Counter) {
+	cache.adopt(counter)
+
+	if cache.evictor != nil {
+		cache.evictor.mutex.Lock()
+		defer cache.evictor.mutex.Unlock()
+
+		cache.Delete(key)
+		cache.counters.Store(key, counter)
+		cache.evictor.add(key, counter)
+		return
+	}
+
+	previous, loaded := cache.Load(key)
+	if loaded {
+		previous.markDeleted(0, true)
+	}
 	cache.counters.Store(key, counter)
 }
 
 // Delete deletes the value for a key.
 func (cache *Cache) Delete(key string) {
-	cache.counters.Delete(key)
+	// The counter is marked before being deleted, so a concurrent increment can't start a new window on it.
+	counter, ok := cache.Load(key)
+	if !ok {
+		return
+	}
+	counter.markDeleted(0, true)
+	cache.remove(key, counter)
+}
+
+// remove deletes given counter of key, which must be marked as deleted. A counter which has replaced it
+// concurrently is kept.
+func (cache *Cache) remove(key string, counter *Counter) {
+	if cache.counters.CompareAndDelete(key, counter) && cache.evictor != nil {
+		cache.evictor.release(key)
+	}
+}
+
+// adopt makes given counter use the clock of the cache.
+func (cache *Cache) adopt(counter *Counter) {
+	if counter.clock == nil {
+		counter.clock = cache.clock
+	}
 }
 
 // Range calls handler sequentially for each key and value present in the cache.
@@ -183,50 +323,75 @@ func (cache *Cache) Range(handler func(key string, counter *Counter)) {
 
 // Increment increments given value on key.
 // If key is undefined or expired, it will create it.
+// If the cache is full and can't evict any key, the key is not created and given value is returned.
 func (cache *Cache) Increment(key string, value int64, duration time.Duration) (int64, time.Time) {
-	expiration := time.Now().Add(duration).UnixNano()
-
-	// If counter is in cache, try to load it first.
-	counter, loaded := cache.Load(key)
-	if loaded {
-		value, expiration = counter.Increment(value, expiration)
-		return value, time.Unix(0, expiration)
+	count, expiration, err := cache.increment(key, value, duration)
+	if err != nil {
+		return value, cache.clock.Now().Add(duration)
 	}
 
-	// If it's not in cache, try to atomically create it.
-	// We do that in two step to reduce memory allocation.
-	counter, loaded = cache.LoadOrStore(key, &Counter{
-		mutex:      sync.RWMutex{},
-		value:      value,
-		expiration: expiration,
-	})
-	if loaded {
-		value, expiration = counter.Increment(value, expiration)
-		return value, time.Unix(0, expiration)
-	}
+	return count, expiration
+}
+
+// increment increments given value on key.
+// If key is undefined or expired, it will create it.
+// If the cache is full and can't evict any key, it returns ErrStoreFull.
+func (cache *Cache) increment(key string, value int64, duration time.Duration) (int64, time.Time, error) {
+	now, wall := cache.clock.Monotonic(), cache.clock.Now()
+	expiration := now + duration.Nanoseconds()
 
-	// Otherwise, it has been created, return given value.
-	return value, time.Unix(0, expiration)
+	// A counter deleted while it's incremented must be loaded again.
+	for {
+		// If counter is in cache, try to load it first.
+		counter, loaded := cache.Load(key)
+		if !loaded {
+			// If it's not in cache, try to atomically create it.
+			// We do that in two step to reduce memory allocation.
+			var err error
+			counter, loaded, err = cache.loadOrInsert(key, &Counter{
+				mutex:      sync.RWMutex{},
+				value:      value,
+				expiration: expiration,
+			}, true)
+			if err != nil {
+				return 0, time.Time{}, err
+			}
+		}
+
+		// Otherwise, it has been created, return given value.
+		if !loaded {
+			return value, wallTime(now, wall, expiration), nil
+		}
+
+		cache.touch(counter)
+		count, current, ok := counter.increment(now, value, expiration)
+		if ok {
+			return count, wallTime(now, wall, current), nil
+		}
+	}
 }
 
 // Get returns key's value and expiration.
 func (cache *Cache) Get(key string, duration time.Duration) (int64, time.Time) {
-	expiration := time.Now().Add(duration).UnixNano()
+	now, wall := cache.clock.Monotonic(), cache.clock.Now()
+	expiration := now + duration.Nanoseconds()
 
 	counter, ok := cache.Load(key)
 	if !ok {
-		return 0, time.Unix(0, expiration)
+		return 0, wallTime(now, wall, expiration)
 	}
 
-	value, expiration := counter.Load(expiration)
-	return value, time.Unix(0, expiration)
+	cache.touch(counter)
+	value, expiration := counter.load(now, expiration)
+	return value, wallTime(now, wall, expiration)
 }
 
 // Clean will deleted any expired keys.
 func (cache *Cache) Clean() {
+	now := cache.clock.Monotonic()
 	cache.Range(func(key string, counter *Counter) {
-		if counter.Expired() {
-			cache.Delete(key)
+		if counter.markDeleted(now, false) {
+			cache.remove(key, counter)
 		}
 	})
 }
@@ -235,6 +400,25 @@ func (cache *Cache) Clean() {
 func (cache *Cache) Reset(key string, duration time.Duration) (int64, time.Time) {
 	cache.Delete(key)
 
-	expiration := time.Now().Add(duration).UnixNano()
-	return 0, time.Unix(0, expiration)
+	return 0, cache.clock.Now().Add(duration)
+}
+
+// scan calls handler for every counter whose key starts with given prefix and which has not expired yet,
+// until handler returns false.
+func (cache *Cache) scan(prefix string, handler func(key string, value int64, expiration time.Time) bool) {
+	now, wall := cache.clock.Monotonic(), cache.clock.Now()
+	stopped := false
+
+	cache.Range(func(key string, counter *Counter) {
+		if stopped || !strings.HasPrefix(key, prefix) {
+			return
+		}
+
+		value, expiration := counter.load(now, 0)
+		if expiration == 0 {
+			return
+		}
+
+		stopped = !handler(key, value, wallTime(now, wall, expiration))
+	})
 }
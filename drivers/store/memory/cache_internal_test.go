@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+)
+
+func TestCacheIncrementWhileCleaningSteps(t *testing.T) {
+	is := require.New(t)
+
+	clock := newFakeClock()
+	cache := newCache(0, nil, clock)
+
+	cache.Increment("foo", 5, time.Minute)
+
+	// An expired counter deleted by the cleaner can't start a new window, so the increment is retried.
+	clock.advance(2 * time.Minute)
+
+	counter, ok := cache.Load("foo")
+	is.True(ok)
+
+	cache.Clean()
+
+	_, _, ok = counter.increment(clock.Monotonic(), 1, clock.Monotonic()+int64(time.Minute))
+	is.False(ok)
+
+	value, _ := cache.Increment("foo", 1, time.Minute)
+	is.Equal(int64(1), value)
+
+	value, _ = cache.Get("foo", time.Minute)
+	is.Equal(int64(1), value)
+}
+
+func TestCacheDeleteReplacedSteps(t *testing.T) {
+	is := require.New(t)
+
+	cache := newCache(0, newEvictor(limiter.StoreOptions{MaxKeys: 10}), newFakeClock())
+
+	cache.Increment("foo", 5, time.Minute)
+
+	// A delete whose counter is replaced after being marked keeps the new counter, which is still accounted.
+	counter, ok := cache.Load("foo")
+	is.True(ok)
+	counter.markDeleted(0, true)
+
+	cache.Store("foo", &Counter{value: 1, expiration: cache.clock.Monotonic() + int64(time.Minute)})
+	cache.remove("foo", counter)
+
+	value, _ := cache.Get("foo", time.Minute)
+	is.Equal(int64(1), value)
+	is.Equal(int64(1), cache.evictor.keys)
+}
@@ -1,13 +1,16 @@
 package memory_test
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/ulule/limiter/v3"
 	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
 )
 
 func TestCacheIncrementSequential(t *testing.T) {
@@ -127,3 +130,205 @@ func TestCacheReset(t *testing.T) {
 	is.Equal(int64(2), x)
 	is.InEpsilon(deleted, expire.UnixNano(), epsilon)
 }
+
+func TestCacheMaxKeys(t *testing.T) {
+	is := require.New(t)
+
+	cache := memory.NewCacheWithOptions(limiter.StoreOptions{
+		MaxKeys: 3,
+	})
+	duration := 1 * time.Minute
+
+	for _, key := range []string{"a", "b", "c"} {
+		x, _ := cache.Increment(key, 1, duration)
+		is.Equal(int64(1), x)
+	}
+
+	// Referenced keys are given a second chance, so "b" is evicted first.
+	x, _ := cache.Increment("a", 1, duration)
+	is.Equal(int64(2), x)
+
+	x, _ = cache.Increment("d", 1, duration)
+	is.Equal(int64(1), x)
+
+	x, _ = cache.Get("b", duration)
+	is.Equal(int64(0), x)
+
+	x, _ = cache.Get("a", duration)
+	is.Equal(int64(2), x)
+
+	x, _ = cache.Get("c", duration)
+	is.Equal(int64(1), x)
+
+	x, _ = cache.Get("d", duration)
+	is.Equal(int64(1), x)
+
+	// The cache never holds more than MaxKeys keys.
+	x, _ = cache.Increment("e", 1, duration)
+	is.Equal(int64(1), x)
+
+	x, _ = cache.Increment("e", 1, duration)
+	is.Equal(int64(2), x)
+
+	x, _ = cache.Get("e", duration)
+	is.Equal(int64(2), x)
+
+	is.Equal(3, countKeys(cache))
+}
+
+func TestCacheMaxBytes(t *testing.T) {
+	is := require.New(t)
+
+	cache := memory.NewCacheWithOptions(limiter.StoreOptions{
+		MaxBytes: 4096,
+	})
+
+	for i := 0; i < 1000; i++ {
+		cache.Increment(fmt.Sprintf("key-%d", i), 1, time.Minute)
+	}
+
+	is.NotZero(countKeys(cache))
+	is.Less(countKeys(cache), 4096/128)
+
+	x, _ := cache.Get("key-999", time.Minute)
+	is.Equal(int64(1), x)
+}
+
+func TestCacheMaxKeysConcurrent(t *testing.T) {
+	is := require.New(t)
+
+	cache := memory.NewCacheWithOptions(limiter.StoreOptions{
+		MaxKeys:         100,
+		CleanUpInterval: 10 * time.Millisecond,
+	})
+
+	goroutines := 50
+	ops := 200
+
+	wg := &sync.WaitGroup{}
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(i int) {
+			for j := 0; j < ops; j++ {
+				cache.Increment(fmt.Sprintf("key-%d-%d", i, j), 1, time.Duration(j%3+1)*time.Millisecond)
+				if j%10 == 0 {
+					cache.Reset(fmt.Sprintf("key-%d-%d", i, j-5), time.Minute)
+				}
+			}
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+
+	is.LessOrEqual(countKeys(cache), 100)
+}
+
+func TestCacheMaxKeysReject(t *testing.T) {
+	is := require.New(t)
+
+	cache := memory.NewCacheWithOptions(limiter.StoreOptions{
+		MaxKeys:  2,
+		Overflow: limiter.OverflowReject,
+	})
+	duration := 1 * time.Minute
+
+	cache.Increment("a", 1, duration)
+	cache.Increment("b", 1, duration)
+
+	// The new key is not stored.
+	x, _ := cache.Increment("c", 1, duration)
+	is.Equal(int64(1), x)
+	x, _ = cache.Increment("c", 1, duration)
+	is.Equal(int64(1), x)
+
+	x, _ = cache.Increment("a", 1, duration)
+	is.Equal(int64(2), x)
+
+	cache.Reset("a", duration)
+
+	cache.Increment("c", 1, duration)
+	x, _ = cache.Increment("c", 1, duration)
+	is.Equal(int64(2), x)
+
+	is.Equal(2, countKeys(cache))
+}
+
+func TestCacheCleanWithClock(t *testing.T) {
+	is := require.New(t)
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	cache := memory.NewCacheWithOptions(limiter.StoreOptions{
+		Clock: clock,
+	})
+
+	cache.Increment("foo", 1, 10*time.Second)
+	cache.Increment("bar", 1, time.Minute)
+
+	cache.Clean()
+	is.Equal(2, countKeys(cache))
+
+	clock.Advance(11 * time.Second)
+	cache.Clean()
+	is.Equal(1, countKeys(cache))
+
+	x, expire := cache.Get("bar", time.Minute)
+	is.Equal(int64(1), x)
+	is.Equal(clock.Now().Add(49*time.Second), expire)
+
+	clock.Advance(time.Minute)
+	cache.Clean()
+	is.Equal(0, countKeys(cache))
+}
+
+func countKeys(cache *memory.CacheWrapper) int {
+	count := 0
+	cache.Range(func(key string, counter *memory.Counter) {
+		count++
+	})
+	return count
+}
+
+func TestCacheCounterClock(t *testing.T) {
+	is := require.New(t)
+
+	clock := tests.NewManualClock(time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC))
+	cache := memory.NewCacheWithOptions(limiter.StoreOptions{
+		Clock: clock,
+	})
+
+	cache.Increment("foo", 2, time.Minute)
+	counter, ok := cache.Load("foo")
+	is.True(ok)
+
+	// Expirations are wall clock times of the clock of the cache.
+	expiration := clock.Now().Add(time.Minute).UnixNano()
+	is.Equal(expiration, counter.Expiration())
+	is.False(counter.Expired())
+
+	value, current := counter.Load(0)
+	is.Equal(int64(2), value)
+	is.Equal(expiration, current)
+
+	value, current = counter.Increment(3, 0)
+	is.Equal(int64(5), value)
+	is.Equal(expiration, current)
+
+	clock.Advance(2 * time.Minute)
+	is.True(counter.Expired())
+
+	next := clock.Now().Add(time.Minute).UnixNano()
+	value, current = counter.Load(next)
+	is.Equal(int64(0), value)
+	is.Equal(next, current)
+
+	// Counters outside of a cache use the system clock.
+	counter = &memory.Counter{}
+	is.True(counter.Expired())
+
+	expiration = time.Now().Add(time.Minute).UnixNano()
+	value, current = counter.Increment(1, expiration)
+	is.Equal(int64(1), value)
+	is.Equal(expiration, current)
+	is.InDelta(expiration, counter.Expiration(), float64(time.Millisecond))
+	is.False(counter.Expired())
+}
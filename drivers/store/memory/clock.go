@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/internal/fasttime"
+)
+
+// clock gives the current time to a cache.
+// Expirations are computed with a monotonic clock, so that wall clock adjustments (ie: NTP) can't expire or
+// extend a window, and converted to wall clock time only when they are reported.
+type clock interface {
+	// Monotonic returns a monotonic clock value, in nanoseconds.
+	Monotonic() int64
+	// Now returns the current wall clock time.
+	Now() time.Time
+}
+
+// fastClock is the default clock, using a low-overhead monotonic clock.
+type fastClock struct{}
+
+// Monotonic returns a monotonic clock value, in nanoseconds.
+func (fastClock) Monotonic() int64 {
+	return fasttime.Now()
+}
+
+// Now returns the current wall clock time.
+func (fastClock) Now() time.Time {
+	return time.Now()
+}
+
+// customClock is a clock given in options, used as both monotonic and wall clock.
+type customClock struct {
+	clock limiter.Clock
+}
+
+// Monotonic returns the time of the custom clock, in nanoseconds.
+func (clock customClock) Monotonic() int64 {
+	return clock.clock.Now().UnixNano()
+}
+
+// Now returns the time of the custom clock.
+func (clock customClock) Now() time.Time {
+	return clock.clock.Now()
+}
+
+// newClock returns the clock defined in given options, or the default clock.
+func newClock(options limiter.StoreOptions) clock {
+	if options.Clock == nil {
+		return fastClock{}
+	}
+
+	return customClock{clock: options.Clock}
+}
+
+// wallTime converts given monotonic expiration to wall clock time, given the current time in both clocks.
+func wallTime(now int64, wall time.Time, expiration int64) time.Time {
+	return wall.Add(time.Duration(expiration - now))
+}
+
+// monotonicTime converts given wall clock expiration, in nanoseconds since the Unix epoch, to a monotonic one,
+// given the current time in both clocks.
+func monotonicTime(now int64, wall time.Time, expiration int64) int64 {
+	return now + expiration - wall.UnixNano()
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock whose monotonic and wall clock times are moved manually.
+type fakeClock struct {
+	monotonic int64
+	wall      time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		monotonic: int64(time.Hour),
+		wall:      time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func (clock *fakeClock) Monotonic() int64 {
+	return clock.monotonic
+}
+
+func (clock *fakeClock) Now() time.Time {
+	return clock.wall
+}
+
+// advance moves both clocks forward.
+func (clock *fakeClock) advance(duration time.Duration) {
+	clock.monotonic += int64(duration)
+	clock.wall = clock.wall.Add(duration)
+}
+
+// jump moves only the wall clock, like an NTP adjustment.
+func (clock *fakeClock) jump(duration time.Duration) {
+	clock.wall = clock.wall.Add(duration)
+}
+
+// clockCache is a cache implementation tested against clock jumps.
+type clockCache interface {
+	Increment(key string, value int64, duration time.Duration) (int64, time.Time)
+	Get(key string, duration time.Duration) (int64, time.Time)
+	Clean()
+}
+
+func testCacheClockJumps(t *testing.T, clock *fakeClock, cache clockCache) {
+	is := require.New(t)
+
+	key := "foobar"
+	duration := time.Minute
+
+	x, expire := cache.Increment(key, 1, duration)
+	is.Equal(int64(1), x)
+	is.Equal(clock.wall.Add(duration), expire)
+
+	// A wall clock jump forward doesn't expire the window, but its reported reset follows the wall clock.
+	clock.advance(10 * time.Second)
+	clock.jump(time.Hour)
+
+	x, expire = cache.Increment(key, 1, duration)
+	is.Equal(int64(2), x)
+	is.Equal(clock.wall.Add(50*time.Second), expire)
+
+	cache.Clean()
+	x, _ = cache.Get(key, duration)
+	is.Equal(int64(2), x)
+
+	// A wall clock jump backward doesn't extend the window.
+	clock.jump(-2 * time.Hour)
+	clock.advance(40 * time.Second)
+
+	x, expire = cache.Get(key, duration)
+	is.Equal(int64(2), x)
+	is.Equal(clock.wall.Add(10*time.Second), expire)
+
+	clock.advance(11 * time.Second)
+
+	x, expire = cache.Increment(key, 1, duration)
+	is.Equal(int64(1), x)
+	is.Equal(clock.wall.Add(duration), expire)
+
+	clock.advance(duration + time.Second)
+	cache.Clean()
+
+	x, _ = cache.Get(key, duration)
+	is.Equal(int64(0), x)
+}
+
+func TestCacheClockJumps(t *testing.T) {
+	clock := newFakeClock()
+	testCacheClockJumps(t, clock, newCache(0, nil, clock))
+}
+
+func TestShardedCacheClockJumps(t *testing.T) {
+	clock := newFakeClock()
+	testCacheClockJumps(t, clock, newShardedCache(4, 0, clock))
+}
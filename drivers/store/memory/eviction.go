@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/ulule/limiter/v3"
+)
+
+// entryOverhead is the approximate size, in bytes, of a key in cache, excluding its name.
+const entryOverhead = 128
+
+// entrySize returns the approximate size, in bytes, of given key in cache.
+func entrySize(key string) int64 {
+	return int64(len(key)) + entryOverhead
+}
+
+// clockEntry is a counter tracked by an evictor.
+type clockEntry struct {
+	key     string
+	counter *Counter
+}
+
+// An evictor bounds the number and the size of keys in a cache, using the CLOCK algorithm:
+// a hand sweeps the counters and evicts the first one which has not been referenced since its last pass.
+type evictor struct {
+	mutex    sync.Mutex
+	maxKeys  int64
+	maxBytes int64
+	evict    bool
+	entries  []clockEntry
+	hand     int
+	keys     int64
+	bytes    int64
+}
+
+// newEvictor returns an evictor with given bounds.
+func newEvictor(options limiter.StoreOptions) *evictor {
+	return &evictor{
+		maxKeys:  int64(options.MaxKeys),
+		maxBytes: options.MaxBytes,
+		evict:    options.Overflow == limiter.OverflowEvict,
+	}
+}
+
+// full returns true if a new key of given size would exceed the bounds.
+func (evictor *evictor) full(size int64) bool {
+	if evictor.maxKeys > 0 && atomic.LoadInt64(&evictor.keys)+1 > evictor.maxKeys {
+		return true
+	}
+	if evictor.maxBytes > 0 && atomic.LoadInt64(&evictor.bytes)+size > evictor.maxBytes {
+		return true
+	}
+	return false
+}
+
+// add tracks given counter.
+// The caller must hold the evictor mutex.
+func (evictor *evictor) add(key string, counter *Counter) {
+	evictor.entries = append(evictor.entries, clockEntry{
+		key:     key,
+		counter: counter,
+	})
+	atomic.AddInt64(&evictor.keys, 1)
+	atomic.AddInt64(&evictor.bytes, entrySize(key))
+}
+
+// release stops accounting a key which has been deleted from the cache.
+func (evictor *evictor) release(key string) {
+	atomic.AddInt64(&evictor.keys, -1)
+	atomic.AddInt64(&evictor.bytes, -entrySize(key))
+}
+
+// remove stops tracking the entry at given index.
+// The caller must hold the evictor mutex.
+func (evictor *evictor) remove(index int) {
+	last := len(evictor.entries) - 1
+	evictor.entries[index] = evictor.entries[last]
+	evictor.entries[last] = clockEntry{}
+	evictor.entries = evictor.entries[:last]
+}
+
+// touch marks given counter as referenced, if the cache is bounded.
+func (cache *Cache) touch(counter *Counter) {
+	if cache.evictor != nil && atomic.LoadUint32(&counter.referenced) == 0 {
+		atomic.StoreUint32(&counter.referenced, 1)
+	}
+}
+
+// loadOrInsert returns the existing counter for the key if present.
+// Otherwise, it stores and tracks the given counter. If bounded is true and the cache is full, another key
+// is evicted or, if the cache doesn't evict keys, ErrStoreFull is returned.
+// The loaded result is true if the counter was loaded, false if stored.
+func (cache *Cache) loadOrInsert(key string, counter *Counter, bounded bool) (*Counter, bool, error) {
+	cache.adopt(counter)
+
+	evictor := cache.evictor
+	if evictor == nil {
+		actual, loaded := cache.LoadOrStore(key, counter)
+		return actual, loaded, nil
+	}
+
+	evictor.mutex.Lock()
+	defer evictor.mutex.Unlock()
+
+	actual, loaded := cache.Load(key)
+	if loaded {
+		return actual, true, nil
+	}
+
+	size := entrySize(key)
+	for bounded && evictor.full(size) {
+		if !evictor.evict || !cache.evictOne() {
+			return nil, false, limiter.ErrStoreFull
+		}
+	}
+
+	cache.counters.Store(key, counter)
+	evictor.add(key, counter)
+
+	if len(evictor.entries) > 2*int(atomic.LoadInt64(&evictor.keys))+64 {
+		cache.compact()
+	}
+
+	return counter, false, nil
+}
+
+// evictOne evicts the first expired or unreferenced key found by the hand.
+// It returns false if no key could be evicted.
+// The caller must hold the evictor mutex.
+func (cache *Cache) evictOne() bool {
+	evictor := cache.evictor
+	now := cache.clock.Monotonic()
+
+	// Every referenced counter is given a second chance, so two passes are enough.
+	for i := 0; i <= 2*len(evictor.entries) && len(evictor.entries) > 0; i++ {
+		if evictor.hand >= len(evictor.entries) {
+			evictor.hand = 0
+		}
+
+		entry := evictor.entries[evictor.hand]
+		current, ok := cache.Load(entry.key)
+		if !ok || current != entry.counter {
+			// The key has already been deleted or replaced.
+			evictor.remove(evictor.hand)
+			continue
+		}
+
+		if !entry.counter.expired(now) && atomic.CompareAndSwapUint32(&entry.counter.referenced, 1, 0) {
+			evictor.hand++
+			continue
+		}
+
+		entry.counter.markDeleted(0, true)
+		cache.remove(entry.key, entry.counter)
+		evictor.remove(evictor.hand)
+		return true
+	}
+
+	return false
+}
+
+// compact stops tracking keys which have been deleted or replaced.
+// The caller must hold the evictor mutex.
+func (cache *Cache) compact() {
+	evictor := cache.evictor
+
+	entries := evictor.entries[:0]
+	for _, entry := range evictor.entries {
+		current, ok := cache.Load(entry.key)
+		if ok && current == entry.counter {
+			entries = append(entries, entry)
+		}
+	}
+
+	for i := len(entries); i < len(evictor.entries); i++ {
+		evictor.entries[i] = clockEntry{}
+	}
+
+	evictor.entries = entries
+	evictor.hand = 0
+}
@@ -0,0 +1,330 @@
+package memory
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// ShardedCacheWrapper is used to ensure that the underlying cleaner goroutine used to clean expired keys will not
+// prevent ShardedCache from being garbage collected.
+type ShardedCacheWrapper struct {
+	*ShardedCache
+}
+
+// A shardedCleaner will periodically delete expired keys from a sharded cache, one shard at a time.
+type shardedCleaner struct {
+	interval time.Duration
+	stop     chan bool
+}
+
+// Run will delete expired keys of a shard of given cache on each tick, so that every shard is cleaned once
+// per interval on a staggered schedule, until GC notify that it should stop.
+func (cleaner *shardedCleaner) Run(cache *ShardedCache) {
+	tick := cleaner.interval / time.Duration(len(cache.shards))
+	if tick <= 0 {
+		tick = cleaner.interval
+	}
+
+	ticker := time.NewTicker(tick)
+	next := 0
+	for {
+		select {
+		case <-ticker.C:
+			cache.shards[next].clean(cache.clock.Monotonic())
+			next = (next + 1) % len(cache.shards)
+		case <-cleaner.stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// stopShardedCleaner is a callback from GC used to stop cleaner goroutine.
+func stopShardedCleaner(wrapper *ShardedCacheWrapper) {
+	wrapper.cleaner.stop <- true
+	wrapper.cleaner = nil
+}
+
+// startShardedCleaner will start a cleaner goroutine for given sharded cache.
+func startShardedCleaner(cache *ShardedCache, interval time.Duration) {
+	cleaner := &shardedCleaner{
+		interval: interval,
+		stop:     make(chan bool),
+	}
+
+	cache.cleaner = cleaner
+	go cleaner.Run(cache)
+}
+
+// atomicCounter is a counter with an expiration, which is incremented atomically under a read lock until it
+// expires, so concurrent increments don't wait for each other. Its lock is only held exclusively to start a new
+// window, to delete it, and to increment it along with other counters.
+// Once deleted from its shard, an expired counter can't be used to start a new window anymore.
+type atomicCounter struct {
+	mutex      sync.RWMutex
+	value      int64
+	expiration int64
+	deleted    bool
+}
+
+// load returns the value and the expiration of this counter.
+// If the counter is expired, it will use the given expiration.
+func (counter *atomicCounter) load(now int64, expiration int64) (int64, int64) {
+	current := atomic.LoadInt64(&counter.expiration)
+	if current == 0 || now > current {
+		return 0, expiration
+	}
+
+	return atomic.LoadInt64(&counter.value), current
+}
+
+// increment increments given value on this counter.
+// If the counter is expired, it will use the given expiration.
+// It returns its current value and expiration, or false if the counter has expired and has been deleted from
+// its shard, in which case it must be loaded again.
+func (counter *atomicCounter) increment(now int64, value int64, expiration int64) (int64, int64, bool) {
+	counter.mutex.RLock()
+	current := atomic.LoadInt64(&counter.expiration)
+	if current != 0 && now <= current {
+		count := atomic.AddInt64(&counter.value, value)
+		counter.mutex.RUnlock()
+		return count, current, true
+	}
+	counter.mutex.RUnlock()
+
+	// The counter has expired: only one goroutine should start the new window.
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	if counter.deleted {
+		return 0, 0, false
+	}
+
+	current = atomic.LoadInt64(&counter.expiration)
+	if current != 0 && now <= current {
+		return atomic.AddInt64(&counter.value, value), current, true
+	}
+
+	atomic.StoreInt64(&counter.value, value)
+	atomic.StoreInt64(&counter.expiration, expiration)
+	return value, expiration, true
+}
+
+// expired returns true if the counter has expired.
+func (counter *atomicCounter) expired(now int64) bool {
+	current := atomic.LoadInt64(&counter.expiration)
+	return current == 0 || now > current
+}
+
+// delete marks the counter as deleted from its shard if it has expired at given monotonic time, or
+// unconditionally if force is true. It returns true if the counter has been marked.
+func (counter *atomicCounter) delete(now int64, force bool) bool {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	if !force && !counter.expired(now) {
+		return false
+	}
+
+	counter.deleted = true
+	return true
+}
+
+// cacheShard is a subset of the counters of a sharded cache.
+type cacheShard struct {
+	mutex    sync.RWMutex
+	counters map[string]*atomicCounter
+}
+
+// loadOrCreate returns the counter for given key. If it doesn't exist, it's created with given value and
+// expiration, under the lock of the shard so it can't be cleaned before being counted, and created is true.
+func (shard *cacheShard) loadOrCreate(key string, value int64, expiration int64) (*atomicCounter, bool) {
+	shard.mutex.RLock()
+	counter, ok := shard.counters[key]
+	shard.mutex.RUnlock()
+	if ok {
+		return counter, false
+	}
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	counter, ok = shard.counters[key]
+	if ok {
+		return counter, false
+	}
+
+	counter = &atomicCounter{
+		value:      value,
+		expiration: expiration,
+	}
+	shard.counters[key] = counter
+
+	return counter, true
+}
+
+// clean deletes any keys of this shard expired at given monotonic time.
+// Counters are marked as deleted, so a concurrent increment starting a new window loads a new counter instead.
+func (shard *cacheShard) clean(now int64) {
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	for key, counter := range shard.counters {
+		if counter.expired(now) && counter.delete(now, false) {
+			delete(shard.counters, key)
+		}
+	}
+}
+
+// ShardedCache contains a collection of counters split into several shards, each one with its own lock
+// and cleanup schedule, to reduce contention under parallel load.
+type ShardedCache struct {
+	shards  []*cacheShard
+	mask    uint64
+	cleaner *shardedCleaner
+	clock   clock
+}
+
+// NewShardedCache returns a new cache with given number of shards, rounded up to a power of two.
+func NewShardedCache(shards int, cleanInterval time.Duration) *ShardedCacheWrapper {
+	return newShardedCache(shards, cleanInterval, fastClock{})
+}
+
+// newShardedCache returns a new cache with given number of shards, using given clock.
+func newShardedCache(shards int, cleanInterval time.Duration, clock clock) *ShardedCacheWrapper {
+	count := 1
+	for count < shards {
+		count <<= 1
+	}
+
+	cache := &ShardedCache{
+		shards: make([]*cacheShard, count),
+		mask:   uint64(count - 1),
+		clock:  clock,
+	}
+	for i := range cache.shards {
+		cache.shards[i] = &cacheShard{
+			counters: map[string]*atomicCounter{},
+		}
+	}
+
+	wrapper := &ShardedCacheWrapper{ShardedCache: cache}
+
+	if cleanInterval > 0 {
+		startShardedCleaner(cache, cleanInterval)
+		runtime.SetFinalizer(wrapper, stopShardedCleaner)
+	}
+
+	return wrapper
+}
+
+// getShard returns the shard of given key.
+func (cache *ShardedCache) getShard(key string) *cacheShard {
+	return cache.shards[xxhash.Sum64String(key)&cache.mask]
+}
+
+// Increment increments given value on key.
+// If key is undefined or expired, it will create it.
+func (cache *ShardedCache) Increment(key string, value int64, duration time.Duration) (int64, time.Time) {
+	now, wall := cache.clock.Monotonic(), cache.clock.Now()
+	expiration := now + duration.Nanoseconds()
+	shard := cache.getShard(key)
+
+	for {
+		counter, created := shard.loadOrCreate(key, value, expiration)
+		if created {
+			return value, wallTime(now, wall, expiration)
+		}
+
+		count, current, ok := counter.increment(now, value, expiration)
+		if ok {
+			return count, wallTime(now, wall, current)
+		}
+	}
+}
+
+// Get returns key's value and expiration.
+func (cache *ShardedCache) Get(key string, duration time.Duration) (int64, time.Time) {
+	now, wall := cache.clock.Monotonic(), cache.clock.Now()
+	expiration := now + duration.Nanoseconds()
+
+	shard := cache.getShard(key)
+	shard.mutex.RLock()
+	counter, ok := shard.counters[key]
+	shard.mutex.RUnlock()
+	if !ok {
+		return 0, wallTime(now, wall, expiration)
+	}
+
+	value, expiration := counter.load(now, expiration)
+	return value, wallTime(now, wall, expiration)
+}
+
+// Reset changes the key's value and resets the expiration.
+func (cache *ShardedCache) Reset(key string, duration time.Duration) (int64, time.Time) {
+	shard := cache.getShard(key)
+	shard.mutex.Lock()
+	counter, ok := shard.counters[key]
+	if ok {
+		counter.delete(0, true)
+		delete(shard.counters, key)
+	}
+	shard.mutex.Unlock()
+
+	return 0, cache.clock.Now().Add(duration)
+}
+
+// Clean will delete any expired keys, in every shard.
+func (cache *ShardedCache) Clean() {
+	now := cache.clock.Monotonic()
+	for i := range cache.shards {
+		cache.shards[i].clean(now)
+	}
+}
+
+// increment increments given value on key.
+// A sharded cache is never full, so it never returns an error.
+func (cache *ShardedCache) increment(key string, value int64, duration time.Duration) (int64, time.Time, error) {
+	count, expiration := cache.Increment(key, value, duration)
+	return count, expiration, nil
+}
+
+// scan calls handler for every counter whose key starts with given prefix and which has not expired yet,
+// until handler returns false. Each shard is read under its lock, but handler is called without it.
+func (cache *ShardedCache) scan(prefix string, handler func(key string, value int64, expiration time.Time) bool) {
+	now, wall := cache.clock.Monotonic(), cache.clock.Now()
+
+	for _, shard := range cache.shards {
+		counters := []snapshotCounter{}
+
+		shard.mutex.RLock()
+		for key, counter := range shard.counters {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+
+			value, expiration := counter.load(now, 0)
+			if expiration == 0 {
+				continue
+			}
+
+			counters = append(counters, snapshotCounter{
+				Key:        key,
+				Value:      value,
+				Expiration: wallTime(now, wall, expiration),
+			})
+		}
+		shard.mutex.RUnlock()
+
+		for _, counter := range counters {
+			if !handler(counter.Key, counter.Value, counter.Expiration) {
+				return
+			}
+		}
+	}
+}
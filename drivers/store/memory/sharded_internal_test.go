@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedCacheIncrementWhileCleaningSteps(t *testing.T) {
+	is := require.New(t)
+
+	clock := newFakeClock()
+	cache := newShardedCache(1, 0, clock)
+	shard := cache.getShard("foo")
+	expiration := clock.Monotonic() + int64(time.Minute)
+
+	// A new counter holds its first increment, so the cleaner can't delete it before it's counted.
+	_, created := shard.loadOrCreate("foo", 1, expiration)
+	is.True(created)
+
+	shard.clean(clock.Monotonic())
+
+	value, _ := cache.Get("foo", time.Minute)
+	is.Equal(int64(1), value)
+
+	// An expired counter deleted by the cleaner can't start a new window, so the increment is retried.
+	clock.advance(2 * time.Minute)
+	expiration = clock.Monotonic() + int64(time.Minute)
+
+	counter, created := shard.loadOrCreate("foo", 1, expiration)
+	is.False(created)
+
+	shard.clean(clock.Monotonic())
+
+	_, _, ok := counter.increment(clock.Monotonic(), 1, expiration)
+	is.False(ok)
+
+	value, _ = cache.Increment("foo", 1, time.Minute)
+	is.Equal(int64(1), value)
+
+	value, _ = cache.Get("foo", time.Minute)
+	is.Equal(int64(1), value)
+}
@@ -0,0 +1,206 @@
+package memory_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+)
+
+func TestShardedCacheIncrementSequential(t *testing.T) {
+	is := require.New(t)
+
+	key := "foobar"
+	cache := memory.NewShardedCache(16, 10*time.Nanosecond)
+	duration := 50 * time.Millisecond
+	deleted := time.Now().Add(duration).UnixNano()
+	epsilon := 0.001
+
+	x, expire := cache.Increment(key, 1, duration)
+	is.Equal(int64(1), x)
+	is.InEpsilon(deleted, expire.UnixNano(), epsilon)
+
+	x, expire = cache.Increment(key, 2, duration)
+	is.Equal(int64(3), x)
+	is.InEpsilon(deleted, expire.UnixNano(), epsilon)
+
+	time.Sleep(duration)
+
+	deleted = time.Now().Add(duration).UnixNano()
+	x, expire = cache.Increment(key, 1, duration)
+	is.Equal(int64(1), x)
+	is.InEpsilon(deleted, expire.UnixNano(), epsilon)
+}
+
+func TestShardedCacheIncrementConcurrent(t *testing.T) {
+	is := require.New(t)
+
+	goroutines := 200
+	ops := 500
+	keys := 10
+
+	cache := memory.NewShardedCache(4, 10*time.Millisecond)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(i int) {
+			for j := 0; j < ops; j++ {
+				cache.Increment(fmt.Sprintf("key-%d", j%keys), 1, time.Minute)
+			}
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < keys; i++ {
+		value, _ := cache.Get(fmt.Sprintf("key-%d", i), time.Minute)
+		is.Equal(int64(goroutines*ops/keys), value)
+	}
+}
+
+func TestShardedCacheIncrementWhileCleaning(t *testing.T) {
+	is := require.New(t)
+
+	goroutines := 8
+	keys := 2000
+
+	// The cleaner runs continuously, while every increment creates a new counter.
+	cache := memory.NewShardedCache(4, 100*time.Microsecond)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			for j := 0; j < keys; j++ {
+				cache.Increment(fmt.Sprintf("key-%d", j), 1, time.Minute)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < keys; i++ {
+		value, _ := cache.Get(fmt.Sprintf("key-%d", i), time.Minute)
+		is.Equal(int64(goroutines), value)
+	}
+}
+
+func TestShardedCacheGet(t *testing.T) {
+	is := require.New(t)
+
+	key := "foobar"
+	cache := memory.NewShardedCache(16, 10*time.Nanosecond)
+	duration := 50 * time.Millisecond
+	deleted := time.Now().Add(duration).UnixNano()
+	epsilon := 0.001
+
+	x, expire := cache.Get(key, duration)
+	is.Equal(int64(0), x)
+	is.InEpsilon(deleted, expire.UnixNano(), epsilon)
+}
+
+func TestShardedCacheReset(t *testing.T) {
+	is := require.New(t)
+
+	key := "foobar"
+	cache := memory.NewShardedCache(16, 10*time.Nanosecond)
+	duration := 50 * time.Millisecond
+	deleted := time.Now().Add(duration).UnixNano()
+	epsilon := 0.001
+
+	x, expire := cache.Increment(key, 1, duration)
+	is.Equal(int64(1), x)
+	is.InEpsilon(deleted, expire.UnixNano(), epsilon)
+
+	x, expire = cache.Increment(key, 1, duration)
+	is.Equal(int64(2), x)
+	is.InEpsilon(deleted, expire.UnixNano(), epsilon)
+
+	x, expire = cache.Reset(key, duration)
+	is.Equal(int64(0), x)
+	is.InEpsilon(deleted, expire.UnixNano(), epsilon)
+
+	x, expire = cache.Increment(key, 1, duration)
+	is.Equal(int64(1), x)
+	is.InEpsilon(deleted, expire.UnixNano(), epsilon)
+}
+
+func TestShardedCacheClean(t *testing.T) {
+	is := require.New(t)
+
+	cache := memory.NewShardedCache(4, 0)
+
+	cache.Increment("foo", 1, 10*time.Millisecond)
+	cache.Increment("bar", 1, time.Minute)
+
+	time.Sleep(20 * time.Millisecond)
+	cache.Clean()
+
+	x, _ := cache.Get("foo", time.Minute)
+	is.Equal(int64(0), x)
+
+	x, _ = cache.Get("bar", time.Minute)
+	is.Equal(int64(1), x)
+}
+
+func BenchmarkCacheParallelSameKey(b *testing.B) {
+	cache := memory.NewCache(time.Hour)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			cache.Increment("foo", 1, time.Minute)
+		}
+	})
+}
+
+func BenchmarkShardedCacheParallelSameKey(b *testing.B) {
+	cache := memory.NewShardedCache(64, time.Hour)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			cache.Increment("foo", 1, time.Minute)
+		}
+	})
+}
+
+func BenchmarkCacheParallelManyKeys(b *testing.B) {
+	cache := memory.NewCache(100 * time.Millisecond)
+	keys := benchmarkKeys(10000)
+	next := int64(0)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := atomic.AddInt64(&next, 1000)
+		for pb.Next() {
+			cache.Increment(keys[i%int64(len(keys))], 1, time.Minute)
+			i++
+		}
+	})
+}
+
+func BenchmarkShardedCacheParallelManyKeys(b *testing.B) {
+	cache := memory.NewShardedCache(64, 100*time.Millisecond)
+	keys := benchmarkKeys(10000)
+	next := int64(0)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := atomic.AddInt64(&next, 1000)
+		for pb.Next() {
+			cache.Increment(keys[i%int64(len(keys))], 1, time.Minute)
+			i++
+		}
+	})
+}
+
+func benchmarkKeys(count int) []string {
+	keys := make([]string, count)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	return keys
+}
@@ -0,0 +1,294 @@
+package memory
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter/v3"
+)
+
+// snapshotVersion is the version of the snapshot format.
+const snapshotVersion = 1
+
+// snapshot is the content of a snapshot.
+type snapshot struct {
+	Version  int               `json:"version"`
+	Counters []snapshotCounter `json:"counters"`
+}
+
+// snapshotCounter is a counter in a snapshot.
+// Its expiration is a wall clock time, since monotonic clock values are meaningless across processes.
+type snapshotCounter struct {
+	Key        string    `json:"key"`
+	Value      int64     `json:"value"`
+	Expiration time.Time `json:"expiration"`
+}
+
+// Snapshot writes every counter which has not expired yet to given writer, in a versioned JSON format.
+func (store *Store) Snapshot(w io.Writer) error {
+	return writeSnapshot(w, store.cache)
+}
+
+// Restore loads counters from a snapshot written by Snapshot.
+// A restored counter replaces the existing one for the same key, and expired counters are skipped.
+// Keys are restored as is, so the store must use the same prefix as the one which wrote the snapshot.
+func (store *Store) Restore(r io.Reader) error {
+	value := snapshot{}
+	err := json.NewDecoder(r).Decode(&value)
+	if err != nil {
+		return errors.Wrap(err, "cannot read snapshot")
+	}
+
+	if value.Version != snapshotVersion {
+		return errors.Errorf("unsupported snapshot version: %d", value.Version)
+	}
+
+	return store.cache.restore(value.Counters)
+}
+
+// writeSnapshot writes every counter of given cache which has not expired yet to given writer.
+func writeSnapshot(w io.Writer, cache counters) error {
+	err := json.NewEncoder(w).Encode(snapshot{
+		Version:  snapshotVersion,
+		Counters: cache.snapshot(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "cannot write snapshot")
+	}
+
+	return nil
+}
+
+// NewPersistentStore creates a new instance of memory store which restores its counters from
+// options.SnapshotFile if it exists, and saves them every options.SnapshotInterval.
+// Close must be called on shutdown to save a last snapshot: only Close persists the final state, and a store
+// garbage collected without being closed loses the counters changed since its last periodic snapshot.
+func NewPersistentStore(options limiter.StoreOptions) (*Store, error) {
+	if options.SnapshotFile == "" {
+		return nil, errors.New("a snapshot file is required for a persistent store")
+	}
+
+	store := NewStoreWithOptions(options).(*Store)
+
+	err := store.restoreFile(options.SnapshotFile)
+	if err != nil {
+		return nil, err
+	}
+
+	store.snapshotter = &snapshotter{
+		file:     options.SnapshotFile,
+		interval: options.SnapshotInterval,
+		stop:     make(chan bool),
+		done:     make(chan bool),
+	}
+
+	if options.SnapshotInterval > 0 {
+		go store.snapshotter.Run(store.cache)
+		runtime.SetFinalizer(store, stopSnapshotter)
+	}
+
+	return store, nil
+}
+
+// Close saves a last snapshot of a persistent store, and stops its periodic snapshots.
+// It does nothing on a store which is not persistent, or which is already closed.
+func (store *Store) Close() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	snapshotter := store.snapshotter
+	if snapshotter == nil {
+		return nil
+	}
+
+	store.snapshotter = nil
+	if snapshotter.interval > 0 {
+		runtime.SetFinalizer(store, nil)
+		snapshotter.Stop()
+	}
+
+	return snapshotter.save(store.cache)
+}
+
+// restoreFile loads counters from given snapshot file, if it exists.
+func (store *Store) restoreFile(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "cannot open snapshot file")
+	}
+	defer file.Close()
+
+	return store.Restore(file)
+}
+
+// A snapshotter will periodically save the counters of a cache to a file.
+type snapshotter struct {
+	file     string
+	interval time.Duration
+	stop     chan bool
+	done     chan bool
+}
+
+// Run will periodically save given cache until it's stopped.
+func (snapshotter *snapshotter) Run(cache counters) {
+	defer close(snapshotter.done)
+
+	ticker := time.NewTicker(snapshotter.interval)
+	for {
+		select {
+		case <-ticker.C:
+			// On failure, the previous snapshot is kept until the next attempt.
+			_ = snapshotter.save(cache)
+		case <-snapshotter.stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// Stop stops periodic snapshots and waits for any snapshot in progress.
+func (snapshotter *snapshotter) Stop() {
+	snapshotter.stop <- true
+	<-snapshotter.done
+}
+
+// save writes a snapshot of given cache to a temporary file, which then replaces the snapshot file,
+// so that a crash never leaves a partial snapshot.
+func (snapshotter *snapshotter) save(cache counters) error {
+	file, err := os.CreateTemp(filepath.Dir(snapshotter.file), filepath.Base(snapshotter.file)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "cannot create snapshot file")
+	}
+	defer os.Remove(file.Name())
+
+	err = writeSnapshot(file, cache)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return errors.Wrap(err, "cannot write snapshot file")
+	}
+
+	err = os.Rename(file.Name(), snapshotter.file)
+	if err != nil {
+		return errors.Wrap(err, "cannot replace snapshot file")
+	}
+
+	return nil
+}
+
+// stopSnapshotter is a callback from GC used to stop snapshotter goroutine.
+// It doesn't save a last snapshot, which is only done by Close.
+func stopSnapshotter(store *Store) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.snapshotter.Stop()
+	store.snapshotter = nil
+}
+
+// snapshot returns every counter of the cache which has not expired yet.
+func (cache *Cache) snapshot() []snapshotCounter {
+	now, wall := cache.clock.Monotonic(), cache.clock.Now()
+
+	counters := []snapshotCounter{}
+	cache.Range(func(key string, counter *Counter) {
+		value, expiration := counter.load(now, 0)
+		if expiration == 0 {
+			return
+		}
+
+		counters = append(counters, snapshotCounter{
+			Key:        key,
+			Value:      value,
+			Expiration: wallTime(now, wall, expiration),
+		})
+	})
+
+	return counters
+}
+
+// restore stores given counters, replacing existing ones.
+// If the cache is full and can't evict any key, it returns ErrStoreFull.
+func (cache *Cache) restore(counters []snapshotCounter) error {
+	now, wall := cache.clock.Monotonic(), cache.clock.Now()
+
+	for _, value := range counters {
+		if !value.Expiration.After(wall) {
+			continue
+		}
+
+		cache.Delete(value.Key)
+		_, _, err := cache.loadOrInsert(value.Key, &Counter{
+			value:      value.Value,
+			expiration: now + int64(value.Expiration.Sub(wall)),
+		}, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// snapshot returns every counter of the cache which has not expired yet.
+func (cache *ShardedCache) snapshot() []snapshotCounter {
+	now, wall := cache.clock.Monotonic(), cache.clock.Now()
+
+	counters := []snapshotCounter{}
+	for _, shard := range cache.shards {
+		shard.mutex.RLock()
+		for key, counter := range shard.counters {
+			value, expiration := counter.load(now, 0)
+			if expiration == 0 {
+				continue
+			}
+
+			counters = append(counters, snapshotCounter{
+				Key:        key,
+				Value:      value,
+				Expiration: wallTime(now, wall, expiration),
+			})
+		}
+		shard.mutex.RUnlock()
+	}
+
+	return counters
+}
+
+// restore stores given counters, replacing existing ones.
+func (cache *ShardedCache) restore(counters []snapshotCounter) error {
+	now, wall := cache.clock.Monotonic(), cache.clock.Now()
+
+	for _, value := range counters {
+		if !value.Expiration.After(wall) {
+			continue
+		}
+
+		shard := cache.getShard(value.Key)
+		shard.mutex.Lock()
+		previous, ok := shard.counters[value.Key]
+		if ok {
+			previous.delete(0, true)
+		}
+		shard.counters[value.Key] = &atomicCounter{
+			value:      value.Value,
+			expiration: now + int64(value.Expiration.Sub(wall)),
+		}
+		shard.mutex.Unlock()
+	}
+
+	return nil
+}
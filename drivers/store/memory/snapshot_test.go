@@ -0,0 +1,198 @@
+package memory_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
+)
+
+func TestMemoryStoreSnapshot(t *testing.T) {
+	for _, shards := range []int{0, 16} {
+		is := require.New(t)
+		ctx := context.Background()
+
+		clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+		options := limiter.StoreOptions{
+			Prefix:      "limiter:memory:snapshot-test",
+			CacheShards: shards,
+			Clock:       clock,
+		}
+
+		minute := limiter.Rate{Limit: 10, Period: time.Minute}
+		second := limiter.Rate{Limit: 10, Period: time.Second}
+
+		store := memory.NewStoreWithOptions(options).(*memory.Store)
+		_, err := store.Increment(ctx, "foo", 3, minute)
+		is.NoError(err)
+		_, err = store.Increment(ctx, "bar", 5, second)
+		is.NoError(err)
+
+		clock.Advance(10 * time.Second)
+		_, err = store.Increment(ctx, "baz", 1, minute)
+		is.NoError(err)
+
+		buffer := &bytes.Buffer{}
+		err = store.Snapshot(buffer)
+		is.NoError(err)
+
+		// Expired counters are not saved.
+		is.True(strings.Contains(buffer.String(), `"version":1`))
+		is.False(strings.Contains(buffer.String(), "bar"))
+
+		// Counters keep their remaining time, even if the snapshot is restored later.
+		clock.Advance(20 * time.Second)
+
+		restored := memory.NewStoreWithOptions(options).(*memory.Store)
+		err = restored.Restore(buffer)
+		is.NoError(err)
+
+		lctx, err := restored.Peek(ctx, "foo", minute)
+		is.NoError(err)
+		is.Equal(int64(7), lctx.Remaining)
+		is.Equal(clock.Now().Add(30*time.Second).Unix(), lctx.Reset)
+
+		lctx, err = restored.Peek(ctx, "baz", minute)
+		is.NoError(err)
+		is.Equal(int64(9), lctx.Remaining)
+
+		lctx, err = restored.Peek(ctx, "bar", second)
+		is.NoError(err)
+		is.Equal(int64(10), lctx.Remaining)
+
+		clock.Advance(31 * time.Second)
+
+		lctx, err = restored.Peek(ctx, "foo", minute)
+		is.NoError(err)
+		is.Equal(int64(10), lctx.Remaining)
+	}
+}
+
+func TestMemoryStoreRestoreUnsupportedVersion(t *testing.T) {
+	is := require.New(t)
+
+	store := memory.NewStore().(*memory.Store)
+
+	err := store.Restore(strings.NewReader(`{"version":2,"counters":[]}`))
+	is.Error(err)
+
+	err = store.Restore(strings.NewReader(`not a snapshot`))
+	is.Error(err)
+}
+
+func TestMemoryStorePersistent(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Minute,
+	}
+
+	options := limiter.StoreOptions{
+		Prefix:       "limiter:memory:persistent-test",
+		SnapshotFile: filepath.Join(t.TempDir(), "limiter.json"),
+	}
+
+	_, err := memory.NewPersistentStore(limiter.StoreOptions{})
+	is.Error(err)
+
+	// A missing snapshot file is not an error.
+	store, err := memory.NewPersistentStore(options)
+	is.NoError(err)
+
+	_, err = store.Increment(ctx, "foo", 4, rate)
+	is.NoError(err)
+
+	err = store.Close()
+	is.NoError(err)
+
+	store, err = memory.NewPersistentStore(options)
+	is.NoError(err)
+
+	lctx, err := store.Get(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(5), lctx.Remaining)
+
+	err = store.Close()
+	is.NoError(err)
+
+	// A corrupted snapshot file is an error.
+	err = os.WriteFile(options.SnapshotFile, []byte("{"), 0600)
+	is.NoError(err)
+
+	_, err = memory.NewPersistentStore(options)
+	is.Error(err)
+}
+
+func TestMemoryStorePersistentInterval(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Minute,
+	}
+
+	options := limiter.StoreOptions{
+		Prefix:           "limiter:memory:persistent-interval-test",
+		SnapshotFile:     filepath.Join(t.TempDir(), "limiter.json"),
+		SnapshotInterval: 10 * time.Millisecond,
+	}
+
+	store, err := memory.NewPersistentStore(options)
+	is.NoError(err)
+
+	_, err = store.Increment(ctx, "foo", 4, rate)
+	is.NoError(err)
+
+	// The snapshot is saved without closing the store. It's read by a store which doesn't write it, and
+	// snapshot files are replaced atomically, so it's either missing or complete.
+	is.Eventually(func() bool {
+		file, err := os.Open(options.SnapshotFile)
+		if err != nil {
+			return false
+		}
+		defer file.Close()
+
+		reader := memory.NewStoreWithOptions(limiter.StoreOptions{Prefix: options.Prefix}).(*memory.Store)
+		err = reader.Restore(file)
+		if err != nil {
+			return false
+		}
+
+		lctx, err := reader.Peek(ctx, "foo", rate)
+		return err == nil && lctx.Remaining == 6
+	}, 5*time.Second, 5*time.Millisecond)
+
+	// Concurrent calls save a single last snapshot.
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			defer wg.Done()
+			is.NoError(store.Close())
+		}()
+	}
+	wg.Wait()
+
+	restored, err := memory.NewPersistentStore(options)
+	is.NoError(err)
+
+	lctx, err := restored.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(6), lctx.Remaining)
+
+	err = restored.Close()
+	is.NoError(err)
+}
@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ulule/limiter/v3"
@@ -13,8 +14,27 @@ import (
 type Store struct {
 	// Prefix used for the key.
 	Prefix string
+	// Overflow defines how a new key is handled when cache is full.
+	Overflow limiter.OverflowPolicy
 	// cache used to store values in-memory.
-	cache *CacheWrapper
+	cache counters
+	// clock used to compute the state of a limit.
+	clock clock
+	// snapshotter used to save counters, if the store is persistent.
+	snapshotter *snapshotter
+	// mutex is used to avoid concurrent access on snapshotter.
+	mutex sync.Mutex
+}
+
+// counters is a collection of counters, implemented by Cache and ShardedCache.
+type counters interface {
+	increment(key string, value int64, duration time.Duration) (int64, time.Time, error)
+	incrementAll(keys []string, value int64, durations []time.Duration, limits []int64) ([]int64, []time.Time, bool, error)
+	Get(key string, duration time.Duration) (int64, time.Time)
+	Reset(key string, duration time.Duration) (int64, time.Time)
+	snapshot() []snapshotCounter
+	restore(counters []snapshotCounter) error
+	scan(prefix string, handler func(key string, value int64, expiration time.Time) bool)
 }
 
 // NewStore creates a new instance of memory store with defaults.
@@ -27,25 +47,34 @@ func NewStore() limiter.Store {
 
 // NewStoreWithOptions creates a new instance of memory store with options.
 func NewStoreWithOptions(options limiter.StoreOptions) limiter.Store {
-	return &Store{
-		Prefix: options.Prefix,
-		cache:  NewCache(options.CleanUpInterval),
+	store := &Store{
+		Prefix:   options.Prefix,
+		Overflow: options.Overflow,
+		clock:    newClock(options),
+	}
+
+	if options.CacheShards > 1 && options.MaxKeys <= 0 && options.MaxBytes <= 0 {
+		store.cache = newShardedCache(options.CacheShards, options.CleanUpInterval, store.clock)
+	} else {
+		store.cache = NewCacheWithOptions(options)
 	}
+
+	return store
 }
 
 // Get returns the limit for given identifier.
 func (store *Store) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
-	count, expiration := store.cache.Increment(store.getCacheKey(key), 1, rate.Period)
-
-	lctx := common.GetContextFromState(time.Now(), rate, expiration, count)
-	return lctx, nil
+	return store.Increment(ctx, key, 1, rate)
 }
 
 // Increment increments the limit by given count & returns the new limit value for given identifier.
 func (store *Store) Increment(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
-	newCount, expiration := store.cache.Increment(store.getCacheKey(key), count, rate.Period)
+	newCount, expiration, err := store.cache.increment(store.getCacheKey(key), count, rate.Period)
+	if err != nil {
+		return store.onOverflow(rate, err)
+	}
 
-	lctx := common.GetContextFromState(time.Now(), rate, expiration, newCount)
+	lctx := common.GetContextFromState(store.clock.Now(), rate, expiration, newCount)
 	return lctx, nil
 }
 
@@ -53,7 +82,7 @@ func (store *Store) Increment(ctx context.Context, key string, count int64, rate
 func (store *Store) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
 	count, expiration := store.cache.Get(store.getCacheKey(key), rate.Period)
 
-	lctx := common.GetContextFromState(time.Now(), rate, expiration, count)
+	lctx := common.GetContextFromState(store.clock.Now(), rate, expiration, count)
 	return lctx, nil
 }
 
@@ -61,7 +90,65 @@ func (store *Store) Peek(ctx context.Context, key string, rate limiter.Rate) (li
 func (store *Store) Reset(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
 	count, expiration := store.cache.Reset(store.getCacheKey(key), rate.Period)
 
-	lctx := common.GetContextFromState(time.Now(), rate, expiration, count)
+	lctx := common.GetContextFromState(store.clock.Now(), rate, expiration, count)
+	return lctx, nil
+}
+
+// GetMulti returns the limit for given identifiers.
+func (store *Store) GetMulti(ctx context.Context, keys []string, rate limiter.Rate) ([]limiter.Context, error) {
+	return store.IncrementMulti(ctx, keys, 1, rate)
+}
+
+// IncrementMulti increments the limit by given count & returns the new limit value for given identifiers.
+func (store *Store) IncrementMulti(ctx context.Context, keys []string, count int64,
+	rate limiter.Rate) ([]limiter.Context, error) {
+
+	now := store.clock.Now()
+	contexts := make([]limiter.Context, len(keys))
+	for i := range keys {
+		newCount, expiration, err := store.cache.increment(store.getCacheKey(keys[i]), count, rate.Period)
+		if err != nil {
+			contexts[i], err = store.onOverflow(rate, err)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		contexts[i] = common.GetContextFromState(now, rate, expiration, newCount)
+	}
+
+	return contexts, nil
+}
+
+// Scan calls handler for every identifier starting with given prefix which has not expired yet,
+// until handler returns false.
+func (store *Store) Scan(ctx context.Context, prefix string, handler func(entry limiter.Entry) bool) error {
+	prefix = store.getCacheKey(prefix)
+	offset := len(store.Prefix) + 1
+
+	store.cache.scan(prefix, func(key string, value int64, expiration time.Time) bool {
+		if strings.HasPrefix(key[offset:], limiter.InternalPrefix) {
+			return true
+		}
+
+		return handler(limiter.Entry{
+			Key:        key[offset:],
+			Count:      value,
+			Expiration: expiration,
+		})
+	})
+
+	return nil
+}
+
+// onOverflow returns the limit for a new identifier which can't be stored because the cache is full.
+func (store *Store) onOverflow(rate limiter.Rate, err error) (limiter.Context, error) {
+	if store.Overflow != limiter.OverflowLimit {
+		return limiter.Context{}, err
+	}
+
+	now := store.clock.Now()
+	lctx := common.GetContextFromState(now, rate, now.Add(rate.Period), rate.Limit+1)
 	return lctx, nil
 }
 
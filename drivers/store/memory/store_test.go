@@ -1,9 +1,12 @@
 package memory_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/ulule/limiter/v3"
 	"github.com/ulule/limiter/v3/drivers/store/memory"
 	"github.com/ulule/limiter/v3/drivers/store/tests"
@@ -23,6 +26,143 @@ func TestMemoryStoreConcurrentAccess(t *testing.T) {
 	}))
 }
 
+func TestMemoryStoreBatchAccess(t *testing.T) {
+	tests.TestStoreBatchAccess(t, memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:          "limiter:memory:batch-test",
+		CleanUpInterval: 30 * time.Second,
+	}))
+}
+
+func TestMemoryStoreShardedSequentialAccess(t *testing.T) {
+	tests.TestStoreSequentialAccess(t, memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:          "limiter:memory:sharded-sequential-test",
+		CleanUpInterval: 30 * time.Second,
+		CacheShards:     16,
+	}))
+}
+
+func TestMemoryStoreShardedConcurrentAccess(t *testing.T) {
+	tests.TestStoreConcurrentAccess(t, memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:          "limiter:memory:sharded-concurrent-test",
+		CleanUpInterval: 1 * time.Millisecond,
+		CacheShards:     16,
+	}))
+}
+
+func TestMemoryStoreScan(t *testing.T) {
+	tests.TestStoreScan(t, memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:          "limiter:memory:scan-test",
+		CleanUpInterval: 30 * time.Second,
+	}))
+}
+
+func TestMemoryStoreShardedScan(t *testing.T) {
+	tests.TestStoreScan(t, memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:          "limiter:memory:sharded-scan-test",
+		CleanUpInterval: 30 * time.Second,
+		CacheShards:     16,
+	}))
+}
+
+func TestMemoryStoreIncrementAll(t *testing.T) {
+	tests.TestStoreIncrementAll(t, memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:          "limiter:memory:increment-all-test",
+		CleanUpInterval: 30 * time.Second,
+	}))
+}
+
+func TestMemoryStoreShardedIncrementAll(t *testing.T) {
+	tests.TestStoreIncrementAll(t, memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:          "limiter:memory:sharded-increment-all-test",
+		CleanUpInterval: 30 * time.Second,
+		CacheShards:     16,
+	}))
+}
+
+func TestMemoryStoreWindowRollover(t *testing.T) {
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	tests.TestStoreWindowRollover(t, memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix: "limiter:memory:window-rollover-test",
+		Clock:  clock,
+	}), clock)
+}
+
+func TestMemoryStoreShardedWindowRollover(t *testing.T) {
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	tests.TestStoreWindowRollover(t, memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:      "limiter:memory:sharded-window-rollover-test",
+		CacheShards: 16,
+		Clock:       clock,
+	}), clock)
+}
+
+func TestMemoryStoreOverflow(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Minute,
+	}
+
+	store := memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:   "limiter:memory:overflow-reject-test",
+		MaxKeys:  2,
+		Overflow: limiter.OverflowReject,
+	})
+
+	for _, key := range []string{"foo", "bar"} {
+		lctx, err := store.Get(ctx, key, rate)
+		is.NoError(err)
+		is.Equal(int64(9), lctx.Remaining)
+	}
+
+	_, err := store.Get(ctx, "baz", rate)
+	is.Equal(limiter.ErrStoreFull, err)
+
+	_, err = store.(limiter.BatchStore).GetMulti(ctx, []string{"foo", "baz"}, rate)
+	is.Equal(limiter.ErrStoreFull, err)
+
+	lctx, err := store.Get(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(7), lctx.Remaining)
+
+	store = memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:   "limiter:memory:overflow-limit-test",
+		MaxKeys:  2,
+		Overflow: limiter.OverflowLimit,
+	})
+
+	for _, key := range []string{"foo", "bar"} {
+		lctx, err = store.Get(ctx, key, rate)
+		is.NoError(err)
+		is.False(lctx.Reached)
+	}
+
+	lctx, err = store.Get(ctx, "baz", rate)
+	is.NoError(err)
+	is.True(lctx.Reached)
+	is.Equal(int64(0), lctx.Remaining)
+	is.True((lctx.Reset - time.Now().Unix()) <= 60)
+
+	store = memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:  "limiter:memory:overflow-evict-test",
+		MaxKeys: 2,
+	})
+
+	for _, key := range []string{"foo", "bar", "baz"} {
+		lctx, err = store.Get(ctx, key, rate)
+		is.NoError(err)
+		is.Equal(int64(9), lctx.Remaining)
+	}
+
+	lctx, err = store.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(10), lctx.Remaining)
+}
+
 func BenchmarkMemoryStoreSequentialAccess(b *testing.B) {
 	tests.BenchmarkStoreSequentialAccess(b, memory.NewStoreWithOptions(limiter.StoreOptions{
 		Prefix:          "limiter:memory:sequential-benchmark",
@@ -36,3 +176,11 @@ func BenchmarkMemoryStoreConcurrentAccess(b *testing.B) {
 		CleanUpInterval: 1 * time.Hour,
 	}))
 }
+
+func BenchmarkMemoryStoreShardedConcurrentAccess(b *testing.B) {
+	tests.BenchmarkStoreConcurrentAccess(b, memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:          "limiter:memory:sharded-concurrent-benchmark",
+		CleanUpInterval: 1 * time.Hour,
+		CacheShards:     64,
+	}))
+}
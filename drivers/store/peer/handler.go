@@ -0,0 +1,195 @@
+package peer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter/v3"
+)
+
+// Operations forwarded to the peer owning an identifier.
+const (
+	operationIncrement = "increment"
+	operationPeek      = "peek"
+	operationReset     = "reset"
+)
+
+// maxRequestSize is the maximum size of a request received by a handler.
+const maxRequestSize = 64 * 1024
+
+// request is an operation forwarded to the peer owning an identifier.
+type request struct {
+	Operation string `json:"op"`
+	Key       string `json:"key"`
+	Count     int64  `json:"count,omitempty"`
+	Limit     int64  `json:"limit"`
+	Period    int64  `json:"period"`
+}
+
+// response is the limit context returned by the peer owning an identifier.
+type response struct {
+	Limit     int64 `json:"limit"`
+	Remaining int64 `json:"remaining"`
+	Reset     int64 `json:"reset"`
+	Reached   bool  `json:"reached"`
+}
+
+// execute runs the operation on given store.
+func (req request) execute(ctx context.Context, store limiter.Store, rate limiter.Rate) (limiter.Context, error) {
+	switch req.Operation {
+	case operationIncrement:
+		return store.Increment(ctx, req.Key, req.Count, rate)
+	case operationPeek:
+		return store.Peek(ctx, req.Key, rate)
+	case operationReset:
+		return store.Reset(ctx, req.Key, rate)
+	default:
+		return limiter.Context{}, errors.Errorf("unknown operation '%s'", req.Operation)
+	}
+}
+
+// isValid returns true if the request has a known operation, an identifier and a rate.
+// A limit of zero is valid, and denies every request.
+func (req request) isValid() bool {
+	switch req.Operation {
+	case operationIncrement, operationPeek, operationReset:
+		return req.Key != "" && req.Limit >= 0 && req.Period > 0
+	default:
+		return false
+	}
+}
+
+// statusError is returned when a peer responds with an unexpected status code.
+type statusError struct {
+	peer   string
+	status int
+}
+
+// Error returns the peer and its status code.
+func (err *statusError) Error() string {
+	return fmt.Sprintf("peer '%s' returned status %d", err.peer, err.status)
+}
+
+// isPeerDown returns true if given error of forward means that the peer can't run requests, like a transport
+// error or a 5xx status code, rather than a request it rejects.
+func isPeerDown(err error) bool {
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.status >= http.StatusInternalServerError
+	}
+
+	return true
+}
+
+// forward sends given request to given peer, and returns its response.
+// The HTTP request is given to prepare before being sent, if it's defined.
+func forward(ctx context.Context, client *http.Client, prepare func(r *http.Request), peer string, req request,
+	rate limiter.Rate) (limiter.Context, error) {
+
+	req.Limit = rate.Limit
+	req.Period = int64(rate.Period)
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return limiter.Context{}, errors.Wrap(err, "cannot encode request")
+	}
+
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, peer, bytes.NewReader(body))
+	if err != nil {
+		return limiter.Context{}, errors.Wrapf(err, "cannot create request for peer '%s'", peer)
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	if prepare != nil {
+		prepare(hreq)
+	}
+
+	hres, err := client.Do(hreq)
+	if err != nil {
+		return limiter.Context{}, errors.Wrapf(err, "cannot reach peer '%s'", peer)
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, hres.Body)
+		_ = hres.Body.Close()
+	}()
+
+	if hres.StatusCode != http.StatusOK {
+		return limiter.Context{}, &statusError{peer: peer, status: hres.StatusCode}
+	}
+
+	res := response{}
+	err = json.NewDecoder(hres.Body).Decode(&res)
+	if err != nil {
+		return limiter.Context{}, errors.Wrapf(err, "cannot decode response of peer '%s'", peer)
+	}
+
+	return limiter.Context{
+		Limit:     res.Limit,
+		Remaining: res.Remaining,
+		Reset:     res.Reset,
+		Reached:   res.Reached,
+	}, nil
+}
+
+// Authorizer is a hook deciding whether a request forwarded by another peer can be run.
+type Authorizer func(r *http.Request) bool
+
+// handler runs the requests forwarded by other peers on the local store.
+// It never forwards them again, so peers with different lists can't loop.
+type handler struct {
+	store limiter.Store
+	// authorize is called before every request. Requests are denied if it's nil.
+	authorize Authorizer
+}
+
+// ServeHTTP implements http.Handler.
+func (handler *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler.authorize == nil || !handler.authorize(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := request{}
+	err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&req)
+	if err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if !req.isValid() {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	rate := limiter.Rate{
+		Limit:  req.Limit,
+		Period: time.Duration(req.Period),
+	}
+
+	lctx, err := req.execute(r.Context(), handler.store, rate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response{
+		Limit:     lctx.Limit,
+		Remaining: lctx.Remaining,
+		Reset:     lctx.Reset,
+		Reached:   lctx.Reached,
+	})
+}
@@ -0,0 +1,207 @@
+package peer
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/dgryski/go-rendezvous"
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+)
+
+// maxIdleConnsPerPeer is the number of idle connections kept to each peer.
+const maxIdleConnsPerPeer = 64
+
+// Store is a store which partitions identifiers across several instances of the same service, without any
+// external store: every identifier is owned by one peer, found using rendezvous hashing, which keeps its
+// counter in memory. Requests for an identifier owned by another peer are forwarded to it over HTTP.
+//
+// Each peer is identified by the URL of its Handler, and every instance must serve its own Handler at the
+// URL given as self, which must only be reachable by the other peers. When the owner of an identifier can't be
+// reached or fails with a 5xx status code, its counter is kept locally until PeerRetryInterval has elapsed: each
+// instance may then admit up to the whole limit while a peer is down. Other responses, like a request denied by
+// the Authorizer of the owner, are returned as errors.
+type Store struct {
+	// self is the URL of this peer.
+	self string
+	// local keeps the counters of identifiers owned by this peer, and of those owned by failed peers.
+	local limiter.Store
+	// client used to forward requests to other peers.
+	client *http.Client
+	// prepare is called before forwarding a request, if it's defined.
+	prepare func(r *http.Request)
+	// clock used to know whether a failed peer can be tried again.
+	clock limiter.Clock
+	// retryInterval is the time duration during which a failed peer is not tried again.
+	retryInterval time.Duration
+	// mutex is used to avoid concurrent access on table and failures.
+	mutex sync.RWMutex
+	// table is used to find the peer owning an identifier.
+	table *rendezvous.Rendezvous
+	// failures contains the time until which each failed peer is not tried again.
+	failures map[string]time.Time
+}
+
+// NewStore returns an instance of peer store with defaults.
+func NewStore(self string, peers []string) (limiter.Store, error) {
+	return NewStoreWithOptions(self, peers, limiter.StoreOptions{
+		Prefix:            limiter.DefaultPrefix,
+		CleanUpInterval:   limiter.DefaultCleanUpInterval,
+		PeerTimeout:       limiter.DefaultPeerTimeout,
+		PeerRetryInterval: limiter.DefaultPeerRetryInterval,
+	})
+}
+
+// NewStoreWithOptions returns an instance of peer store with options.
+// Self is the URL of this peer, which is added to peers if missing. Options are also used by the memory store
+// keeping local counters.
+func NewStoreWithOptions(self string, peers []string, options limiter.StoreOptions) (limiter.Store, error) {
+	if self == "" {
+		return nil, errors.New("peer store requires the URL of this peer")
+	}
+
+	store := &Store{
+		self:          self,
+		local:         memory.NewStoreWithOptions(options),
+		client:        options.PeerClient,
+		prepare:       options.PeerPrepareRequest,
+		clock:         options.Clock,
+		retryInterval: options.PeerRetryInterval,
+		failures:      map[string]time.Time{},
+	}
+
+	if store.client == nil {
+		store.client = newClient(options.PeerTimeout)
+	}
+
+	if store.retryInterval <= 0 {
+		store.retryInterval = limiter.DefaultPeerRetryInterval
+	}
+
+	if store.clock == nil {
+		store.clock = limiter.SystemClock
+	}
+
+	store.SetPeers(peers)
+
+	return store, nil
+}
+
+// SetPeers replaces the peers sharing the identifiers. This peer is added to the list if it's missing.
+// Only the identifiers whose owner changes are moved, and they start over with a new counter on their new owner.
+func (store *Store) SetPeers(peers []string) {
+	names := []string{store.self}
+	for i := range peers {
+		if peers[i] != store.self {
+			names = append(names, peers[i])
+		}
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.table = rendezvous.New(names, xxhash.Sum64String)
+	store.failures = map[string]time.Time{}
+}
+
+// Handler returns the handler receiving the requests forwarded by other peers, using given hook to authorize
+// them. Requests are denied if it's nil.
+//
+// The handler can read and change every counter of this peer: it must not be public, and should only be
+// reachable by the other peers, like on a private network.
+func (store *Store) Handler(authorize Authorizer) http.Handler {
+	return &handler{store: store.local, authorize: authorize}
+}
+
+// Get returns the limit for given identifier.
+func (store *Store) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return store.Increment(ctx, key, 1, rate)
+}
+
+// Increment increments the limit by given count & gives back the new limit for given identifier.
+func (store *Store) Increment(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
+	return store.execute(ctx, request{
+		Operation: operationIncrement,
+		Key:       key,
+		Count:     count,
+	}, rate)
+}
+
+// Peek returns the limit for given identifier, without modification on current values.
+func (store *Store) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return store.execute(ctx, request{
+		Operation: operationPeek,
+		Key:       key,
+	}, rate)
+}
+
+// Reset returns the limit for given identifier which is set to zero.
+func (store *Store) Reset(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return store.execute(ctx, request{
+		Operation: operationReset,
+		Key:       key,
+	}, rate)
+}
+
+// execute forwards given request to the owner of its identifier, or runs it on the local store if this peer
+// owns the identifier or if its owner can't be reached.
+func (store *Store) execute(ctx context.Context, req request, rate limiter.Rate) (limiter.Context, error) {
+	owner := store.getOwner(req.Key)
+	if owner == store.self {
+		return req.execute(ctx, store.local, rate)
+	}
+
+	lctx, err := forward(ctx, store.client, store.prepare, owner, req, rate)
+	if err != nil && (ctx.Err() != nil || !isPeerDown(err)) {
+		return limiter.Context{}, err
+	}
+	if err != nil {
+		store.fail(owner)
+		return req.execute(ctx, store.local, rate)
+	}
+
+	return lctx, nil
+}
+
+// getOwner returns the peer owning given identifier, or this peer if its owner has failed recently.
+func (store *Store) getOwner(key string) string {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	owner := store.table.Lookup(key)
+
+	until, ok := store.failures[owner]
+	if ok && store.clock.Now().Before(until) {
+		return store.self
+	}
+
+	return owner
+}
+
+// fail marks given peer as failed, so it's not tried again during the retry interval.
+func (store *Store) fail(peer string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.failures[peer] = store.clock.Now().Add(store.retryInterval)
+}
+
+// newClient returns a client with given timeout, keeping enough idle connections to each peer.
+func newClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = limiter.DefaultPeerTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerPeer
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
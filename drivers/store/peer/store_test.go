@@ -0,0 +1,421 @@
+package peer_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/peer"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
+)
+
+func TestPeerStoreSequentialAccess(t *testing.T) {
+	cluster := newCluster(t, 3, limiter.StoreOptions{
+		Prefix:          "limiter:peer:sequential-test",
+		CleanUpInterval: 30 * time.Second,
+	})
+
+	tests.TestStoreSequentialAccess(t, cluster.stores[0])
+}
+
+func TestPeerStoreConcurrentAccess(t *testing.T) {
+	cluster := newCluster(t, 3, limiter.StoreOptions{
+		Prefix:          "limiter:peer:concurrent-test",
+		CleanUpInterval: 1 * time.Millisecond,
+		PeerTimeout:     5 * time.Second,
+	})
+
+	tests.TestStoreConcurrentAccess(t, cluster.stores[0])
+}
+
+func TestPeerStoreBatchAccess(t *testing.T) {
+	cluster := newCluster(t, 3, limiter.StoreOptions{
+		Prefix: "limiter:peer:batch-test",
+	})
+
+	tests.TestStoreBatchAccess(t, cluster.stores[0])
+}
+
+func TestPeerStoreOverAdmission(t *testing.T) {
+	cluster := newCluster(t, 3, limiter.StoreOptions{
+		Prefix:      "limiter:peer:over-admission-test",
+		PeerTimeout: 5 * time.Second,
+	})
+
+	tests.TestStoreOverAdmission(t, cluster.stores, 0)
+}
+
+func TestPeerStoreWindowRollover(t *testing.T) {
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	cluster := newCluster(t, 3, limiter.StoreOptions{
+		Prefix: "limiter:peer:window-rollover-test",
+		Clock:  clock,
+	})
+
+	tests.TestStoreWindowRollover(t, cluster.stores[0], clock)
+}
+
+func TestPeerStoreForward(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	cluster := newCluster(t, 3, limiter.StoreOptions{
+		Prefix: "limiter:peer:forward-test",
+	})
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Minute,
+	}
+
+	// Every peer sees the same counter, whichever peer owns it.
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key-%d", i)
+
+		for _, store := range cluster.stores {
+			_, err := store.Get(ctx, key, rate)
+			is.NoError(err)
+		}
+
+		for _, store := range cluster.stores {
+			lctx, err := store.Peek(ctx, key, rate)
+			is.NoError(err)
+			is.Equal(int64(7), lctx.Remaining, key)
+		}
+
+		lctx, err := cluster.stores[1].Reset(ctx, key, rate)
+		is.NoError(err)
+		is.Equal(int64(10), lctx.Remaining)
+
+		lctx, err = cluster.stores[2].Peek(ctx, key, rate)
+		is.NoError(err)
+		is.Equal(int64(10), lctx.Remaining)
+	}
+
+	// Identifiers are spread across every peer, and only those owned by another peer are forwarded.
+	for i := range cluster.stores {
+		is.NotZero(cluster.received(i))
+	}
+	is.Less(cluster.received(0)+cluster.received(1)+cluster.received(2), int64(20*7))
+}
+
+func TestPeerStoreFallback(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	cluster := newCluster(t, 2, limiter.StoreOptions{
+		Prefix:            "limiter:peer:fallback-test",
+		Clock:             clock,
+		PeerRetryInterval: 10 * time.Second,
+	})
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Minute,
+	}
+
+	key := cluster.ownedBy(t, 1)
+
+	lctx, err := cluster.stores[0].Get(ctx, key, rate)
+	is.NoError(err)
+	is.Equal(int64(9), lctx.Remaining)
+
+	// The owner is down: the counter is kept locally, and the owner is not tried again.
+	cluster.down(1, true)
+
+	lctx, err = cluster.stores[0].Get(ctx, key, rate)
+	is.NoError(err)
+	is.Equal(int64(9), lctx.Remaining)
+
+	received := cluster.received(1)
+
+	lctx, err = cluster.stores[0].Get(ctx, key, rate)
+	is.NoError(err)
+	is.Equal(int64(8), lctx.Remaining)
+	is.Equal(received, cluster.received(1))
+
+	// The owner is tried again after the retry interval.
+	cluster.down(1, false)
+	clock.Advance(10 * time.Second)
+
+	lctx, err = cluster.stores[0].Get(ctx, key, rate)
+	is.NoError(err)
+	is.Equal(int64(8), lctx.Remaining)
+	is.Equal(received+1, cluster.received(1))
+}
+
+func TestPeerStoreCredentials(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	hasToken := func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer secret"
+	}
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Minute,
+	}
+
+	// Credentials checked by the other peers are added to forwarded requests.
+	cluster := newCluster(t, 2, limiter.StoreOptions{
+		Prefix: "limiter:peer:credentials-test",
+		PeerPrepareRequest: func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer secret")
+		},
+	})
+	cluster.authorize = hasToken
+
+	key := cluster.ownedBy(t, 1)
+
+	_, err := cluster.stores[0].Get(ctx, key, rate)
+	is.NoError(err)
+
+	lctx, err := cluster.stores[1].Peek(ctx, key, rate)
+	is.NoError(err)
+	is.Equal(int64(9), lctx.Remaining)
+
+	// A denied request is an error, instead of a failed peer whose identifiers are counted locally.
+	cluster = newCluster(t, 2, limiter.StoreOptions{
+		Prefix: "limiter:peer:credentials-test",
+	})
+	cluster.authorize = hasToken
+
+	denied := 0
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key-%d", i)
+
+		_, err = cluster.stores[0].Get(ctx, key, rate)
+		if err == nil {
+			continue
+		}
+		is.EqualError(err, fmt.Sprintf("peer '%s' returned status 403", cluster.urls[1]))
+
+		_, err = cluster.stores[0].Get(ctx, key, rate)
+		is.Error(err)
+		denied += 2
+	}
+	is.NotZero(denied)
+	is.Equal(int64(denied), cluster.received(1))
+}
+
+func TestPeerStoreSetPeers(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	cluster := newCluster(t, 2, limiter.StoreOptions{
+		Prefix: "limiter:peer:set-peers-test",
+	})
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Minute,
+	}
+
+	key := cluster.ownedBy(t, 1)
+
+	_, err := cluster.stores[0].Get(ctx, key, rate)
+	is.NoError(err)
+
+	// Without any other peer, every identifier is counted locally.
+	cluster.stores[0].(*peer.Store).SetPeers(nil)
+
+	lctx, err := cluster.stores[0].Get(ctx, key, rate)
+	is.NoError(err)
+	is.Equal(int64(9), lctx.Remaining)
+
+	cluster.stores[0].(*peer.Store).SetPeers(cluster.urls)
+
+	lctx, err = cluster.stores[0].Get(ctx, key, rate)
+	is.NoError(err)
+	is.Equal(int64(8), lctx.Remaining)
+}
+
+func TestPeerStoreHandler(t *testing.T) {
+	is := require.New(t)
+
+	cluster := newCluster(t, 1, limiter.StoreOptions{
+		Prefix: "limiter:peer:handler-test",
+	})
+
+	res, err := http.Get(cluster.urls[0])
+	is.NoError(err)
+	is.NoError(res.Body.Close())
+	is.Equal(http.StatusMethodNotAllowed, res.StatusCode)
+
+	bodies := []string{
+		`{`,
+		`{"op":"increment","key":"foo","count":1,"limit":-1,"period":60000000000}`,
+		`{"op":"increment","key":"foo","count":1,"limit":10,"period":0}`,
+		`{"op":"increment","key":"","count":1,"limit":10,"period":60000000000}`,
+		`{"op":"delete","key":"foo","limit":10,"period":60000000000}`,
+	}
+
+	for _, body := range bodies {
+		res, err = http.Post(cluster.urls[0], "application/json", strings.NewReader(body))
+		is.NoError(err)
+		is.NoError(res.Body.Close())
+		is.Equal(http.StatusBadRequest, res.StatusCode, body)
+	}
+
+	res, err = http.Post(cluster.urls[0], "application/json",
+		strings.NewReader(`{"op":"increment","key":"foo","count":2,"limit":10,"period":60000000000}`))
+	is.NoError(err)
+	is.NoError(res.Body.Close())
+	is.Equal(http.StatusOK, res.StatusCode)
+
+	lctx, err := cluster.stores[0].Peek(context.Background(), "foo", limiter.Rate{Limit: 10, Period: time.Minute})
+	is.NoError(err)
+	is.Equal(int64(8), lctx.Remaining)
+
+	// A limit of zero denies every request.
+	res, err = http.Post(cluster.urls[0], "application/json",
+		strings.NewReader(`{"op":"increment","key":"bar","count":1,"limit":0,"period":60000000000}`))
+	is.NoError(err)
+	is.NoError(res.Body.Close())
+	is.Equal(http.StatusOK, res.StatusCode)
+
+	lctx, err = cluster.stores[0].Peek(context.Background(), "bar", limiter.Rate{Limit: 0, Period: time.Minute})
+	is.NoError(err)
+	is.True(lctx.Reached)
+
+	// Requests are denied without an authorizer, or if it refuses them.
+	store := cluster.stores[0].(*peer.Store)
+	for _, handler := range []http.Handler{store.Handler(nil), store.Handler(func(*http.Request) bool { return false })} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/limiter",
+			strings.NewReader(`{"op":"increment","key":"foo","count":1,"limit":10,"period":60000000000}`))
+		handler.ServeHTTP(rec, req)
+		is.Equal(http.StatusForbidden, rec.Code)
+	}
+
+	lctx, err = cluster.stores[0].Peek(context.Background(), "foo", limiter.Rate{Limit: 10, Period: time.Minute})
+	is.NoError(err)
+	is.Equal(int64(8), lctx.Remaining)
+}
+
+func BenchmarkPeerStoreSequentialAccess(b *testing.B) {
+	cluster := newCluster(b, 3, limiter.StoreOptions{
+		Prefix:          "limiter:peer:sequential-benchmark",
+		CleanUpInterval: 30 * time.Second,
+	})
+
+	tests.BenchmarkStoreSequentialAccess(b, cluster.stores[0])
+}
+
+func BenchmarkPeerStoreConcurrentAccess(b *testing.B) {
+	cluster := newCluster(b, 3, limiter.StoreOptions{
+		Prefix:          "limiter:peer:concurrent-benchmark",
+		CleanUpInterval: 30 * time.Second,
+	})
+
+	tests.BenchmarkStoreConcurrentAccess(b, cluster.stores[0])
+}
+
+// cluster is a group of peer stores, each one served on loopback.
+type cluster struct {
+	urls   []string
+	stores []limiter.Store
+	// counters contains the number of requests received by each peer.
+	counters []int64
+	// failing contains 1 for each peer responding with an error.
+	failing []int32
+	// authorize authorizes the requests received by every peer. Default authorizes those sent from loopback.
+	authorize peer.Authorizer
+}
+
+// newCluster returns a cluster of given size, whose stores use given options.
+// Servers are closed at the end of the test.
+func newCluster(tb testing.TB, size int, options limiter.StoreOptions) *cluster {
+	c := &cluster{
+		urls:      make([]string, size),
+		stores:    make([]limiter.Store, size),
+		counters:  make([]int64, size),
+		failing:   make([]int32, size),
+		authorize: isLoopback,
+	}
+
+	handlers := make([]http.Handler, size)
+	for i := 0; i < size; i++ {
+		i := i
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(&c.failing[i]) == 1 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			atomic.AddInt64(&c.counters[i], 1)
+			handlers[i].ServeHTTP(w, r)
+		}))
+		tb.Cleanup(server.Close)
+
+		c.urls[i] = server.URL + "/limiter"
+	}
+
+	for i := 0; i < size; i++ {
+		store, err := peer.NewStoreWithOptions(c.urls[i], c.urls, options)
+		require.NoError(tb, err)
+
+		c.stores[i] = store
+		handlers[i] = store.(*peer.Store).Handler(func(r *http.Request) bool {
+			return c.authorize(r)
+		})
+	}
+
+	return c
+}
+
+// isLoopback authorizes the requests sent from the loopback interface.
+func isLoopback(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// received returns the number of requests received by given peer.
+func (c *cluster) received(i int) int64 {
+	return atomic.LoadInt64(&c.counters[i])
+}
+
+// down makes given peer fail, or recover.
+func (c *cluster) down(i int, failing bool) {
+	value := int32(0)
+	if failing {
+		value = 1
+	}
+	atomic.StoreInt32(&c.failing[i], value)
+}
+
+// ownedBy returns an identifier owned by given peer, detected with the requests it receives.
+func (c *cluster) ownedBy(tb testing.TB, i int) string {
+	rate := limiter.Rate{Limit: 10, Period: time.Minute}
+	other := (i + 1) % len(c.stores)
+
+	for j := 0; j < 100; j++ {
+		key := fmt.Sprintf("owned-by-%d-%d", i, j)
+		received := c.received(i)
+
+		_, err := c.stores[other].Peek(context.Background(), key, rate)
+		require.NoError(tb, err)
+
+		if c.received(i) > received {
+			return key
+		}
+	}
+
+	tb.Fatalf("no identifier owned by peer %d", i)
+	return ""
+}
@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/common"
+)
+
+// IncrementAll increments the limit of given identifiers of given group by given count, each one with the rate at
+// the same index, only if none of them would exceed its limit. It's done with a single lua script: if HashTag is
+// enabled, only the group is wrapped in the hash tag, so every key belongs to the same slot of a Redis Cluster.
+func (store *Store) IncrementAll(ctx context.Context, group string, keys []string, count int64,
+	rates []limiter.Rate) ([]limiter.Context, error) {
+
+	err := common.CheckIncrementAll(keys, rates)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKeys := make([]string, len(keys))
+	args := make([]interface{}, 0, 1+2*len(keys))
+	args = append(args, count)
+	for i := range keys {
+		cacheKeys[i] = store.getGroupKey(group, keys[i])
+		args = append(args, rates[i].Limit, rates[i].Period.Milliseconds())
+	}
+
+	result, err := store.evalSHA(ctx, store.getLuaIncrAllSHA, cacheKeys, args...).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "an error has occurred with redis command")
+	}
+
+	fields, ok := result.([]interface{})
+	if !ok || len(fields) != 1+2*len(keys) {
+		return nil, errors.Errorf("%d elements in result were expected", 1+2*len(keys))
+	}
+
+	admitted, ok := fields[0].(int64)
+	if !ok {
+		return nil, errors.New("type of the result should be number")
+	}
+
+	now := store.clock.Now()
+	contexts := make([]limiter.Context, len(keys))
+	for i := range keys {
+		value, ok1 := fields[1+2*i].(int64)
+		ttl, ok2 := fields[2+2*i].(int64)
+		if !ok1 || !ok2 {
+			return nil, errors.New("type of the count and/or ttl should be number")
+		}
+
+		expiration := now.Add(rates[i].Period)
+		if ttl > 0 {
+			expiration = now.Add(time.Duration(ttl) * time.Millisecond)
+		}
+
+		contexts[i] = common.GetContextFromState(now, rates[i], expiration, value)
+		if admitted == 0 && value+count > rates[i].Limit {
+			contexts[i].Reached = true
+		}
+	}
+
+	return contexts, nil
+}
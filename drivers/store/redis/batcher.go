@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	libredis "github.com/redis/go-redis/v9"
+)
+
+// batch is a list of lua script evaluations sent in a single pipeline.
+type batch struct {
+	calls []scriptCall
+	cmds  []*libredis.Cmd
+	done  chan struct{}
+}
+
+// batcher coalesces lua script evaluations issued within a short window into a single pipeline.
+type batcher struct {
+	store   *Store
+	window  time.Duration
+	maxSize int
+	mutex   sync.Mutex
+	pending *batch
+}
+
+// newBatcher returns a batcher for given store.
+func newBatcher(store *Store, window time.Duration, maxSize int) *batcher {
+	return &batcher{
+		store:   store,
+		window:  window,
+		maxSize: maxSize,
+	}
+}
+
+// evalSHA adds the lua sha evaluation to the pending batch and waits for its result.
+// If the context is canceled while waiting, the evaluation will still be sent to redis server.
+func (batcher *batcher) evalSHA(ctx context.Context, getSha func() string,
+	keys []string, args ...interface{}) *libredis.Cmd {
+
+	batcher.mutex.Lock()
+	current := batcher.pending
+	if current == nil {
+		current = &batch{done: make(chan struct{})}
+		batcher.pending = current
+		time.AfterFunc(batcher.window, func() {
+			batcher.flush(current)
+		})
+	}
+
+	index := len(current.calls)
+	current.calls = append(current.calls, scriptCall{
+		getSha: getSha,
+		keys:   keys,
+		args:   args,
+	})
+
+	full := len(current.calls) >= batcher.maxSize
+	if full {
+		batcher.pending = nil
+	}
+	batcher.mutex.Unlock()
+
+	if full {
+		batcher.exec(current)
+	}
+
+	select {
+	case <-current.done:
+		return current.cmds[index]
+	case <-ctx.Done():
+		cmd := libredis.NewCmd(ctx)
+		cmd.SetErr(ctx.Err())
+		return cmd
+	}
+}
+
+// flush sends given batch at the end of its window, unless it has already been sent.
+func (batcher *batcher) flush(current *batch) {
+	batcher.mutex.Lock()
+	if batcher.pending != current {
+		batcher.mutex.Unlock()
+		return
+	}
+	batcher.pending = nil
+	batcher.mutex.Unlock()
+
+	batcher.exec(current)
+}
+
+// exec sends given batch in a single pipeline and notifies its callers.
+func (batcher *batcher) exec(current *batch) {
+	// The batch is shared by several callers, so it can't rely on their context.
+	current.cmds = batcher.store.evalSHAPipeline(context.Background(), current.calls)
+	close(current.done)
+}
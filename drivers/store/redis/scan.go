@@ -0,0 +1,201 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	libredis "github.com/redis/go-redis/v9"
+
+	"github.com/ulule/limiter/v3"
+)
+
+// scanBatchSize is the number of keys requested by each SCAN command.
+const scanBatchSize = 100
+
+// errScanStopped is used to stop a scan when its handler returns false.
+var errScanStopped = errors.New("scan stopped")
+
+// ErrScanNotSupported is returned by Scan if the client implements neither ScanClient nor ForEachMaster.
+var ErrScanNotSupported = errors.New("scan not supported by redis client")
+
+// ScanClient is implemented by clients able to run a SCAN command, like the clients of go-redis.
+// It's required to enumerate identifiers with Scan.
+type ScanClient interface {
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *libredis.ScanCmd
+}
+
+// masterIterator is implemented by clients of a Redis Cluster, whose SCAN command only runs on a single node.
+type masterIterator interface {
+	ForEachMaster(ctx context.Context, fn func(ctx context.Context, client *libredis.Client) error) error
+}
+
+// Scan calls handler for every identifier starting with given prefix, until handler returns false.
+// Keys are enumerated with SCAN, on every master of a Redis Cluster, and their values are read with the
+// "peek" lua script. Identifiers expired between both steps are skipped.
+// ErrScanNotSupported is returned if the client can't run a SCAN command.
+func (store *Store) Scan(ctx context.Context, prefix string, handler func(entry limiter.Entry) bool) error {
+	match := store.getScanPattern(prefix)
+
+	cluster, ok := store.client.(masterIterator)
+	if !ok {
+		node, ok := store.client.(ScanClient)
+		if !ok {
+			return ErrScanNotSupported
+		}
+		return ignoreScanStopped(store.scan(ctx, node, match, handler))
+	}
+
+	// Masters are scanned concurrently, but handler is called sequentially.
+	mutex := sync.Mutex{}
+	stopped := false
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *libredis.Client) error {
+		return store.scan(ctx, client, match, func(entry limiter.Entry) bool {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if stopped {
+				return false
+			}
+
+			stopped = !handler(entry)
+			return !stopped
+		})
+	})
+
+	return ignoreScanStopped(err)
+}
+
+// scan calls handler for every key matching given pattern on given node, until handler returns false.
+// It returns errScanStopped if handler has stopped the scan.
+func (store *Store) scan(ctx context.Context, node ScanClient, match string,
+	handler func(entry limiter.Entry) bool) error {
+
+	cursor := uint64(0)
+	for {
+		keys, next, err := node.Scan(ctx, cursor, match, scanBatchSize).Result()
+		if err != nil {
+			return errors.Wrap(err, "cannot scan keys")
+		}
+
+		err = store.scanKeys(ctx, keys, handler)
+		if err != nil {
+			return err
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// scanKeys reads the count and expiration of given keys in a single pipeline, and calls handler for each one.
+// Keys which don't belong to the store, or whose identifier is internal, are skipped without being read.
+func (store *Store) scanKeys(ctx context.Context, keys []string, handler func(entry limiter.Entry) bool) error {
+	identifiers := make([]string, 0, len(keys))
+	calls := make([]scriptCall, 0, len(keys))
+	for i := range keys {
+		key, ok := store.getIdentifier(keys[i])
+		if !ok || strings.HasPrefix(key, limiter.InternalPrefix) {
+			continue
+		}
+
+		identifiers = append(identifiers, key)
+		calls = append(calls, scriptCall{
+			getSha: store.getLuaPeekSHA,
+			keys:   []string{keys[i]},
+		})
+	}
+
+	cmds := store.evalSHAPipeline(ctx, calls)
+	now := store.clock.Now()
+
+	for i := range identifiers {
+		count, ttl, err := parseCountAndTTL(cmds[i])
+		if err != nil {
+			return err
+		}
+
+		// The key has expired since it was enumerated.
+		if ttl == 0 || ttl == -2 {
+			continue
+		}
+
+		entry := limiter.Entry{
+			Key:   identifiers[i],
+			Count: count,
+		}
+		if ttl > 0 {
+			entry.Expiration = now.Add(time.Duration(ttl) * time.Millisecond)
+		}
+
+		if !handler(entry) {
+			return errScanStopped
+		}
+	}
+
+	return nil
+}
+
+// getScanPattern returns the pattern matching the keys of every identifier starting with given prefix.
+func (store *Store) getScanPattern(prefix string) string {
+	buffer := strings.Builder{}
+	buffer.WriteString(escapePattern(store.Prefix))
+	buffer.WriteString(":")
+	if store.HashTag {
+		buffer.WriteString("{")
+		prefix = hashTagReplacer.Replace(prefix)
+	}
+	buffer.WriteString(escapePattern(prefix))
+	buffer.WriteString("*")
+	return buffer.String()
+}
+
+// getIdentifier returns the identifier of given key, without prefix nor hash tag. The identifiers of a group are
+// given as the group followed by a colon and the identifier.
+// It returns false if the key doesn't belong to the store.
+func (store *Store) getIdentifier(key string) (string, bool) {
+	key = strings.TrimPrefix(key, store.Prefix+":")
+	if !store.HashTag {
+		return key, true
+	}
+
+	end := strings.IndexByte(key, '}')
+	if !strings.HasPrefix(key, "{") || end < 0 {
+		return "", false
+	}
+
+	tag, rest := unescapeHashTag(key[1:end]), key[end+1:]
+	switch {
+	case rest == "":
+		return tag, true
+	case strings.HasPrefix(rest, ":"):
+		return tag + rest, true
+	default:
+		return "", false
+	}
+}
+
+// escapePattern escapes the special characters of a glob-style pattern.
+func escapePattern(value string) string {
+	buffer := strings.Builder{}
+	for _, r := range value {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			buffer.WriteByte('\\')
+		}
+		buffer.WriteRune(r)
+	}
+	return buffer.String()
+}
+
+// ignoreScanStopped returns nil if given error only means that handler has stopped the scan.
+func ignoreScanStopped(err error) error {
+	if err == errScanStopped {
+		return nil
+	}
+	return err
+}
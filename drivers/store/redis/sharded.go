@@ -0,0 +1,257 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/dgryski/go-rendezvous"
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter/v3"
+)
+
+// ShardedStore is a store which routes every identifier to one of several independent redis servers,
+// using rendezvous hashing.
+//
+// Each shard is identified by the name returned by its client String() method, which contains the address
+// and database of go-redis clients. When a shard is added, only the identifiers now owned by this new shard
+// are moved to it. When a shard is removed, only the identifiers it owned are moved to the remaining ones.
+// A moved identifier starts over with a new counter on its new shard, while its previous counter expires
+// with its window.
+type ShardedStore struct {
+	// options used to create every shard.
+	options limiter.StoreOptions
+	// mutex is used to avoid concurrent access on shards and table.
+	mutex sync.RWMutex
+	// shards are the redis stores indexed by their name.
+	shards map[string]*Store
+	// table is used to find the shard owning an identifier.
+	table *rendezvous.Rendezvous
+}
+
+// NewShardedStore returns an instance of sharded redis store with defaults.
+func NewShardedStore(clients []Client) (limiter.Store, error) {
+	return NewShardedStoreWithOptions(clients, limiter.StoreOptions{
+		Prefix:          limiter.DefaultPrefix,
+		CleanUpInterval: limiter.DefaultCleanUpInterval,
+		MaxRetry:        limiter.DefaultMaxRetry,
+	})
+}
+
+// NewShardedStoreWithOptions returns an instance of sharded redis store with options.
+// Every shard uses the same options.
+func NewShardedStoreWithOptions(clients []Client, options limiter.StoreOptions) (limiter.Store, error) {
+	store := &ShardedStore{
+		options: options,
+		shards:  map[string]*Store{},
+		table:   rendezvous.New(nil, xxhash.Sum64String),
+	}
+
+	for i := range clients {
+		err := store.AddShard(clients[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// AddShard adds given client to the shards.
+func (store *ShardedStore) AddShard(client Client) error {
+	name, err := getShardName(client)
+	if err != nil {
+		return err
+	}
+
+	shard, err := NewStoreWithOptions(client, store.options)
+	if err != nil {
+		return err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	_, ok := store.shards[name]
+	if ok {
+		return errors.Errorf("shard '%s' already exists", name)
+	}
+
+	store.shards[name] = shard.(*Store)
+	store.table.Add(name)
+
+	return nil
+}
+
+// RemoveShard removes given client from the shards.
+func (store *ShardedStore) RemoveShard(client Client) error {
+	name, err := getShardName(client)
+	if err != nil {
+		return err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	_, ok := store.shards[name]
+	if !ok {
+		return errors.Errorf("shard '%s' doesn't exist", name)
+	}
+
+	delete(store.shards, name)
+
+	names := make([]string, 0, len(store.shards))
+	for name := range store.shards {
+		names = append(names, name)
+	}
+	store.table = rendezvous.New(names, xxhash.Sum64String)
+
+	return nil
+}
+
+// Get returns the limit for given identifier.
+func (store *ShardedStore) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	shard, err := store.getShard(key)
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	return shard.Get(ctx, key, rate)
+}
+
+// Increment increments the limit by given count & gives back the new limit for given identifier
+func (store *ShardedStore) Increment(ctx context.Context, key string, count int64,
+	rate limiter.Rate) (limiter.Context, error) {
+
+	shard, err := store.getShard(key)
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	return shard.Increment(ctx, key, count, rate)
+}
+
+// Peek returns the limit for given identifier, without modification on current values.
+func (store *ShardedStore) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	shard, err := store.getShard(key)
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	return shard.Peek(ctx, key, rate)
+}
+
+// Reset returns the limit for given identifier which is set to zero.
+func (store *ShardedStore) Reset(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	shard, err := store.getShard(key)
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	return shard.Reset(ctx, key, rate)
+}
+
+// GetMulti returns the limit for given identifiers, using a single pipeline per shard.
+func (store *ShardedStore) GetMulti(ctx context.Context, keys []string, rate limiter.Rate) ([]limiter.Context, error) {
+	return store.IncrementMulti(ctx, keys, 1, rate)
+}
+
+// IncrementMulti increments the limit by given count & gives back the new limit for given identifiers,
+// using a single pipeline per shard.
+func (store *ShardedStore) IncrementMulti(ctx context.Context, keys []string, count int64,
+	rate limiter.Rate) ([]limiter.Context, error) {
+
+	shards := map[*Store][]int{}
+	for i := range keys {
+		shard, err := store.getShard(keys[i])
+		if err != nil {
+			return nil, err
+		}
+		shards[shard] = append(shards[shard], i)
+	}
+
+	contexts := make([]limiter.Context, len(keys))
+	for shard, indexes := range shards {
+		subset := make([]string, len(indexes))
+		for i, index := range indexes {
+			subset[i] = keys[index]
+		}
+
+		values, err := shard.IncrementMulti(ctx, subset, count, rate)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, index := range indexes {
+			contexts[index] = values[i]
+		}
+	}
+
+	return contexts, nil
+}
+
+// IncrementAll increments the limit of given identifiers of given group by given count, each one with the rate at
+// the same index, only if none of them would exceed its limit. The whole group is routed to the shard owning the
+// group, so its identifiers are counted by the same shard, and may not be found on the shard owning
+// "group:identifier" by the other methods.
+func (store *ShardedStore) IncrementAll(ctx context.Context, group string, keys []string, count int64,
+	rates []limiter.Rate) ([]limiter.Context, error) {
+
+	shard, err := store.getShard(group)
+	if err != nil {
+		return nil, err
+	}
+
+	return shard.IncrementAll(ctx, group, keys, count, rates)
+}
+
+// Scan calls handler for every identifier starting with given prefix, on every shard, until handler returns false.
+func (store *ShardedStore) Scan(ctx context.Context, prefix string, handler func(entry limiter.Entry) bool) error {
+	store.mutex.RLock()
+	shards := make([]*Store, 0, len(store.shards))
+	for _, shard := range store.shards {
+		shards = append(shards, shard)
+	}
+	store.mutex.RUnlock()
+
+	for _, shard := range shards {
+		stopped := false
+		err := shard.Scan(ctx, prefix, func(entry limiter.Entry) bool {
+			stopped = !handler(entry)
+			return !stopped
+		})
+		if err != nil {
+			return err
+		}
+		if stopped {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// getShard returns the shard owning given identifier.
+func (store *ShardedStore) getShard(key string) (*Store, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	shard, ok := store.shards[store.table.Lookup(key)]
+	if !ok {
+		return nil, errors.New("no shard available")
+	}
+
+	return shard, nil
+}
+
+// getShardName returns the name of given client.
+func getShardName(client Client) (string, error) {
+	stringer, ok := client.(fmt.Stringer)
+	if !ok {
+		return "", errors.New("client must implement fmt.Stringer to be used as a shard")
+	}
+
+	return stringer.String(), nil
+}
@@ -0,0 +1,252 @@
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	libredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/redis"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
+)
+
+func TestRedisShardedStoreSequentialAccess(t *testing.T) {
+	is := require.New(t)
+
+	clients, err := newRedisShardClients(3)
+	is.NoError(err)
+
+	store, err := redis.NewShardedStoreWithOptions(asClients(clients), limiter.StoreOptions{
+		Prefix: "limiter:redis:sharded-sequential-test",
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.TestStoreSequentialAccess(t, store)
+}
+
+func TestRedisShardedStoreConcurrentAccess(t *testing.T) {
+	is := require.New(t)
+
+	clients, err := newRedisShardClients(3)
+	is.NoError(err)
+
+	store, err := redis.NewShardedStoreWithOptions(asClients(clients), limiter.StoreOptions{
+		Prefix: "limiter:redis:sharded-concurrent-test",
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.TestStoreConcurrentAccess(t, store)
+}
+
+func TestRedisShardedStoreBatchAccess(t *testing.T) {
+	is := require.New(t)
+
+	clients, err := newRedisShardClients(3)
+	is.NoError(err)
+
+	store, err := redis.NewShardedStoreWithOptions(asClients(clients), limiter.StoreOptions{
+		Prefix: "limiter:redis:sharded-batch-test",
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.TestStoreBatchAccess(t, store)
+}
+
+func TestRedisShardedStoreScan(t *testing.T) {
+	is := require.New(t)
+
+	clients, err := newRedisShardClients(3)
+	is.NoError(err)
+
+	store, err := redis.NewShardedStoreWithOptions(asClients(clients), limiter.StoreOptions{
+		Prefix: "limiter:redis:sharded-scan-test",
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.TestStoreScan(t, store)
+}
+
+func TestRedisShardedStoreIncrementAll(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clients, err := newRedisShardClients(3)
+	is.NoError(err)
+
+	prefix := "limiter:redis:sharded-increment-all-test"
+	store, err := redis.NewShardedStoreWithOptions(asClients(clients), limiter.StoreOptions{
+		Prefix: prefix,
+	})
+	is.NoError(err)
+
+	atomicStore, ok := store.(limiter.AtomicStore)
+	is.True(ok)
+
+	rates := []limiter.Rate{
+		{Limit: 2, Period: time.Minute},
+		{Limit: 10, Period: time.Minute},
+	}
+
+	// Every group is counted by a single shard, and groups are spread across every shard.
+	shards := map[int]bool{}
+	for i := 0; i < 30; i++ {
+		group := fmt.Sprintf("group-%d", i)
+		keys := []string{"user", "org"}
+
+		for j := 0; j < 2; j++ {
+			contexts, err := atomicStore.IncrementAll(ctx, group, keys, 1, rates)
+			is.NoError(err)
+			is.False(contexts[0].Reached)
+		}
+
+		contexts, err := atomicStore.IncrementAll(ctx, group, keys, 1, rates)
+		is.NoError(err)
+		is.True(contexts[0].Reached)
+		is.Equal(int64(8), contexts[1].Remaining)
+
+		owner := -1
+		for j, client := range clients {
+			found := client.Exists(ctx, prefix+":"+group+":user", prefix+":"+group+":org").Val()
+			if found > 0 {
+				is.Equal(int64(2), found)
+				is.Equal(-1, owner)
+				owner = j
+			}
+		}
+		is.NotEqual(-1, owner)
+		shards[owner] = true
+	}
+	is.Len(shards, 3)
+}
+
+func TestRedisShardedStoreRebalancing(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clients, err := newRedisShardClients(4)
+	is.NoError(err)
+
+	prefix := "limiter:redis:sharded-rebalancing-test"
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Minute,
+	}
+
+	instance, err := redis.NewShardedStoreWithOptions(asClients(clients[:3]), limiter.StoreOptions{
+		Prefix: prefix,
+	})
+	is.NoError(err)
+	store := instance.(*redis.ShardedStore)
+
+	keys := make([]string, 300)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		lctx, err := store.Get(ctx, keys[i], rate)
+		is.NoError(err)
+		is.Equal(int64(9), lctx.Remaining)
+	}
+
+	// Every identifier is owned by a single shard.
+	owners := map[string]int{}
+	for _, key := range keys {
+		for i, client := range clients[:3] {
+			if client.Exists(ctx, prefix+":"+key).Val() == 1 {
+				_, ok := owners[key]
+				is.False(ok)
+				owners[key] = i
+			}
+		}
+		_, ok := owners[key]
+		is.True(ok)
+	}
+
+	// When a shard is added, only identifiers moved to this new shard start over.
+	err = store.AddShard(clients[3])
+	is.NoError(err)
+
+	moved := 0
+	for _, key := range keys {
+		lctx, err := store.Get(ctx, key, rate)
+		is.NoError(err)
+
+		if lctx.Remaining == 9 {
+			moved++
+			is.Equal(int64(1), clients[3].Exists(ctx, prefix+":"+key).Val())
+			owners[key] = 3
+		} else {
+			is.Equal(int64(8), lctx.Remaining)
+			is.Equal(int64(0), clients[3].Exists(ctx, prefix+":"+key).Val())
+		}
+	}
+
+	// Approximately a quarter of identifiers should have moved.
+	is.Greater(moved, len(keys)/10)
+	is.Less(moved, len(keys)*4/10)
+
+	// When a shard is removed, only identifiers owned by this shard start over.
+	err = store.RemoveShard(clients[0])
+	is.NoError(err)
+
+	for _, key := range keys {
+		lctx, err := store.Peek(ctx, key, rate)
+		is.NoError(err)
+
+		if owners[key] == 0 {
+			is.Equal(int64(10), lctx.Remaining)
+		} else {
+			is.Less(lctx.Remaining, int64(10))
+		}
+	}
+
+	err = store.RemoveShard(clients[0])
+	is.Error(err)
+
+	err = store.AddShard(clients[1])
+	is.Error(err)
+}
+
+// newRedisShardClients returns given number of clients, each one using its own database as an independent shard.
+func newRedisShardClients(count int) ([]*libredis.Client, error) {
+	uri := "redis://localhost:6379/0"
+	if os.Getenv("REDIS_URI") != "" {
+		uri = os.Getenv("REDIS_URI")
+	}
+
+	clients := make([]*libredis.Client, count)
+	for i := range clients {
+		opt, err := libredis.ParseURL(uri)
+		if err != nil {
+			return nil, err
+		}
+
+		opt.DB = 10 + i
+		client := libredis.NewClient(opt)
+
+		err = client.FlushDB(context.Background()).Err()
+		if err != nil {
+			return nil, err
+		}
+
+		clients[i] = client
+	}
+
+	return clients, nil
+}
+
+// asClients converts given redis clients to the Client interface.
+func asClients(values []*libredis.Client) []redis.Client {
+	clients := make([]redis.Client, len(values))
+	for i := range values {
+		clients[i] = values[i]
+	}
+	return clients
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/ulule/limiter/v3/drivers/store/common"
 )
 
+// Every lua script must only access keys given in KEYS, and these keys must share the same hash tag
+// in order to be used with a Redis Cluster.
 const (
 	luaIncrScript = `
 local key = KEYS[1]
@@ -37,6 +39,40 @@ if v == false then
 end
 local ttl = redis.call("pttl", key)
 return {tonumber(v), ttl}
+`
+	luaIncrAllScript = `
+local count = tonumber(ARGV[1])
+local values = {}
+local admitted = 1
+for i = 1, #KEYS do
+	local v = redis.call("get", KEYS[i])
+	if v == false then
+		values[i] = {0, 0}
+	else
+		values[i] = {tonumber(v), redis.call("pttl", KEYS[i])}
+	end
+	if values[i][1] + count > tonumber(ARGV[2 * i]) then
+		admitted = 0
+	end
+end
+local ret = {admitted}
+for i = 1, #KEYS do
+	if admitted == 1 then
+		local ttl = tonumber(ARGV[2 * i + 1])
+		local v = redis.call("incrby", KEYS[i], count)
+		if v == count then
+			if ttl > 0 then
+				redis.call("pexpire", KEYS[i], ttl)
+			end
+		else
+			ttl = redis.call("pttl", KEYS[i])
+		end
+		values[i] = {v, ttl}
+	end
+	table.insert(ret, values[i][1])
+	table.insert(ret, values[i][2])
+end
+return ret
 `
 )
 
@@ -51,6 +87,12 @@ type Client interface {
 	ScriptLoad(ctx context.Context, script string) *libredis.StringCmd
 }
 
+// PipelineClient is implemented by clients able to send several commands in a single pipeline, like the clients
+// of go-redis. With other clients, the commands of a batch are sent one by one.
+type PipelineClient interface {
+	Pipelined(ctx context.Context, fn func(libredis.Pipeliner) error) ([]libredis.Cmder, error)
+}
+
 // Store is the redis store.
 type Store struct {
 	// Prefix used for the key.
@@ -58,8 +100,12 @@ type Store struct {
 	// MaxRetry is the maximum number of retry under race conditions.
 	// Deprecated: this option is no longer required since all operations are atomic now.
 	MaxRetry int
+	// HashTag defines if the identifier is wrapped in a hash tag, for Redis Cluster.
+	HashTag bool
 	// client used to communicate with redis server.
 	client Client
+	// clock used to report the expiration of a window.
+	clock limiter.Clock
 	// luaMutex is a mutex used to avoid concurrent access on luaIncrSHA and luaPeekSHA.
 	luaMutex sync.RWMutex
 	// luaLoaded is used for CAS and reduce pressure on luaMutex.
@@ -68,6 +114,10 @@ type Store struct {
 	luaIncrSHA string
 	// luaPeekSHA is the SHA of peek and expire key script.
 	luaPeekSHA string
+	// luaIncrAllSHA is the SHA of the script increasing several keys if none exceeds its limit.
+	luaIncrAllSHA string
+	// batcher is used to coalesce concurrent calls into a single pipeline, if enabled.
+	batcher *batcher
 }
 
 // NewStore returns an instance of redis store with defaults.
@@ -85,6 +135,20 @@ func NewStoreWithOptions(client Client, options limiter.StoreOptions) (limiter.S
 		client:   client,
 		Prefix:   options.Prefix,
 		MaxRetry: options.MaxRetry,
+		HashTag:  options.HashTag,
+		clock:    options.Clock,
+	}
+
+	if store.clock == nil {
+		store.clock = limiter.SystemClock
+	}
+
+	if options.BatchWindow > 0 {
+		maxSize := options.BatchMaxSize
+		if maxSize <= 0 {
+			maxSize = limiter.DefaultBatchMaxSize
+		}
+		store.batcher = newBatcher(store, options.BatchWindow, maxSize)
 	}
 
 	err := store.preloadLuaScripts(context.Background())
@@ -98,13 +162,46 @@ func NewStoreWithOptions(client Client, options limiter.StoreOptions) (limiter.S
 // Increment increments the limit by given count & gives back the new limit for given identifier
 func (store *Store) Increment(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
 	cmd := store.evalSHA(ctx, store.getLuaIncrSHA, []string{store.getCacheKey(key)}, count, rate.Period.Milliseconds())
-	return currentContext(cmd, rate)
+	return currentContext(cmd, store.clock.Now(), rate)
 }
 
 // Get returns the limit for given identifier.
 func (store *Store) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
 	cmd := store.evalSHA(ctx, store.getLuaIncrSHA, []string{store.getCacheKey(key)}, 1, rate.Period.Milliseconds())
-	return currentContext(cmd, rate)
+	return currentContext(cmd, store.clock.Now(), rate)
+}
+
+// IncrementMulti increments the limit by given count & gives back the new limit for given identifiers,
+// using a single pipeline.
+func (store *Store) IncrementMulti(ctx context.Context, keys []string, count int64,
+	rate limiter.Rate) ([]limiter.Context, error) {
+
+	calls := make([]scriptCall, len(keys))
+	for i := range keys {
+		calls[i] = scriptCall{
+			getSha: store.getLuaIncrSHA,
+			keys:   []string{store.getCacheKey(keys[i])},
+			args:   []interface{}{count, rate.Period.Milliseconds()},
+		}
+	}
+
+	cmds := store.evalSHAPipeline(ctx, calls)
+	now := store.clock.Now()
+	contexts := make([]limiter.Context, len(cmds))
+	for i := range cmds {
+		lctx, err := currentContext(cmds[i], now, rate)
+		if err != nil {
+			return nil, err
+		}
+		contexts[i] = lctx
+	}
+
+	return contexts, nil
+}
+
+// GetMulti returns the limit for given identifiers, using a single pipeline.
+func (store *Store) GetMulti(ctx context.Context, keys []string, rate limiter.Rate) ([]limiter.Context, error) {
+	return store.IncrementMulti(ctx, keys, 1, rate)
 }
 
 // Peek returns the limit for given identifier, without modification on current values.
@@ -115,7 +212,7 @@ func (store *Store) Peek(ctx context.Context, key string, rate limiter.Rate) (li
 		return limiter.Context{}, err
 	}
 
-	now := time.Now()
+	now := store.clock.Now()
 	expiration := now.Add(rate.Period)
 	if ttl > 0 {
 		expiration = now.Add(time.Duration(ttl) * time.Millisecond)
@@ -132,22 +229,87 @@ func (store *Store) Reset(ctx context.Context, key string, rate limiter.Rate) (l
 	}
 
 	count := int64(0)
-	now := time.Now()
+	now := store.clock.Now()
+	expiration := now.Add(rate.Period)
+
+	return common.GetContextFromState(now, rate, expiration, count), nil
+}
+
+// Set sets the count of given identifier, in a new window of the period of given rate.
+// Unlike a reset followed by an increment, no concurrent request can be counted in between.
+func (store *Store) Set(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
+	err := store.client.Set(ctx, store.getCacheKey(key), count, rate.Period).Err()
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	now := store.clock.Now()
 	expiration := now.Add(rate.Period)
 
 	return common.GetContextFromState(now, rate, expiration, count), nil
 }
 
-// getCacheKey returns the full path for an identifier.
+// getCacheKey returns the full path for an identifier, wrapped in a hash tag if enabled.
 func (store *Store) getCacheKey(key string) string {
 	buffer := strings.Builder{}
 	buffer.WriteString(store.Prefix)
 	buffer.WriteString(":")
+	if store.HashTag {
+		buffer.WriteString("{")
+		buffer.WriteString(escapeHashTag(key))
+		buffer.WriteString("}")
+		return buffer.String()
+	}
+	buffer.WriteString(key)
+	return buffer.String()
+}
+
+// getGroupKey returns the full path for an identifier of given group. If hash tags are enabled, only the group is
+// wrapped in the hash tag, so every identifier of the group belongs to the same slot.
+func (store *Store) getGroupKey(group string, key string) string {
+	if !store.HashTag {
+		return store.getCacheKey(group + ":" + key)
+	}
+
+	buffer := strings.Builder{}
+	buffer.WriteString(store.Prefix)
+	buffer.WriteString(":{")
+	buffer.WriteString(escapeHashTag(group))
+	buffer.WriteString("}:")
 	buffer.WriteString(key)
 	return buffer.String()
 }
 
-// preloadLuaScripts preloads the "incr" and "peek" lua scripts.
+// escapeHashTag escapes given identifier so it can be wrapped in a hash tag: Redis ends a hash tag at the first
+// closing brace, and hashes the whole key if it's empty. Backslashes are doubled, closing braces become \c, and
+// an empty identifier becomes \e.
+func escapeHashTag(key string) string {
+	if key == "" {
+		return `\e`
+	}
+	if !strings.ContainsAny(key, `\}`) {
+		return key
+	}
+	return hashTagReplacer.Replace(key)
+}
+
+// unescapeHashTag returns the identifier escaped by escapeHashTag.
+func unescapeHashTag(tag string) string {
+	if tag == `\e` {
+		return ""
+	}
+	if !strings.Contains(tag, `\`) {
+		return tag
+	}
+	return hashTagUnreplacer.Replace(tag)
+}
+
+var (
+	hashTagReplacer   = strings.NewReplacer(`\`, `\\`, "}", `\c`)
+	hashTagUnreplacer = strings.NewReplacer(`\\`, `\`, `\c`, "}")
+)
+
+// preloadLuaScripts preloads the "incr", "peek" and "incr all" lua scripts.
 func (store *Store) preloadLuaScripts(ctx context.Context) error {
 	// Verify if we need to load lua scripts.
 	// Inspired by sync.Once.
@@ -157,7 +319,7 @@ func (store *Store) preloadLuaScripts(ctx context.Context) error {
 	return nil
 }
 
-// reloadLuaScripts forces a reload of "incr" and "peek" lua scripts.
+// reloadLuaScripts forces a reload of "incr", "peek" and "incr all" lua scripts.
 func (store *Store) reloadLuaScripts(ctx context.Context) error {
 	// Reset lua scripts loaded state.
 	// Inspired by sync.Once.
@@ -165,7 +327,7 @@ func (store *Store) reloadLuaScripts(ctx context.Context) error {
 	return store.loadLuaScripts(ctx)
 }
 
-// loadLuaScripts load "incr" and "peek" lua scripts.
+// loadLuaScripts load "incr", "peek" and "incr all" lua scripts.
 // WARNING: Please use preloadLuaScripts or reloadLuaScripts, instead of this one.
 func (store *Store) loadLuaScripts(ctx context.Context) error {
 	store.luaMutex.Lock()
@@ -186,8 +348,14 @@ func (store *Store) loadLuaScripts(ctx context.Context) error {
 		return errors.Wrap(err, `failed to load "peek" lua script`)
 	}
 
+	luaIncrAllSHA, err := store.client.ScriptLoad(ctx, luaIncrAllScript).Result()
+	if err != nil {
+		return errors.Wrap(err, `failed to load "incr all" lua script`)
+	}
+
 	store.luaIncrSHA = luaIncrSHA
 	store.luaPeekSHA = luaPeekSHA
+	store.luaIncrAllSHA = luaIncrAllSHA
 
 	atomic.StoreUint32(&store.luaLoaded, 1)
 
@@ -208,10 +376,28 @@ func (store *Store) getLuaPeekSHA() string {
 	return store.luaPeekSHA
 }
 
+// getLuaIncrAllSHA returns a "thread-safe" value for luaIncrAllSHA.
+func (store *Store) getLuaIncrAllSHA() string {
+	store.luaMutex.RLock()
+	defer store.luaMutex.RUnlock()
+	return store.luaIncrAllSHA
+}
+
 // evalSHA eval the redis lua sha and load the scripts if missing.
 func (store *Store) evalSHA(ctx context.Context, getSha func() string,
 	keys []string, args ...interface{}) *libredis.Cmd {
 
+	if store.batcher != nil {
+		return store.batcher.evalSHA(ctx, getSha, keys, args...)
+	}
+
+	return store.evalSHAOnce(ctx, getSha, keys, args...)
+}
+
+// evalSHAOnce eval the redis lua sha in its own command, without batching, and load the scripts if missing.
+func (store *Store) evalSHAOnce(ctx context.Context, getSha func() string,
+	keys []string, args ...interface{}) *libredis.Cmd {
+
 	cmd := store.client.EvalSha(ctx, getSha(), keys, args...)
 	err := cmd.Err()
 	if err == nil || !isLuaScriptGone(err) {
@@ -228,6 +414,66 @@ func (store *Store) evalSHA(ctx context.Context, getSha func() string,
 	return store.client.EvalSha(ctx, getSha(), keys, args...)
 }
 
+// scriptCall is a lua script evaluation.
+type scriptCall struct {
+	getSha func() string
+	keys   []string
+	args   []interface{}
+}
+
+// evalSHAPipeline eval the redis lua sha of given calls in a single pipeline and load the scripts if missing.
+// Every returned command holds its own result or error.
+// If the client doesn't implement PipelineClient, calls are evaluated one by one.
+func (store *Store) evalSHAPipeline(ctx context.Context, calls []scriptCall) []*libredis.Cmd {
+	cmds := make([]*libredis.Cmd, len(calls))
+
+	pipeliner, ok := store.client.(PipelineClient)
+	if !ok {
+		for i := range calls {
+			cmds[i] = store.evalSHAOnce(ctx, calls[i].getSha, calls[i].keys, calls[i].args...)
+		}
+		return cmds
+	}
+
+	indexes := make([]int, len(calls))
+	for i := range calls {
+		indexes[i] = i
+	}
+
+	for attempt := 0; attempt < 2 && len(indexes) > 0; attempt++ {
+		if attempt > 0 {
+			err := store.reloadLuaScripts(ctx)
+			if err != nil {
+				for _, i := range indexes {
+					cmds[i] = libredis.NewCmd(ctx)
+					cmds[i].SetErr(err)
+				}
+				return cmds
+			}
+		}
+
+		// Errors are reported by every command of the pipeline.
+		_, _ = pipeliner.Pipelined(ctx, func(pipe libredis.Pipeliner) error {
+			for _, i := range indexes {
+				cmds[i] = pipe.EvalSha(ctx, calls[i].getSha(), calls[i].keys, calls[i].args...)
+			}
+			return nil
+		})
+
+		// Only retry commands with a missing lua script, since others have been applied.
+		missing := indexes[:0]
+		for _, i := range indexes {
+			err := cmds[i].Err()
+			if err != nil && isLuaScriptGone(err) {
+				missing = append(missing, i)
+			}
+		}
+		indexes = missing
+	}
+
+	return cmds
+}
+
 // isLuaScriptGone returns if the error is a missing lua script from redis server.
 func isLuaScriptGone(err error) bool {
 	return strings.HasPrefix(err.Error(), "NOSCRIPT")
@@ -254,13 +500,12 @@ func parseCountAndTTL(cmd *libredis.Cmd) (int64, int64, error) {
 	return count, ttl, nil
 }
 
-func currentContext(cmd *libredis.Cmd, rate limiter.Rate) (limiter.Context, error) {
+func currentContext(cmd *libredis.Cmd, now time.Time, rate limiter.Rate) (limiter.Context, error) {
 	count, ttl, err := parseCountAndTTL(cmd)
 	if err != nil {
 		return limiter.Context{}, err
 	}
 
-	now := time.Now()
 	expiration := now.Add(rate.Period)
 	if ttl > 0 {
 		expiration = now.Add(time.Duration(ttl) * time.Millisecond)
@@ -3,6 +3,9 @@ package redis_test
 import (
 	"context"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -46,6 +49,467 @@ func TestRedisStoreConcurrentAccess(t *testing.T) {
 	tests.TestStoreConcurrentAccess(t, store)
 }
 
+func TestRedisStoreBatchAccess(t *testing.T) {
+	is := require.New(t)
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix: "limiter:redis:batch-test",
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	ctx := context.Background()
+	_, err = client.Del(ctx, "limiter:redis:batch-test:foo", "limiter:redis:batch-test:bar",
+		"limiter:redis:batch-test:baz", "limiter:redis:batch-test:qux").Result()
+	is.NoError(err)
+
+	tests.TestStoreBatchAccess(t, store)
+
+	// Lua scripts must be reloaded when they are gone.
+	_, err = client.ScriptFlush(ctx).Result()
+	is.NoError(err)
+
+	contexts, err := store.(limiter.BatchStore).GetMulti(ctx, []string{"foo", "qux"}, limiter.Rate{
+		Limit:  3,
+		Period: time.Minute,
+	})
+	is.NoError(err)
+	is.Len(contexts, 2)
+	is.True(contexts[0].Reached)
+	is.Equal(int64(2), contexts[1].Remaining)
+}
+
+func TestRedisStoreBatchAccessWithoutPipeline(t *testing.T) {
+	is := require.New(t)
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	ctx := context.Background()
+	_, err = client.Del(ctx, "limiter:redis:batch-without-pipeline-test:foo",
+		"limiter:redis:batch-without-pipeline-test:bar", "limiter:redis:batch-without-pipeline-test:baz",
+		"limiter:redis:batch-without-pipeline-test:qux").Result()
+	is.NoError(err)
+
+	// Clients implementing only the Client interface send batches one command at a time.
+	store, err := redis.NewStoreWithOptions(struct{ redis.Client }{client}, limiter.StoreOptions{
+		Prefix: "limiter:redis:batch-without-pipeline-test",
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.TestStoreBatchAccess(t, store)
+}
+
+func TestRedisStoreBatchedSequentialAccess(t *testing.T) {
+	is := require.New(t)
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix:      "limiter:redis:batched-sequential-test",
+		BatchWindow: time.Millisecond,
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.TestStoreSequentialAccess(t, store)
+}
+
+func TestRedisStoreBatchedConcurrentAccess(t *testing.T) {
+	is := require.New(t)
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix:      "limiter:redis:batched-concurrent-test",
+		BatchWindow: time.Millisecond,
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.TestStoreConcurrentAccess(t, store)
+}
+
+func TestRedisStoreBatchedCoalescing(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	_, err = client.Del(ctx, "limiter:redis:batched-coalescing-test:foo").Result()
+	is.NoError(err)
+
+	counter := &pipelineCounter{Client: client}
+	store, err := redis.NewStoreWithOptions(counter, limiter.StoreOptions{
+		Prefix:       "limiter:redis:batched-coalescing-test",
+		BatchWindow:  50 * time.Millisecond,
+		BatchMaxSize: 10,
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	rate := limiter.Rate{
+		Limit:  100,
+		Period: time.Minute,
+	}
+
+	goroutines := 50
+	remaining := make(chan int64, goroutines)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			lctx, err := store.Get(ctx, "foo", rate)
+			is.NoError(err)
+			remaining <- lctx.Remaining
+		}()
+	}
+	wg.Wait()
+	close(remaining)
+
+	// Every caller must receive its own result.
+	seen := map[int64]bool{}
+	for value := range remaining {
+		is.False(seen[value])
+		seen[value] = true
+	}
+	is.Len(seen, goroutines)
+
+	is.GreaterOrEqual(atomic.LoadInt64(&counter.pipelines), int64(goroutines/10))
+	is.Less(atomic.LoadInt64(&counter.pipelines), int64(goroutines))
+
+	lctx, err := store.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(50), lctx.Remaining)
+}
+
+func TestRedisStoreHashTag(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	_, err = client.Del(ctx, "limiter:redis:hash-tag-test:{foo}").Result()
+	is.NoError(err)
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix:  "limiter:redis:hash-tag-test",
+		HashTag: true,
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	_, err = store.Get(ctx, "foo", limiter.Rate{
+		Limit:  3,
+		Period: time.Minute,
+	})
+	is.NoError(err)
+
+	value, err := client.Get(ctx, "limiter:redis:hash-tag-test:{foo}").Int64()
+	is.NoError(err)
+	is.Equal(int64(1), value)
+
+	// Closing braces and empty identifiers are escaped, so the hash tag covers the whole identifier.
+	for key, cacheKey := range map[string]string{
+		"a}b": `limiter:redis:hash-tag-test:{a\cb}`,
+		`a\c`: `limiter:redis:hash-tag-test:{a\\c}`,
+		"":    `limiter:redis:hash-tag-test:{\e}`,
+	} {
+		_, err = client.Del(ctx, cacheKey).Result()
+		is.NoError(err)
+
+		_, err = store.Get(ctx, key, limiter.Rate{
+			Limit:  3,
+			Period: time.Minute,
+		})
+		is.NoError(err)
+
+		value, err = client.Get(ctx, cacheKey).Int64()
+		is.NoError(err)
+		is.Equal(int64(1), value)
+		is.Equal(cacheKey[len("limiter:redis:hash-tag-test:{"):len(cacheKey)-1], hashTag(cacheKey))
+	}
+
+	keys := map[string]bool{}
+	err = store.(limiter.Scanner).Scan(ctx, "", func(entry limiter.Entry) bool {
+		keys[entry.Key] = true
+		return true
+	})
+	is.NoError(err)
+	is.Equal(map[string]bool{"foo": true, "a}b": true, `a\c`: true, "": true}, keys)
+}
+
+func TestRedisStoreClock(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	_, err = client.Del(ctx, "limiter:redis:clock-test:foo").Result()
+	is.NoError(err)
+
+	clock := tests.NewManualClock(time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC))
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix: "limiter:redis:clock-test",
+		Clock:  clock,
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	rate := limiter.Rate{
+		Limit:  3,
+		Period: time.Minute,
+	}
+
+	// Expirations are handled by the server, but reported from the clock.
+	lctx, err := store.Get(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(2), lctx.Remaining)
+	is.InDelta(clock.Now().Add(time.Minute).Unix(), lctx.Reset, 1)
+
+	lctx, err = store.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(2), lctx.Remaining)
+	is.InDelta(clock.Now().Add(time.Minute).Unix(), lctx.Reset, 1)
+
+	clock.Advance(time.Hour)
+
+	lctx, err = store.Reset(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(3), lctx.Remaining)
+	is.Equal(clock.Now().Add(time.Minute).Unix(), lctx.Reset)
+}
+
+func TestRedisStoreScan(t *testing.T) {
+	is := require.New(t)
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	for prefix, hashTag := range map[string]bool{
+		"limiter:redis:scan-test":          false,
+		"limiter:redis:hash-tag-scan-test": true,
+	} {
+		store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+			Prefix:  prefix,
+			HashTag: hashTag,
+		})
+		is.NoError(err)
+		is.NotNil(store)
+
+		tests.TestStoreScan(t, store)
+	}
+
+	// Clients implementing only the Client interface can't scan.
+	store, err := redis.NewStoreWithOptions(struct{ redis.Client }{client}, limiter.StoreOptions{
+		Prefix: "limiter:redis:scan-test",
+	})
+	is.NoError(err)
+
+	err = store.(limiter.Scanner).Scan(context.Background(), "", func(entry limiter.Entry) bool {
+		return true
+	})
+	is.Equal(redis.ErrScanNotSupported, err)
+}
+
+func TestRedisStoreIncrementAll(t *testing.T) {
+	is := require.New(t)
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix: "limiter:redis:increment-all-test",
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.TestStoreIncrementAll(t, store)
+}
+
+func TestRedisStoreHierarchyHashTag(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	prefix := "limiter:redis:hierarchy-hash-tag-test"
+	keys, err := client.Keys(ctx, prefix+":*").Result()
+	is.NoError(err)
+	for _, key := range keys {
+		is.NoError(client.Del(ctx, key).Err())
+	}
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix:  prefix,
+		HashTag: true,
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	rate := limiter.Rate{Limit: 10, Period: time.Minute}
+	hierarchy, err := limiter.NewHierarchy(
+		limiter.Level{Name: "user", Limiter: limiter.New(store, rate)},
+		limiter.Level{Name: "org", Limiter: limiter.New(store, rate)},
+		limiter.Level{Name: "global", Limiter: limiter.New(store, rate)})
+	is.NoError(err)
+
+	_, err = hierarchy.Get(ctx, "alice", "acme", "all")
+	is.NoError(err)
+
+	// Every level shares the hash tag of the last one, so their keys belong to the same slot.
+	keys, err = client.Keys(ctx, prefix+":*").Result()
+	is.NoError(err)
+	is.Len(keys, 3)
+	for _, key := range keys {
+		is.Equal("global:all", hashTag(key), key)
+	}
+
+	entries := []string{}
+	err = store.(limiter.Scanner).Scan(ctx, "", func(entry limiter.Entry) bool {
+		entries = append(entries, entry.Key)
+		return true
+	})
+	is.NoError(err)
+	is.ElementsMatch([]string{"global:all:user:alice", "global:all:org:acme", "global:all:global:all"}, entries)
+}
+
+func TestRedisStoreSet(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix: "limiter:redis:set-test",
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	rate := limiter.Rate{Limit: 10, Period: time.Minute}
+
+	_, err = store.Increment(ctx, "foo", 8, rate)
+	is.NoError(err)
+
+	lctx, err := store.(*redis.Store).Set(ctx, "foo", 3, rate)
+	is.NoError(err)
+	is.Equal(int64(7), lctx.Remaining)
+
+	lctx, err = store.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(7), lctx.Remaining)
+
+	ttl, err := client.PTTL(ctx, "limiter:redis:set-test:foo").Result()
+	is.NoError(err)
+	is.True(ttl > 0 && ttl <= time.Minute, ttl)
+
+	lctx, err = store.Increment(ctx, "foo", 1, rate)
+	is.NoError(err)
+	is.Equal(int64(6), lctx.Remaining)
+
+	_, err = store.Reset(ctx, "foo", rate)
+	is.NoError(err)
+}
+
+func TestRedisStoreClusterScan(t *testing.T) {
+	is := require.New(t)
+
+	client, err := newRedisClusterClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix:  "limiter:redis:cluster-scan-test",
+		HashTag: true,
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.TestStoreScan(t, store)
+}
+
+func TestRedisStoreClusterSequentialAccess(t *testing.T) {
+	is := require.New(t)
+
+	client, err := newRedisClusterClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix:  "limiter:redis:cluster-sequential-test",
+		HashTag: true,
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.TestStoreSequentialAccess(t, store)
+}
+
+func TestRedisStoreClusterConcurrentAccess(t *testing.T) {
+	is := require.New(t)
+
+	client, err := newRedisClusterClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix:      "limiter:redis:cluster-concurrent-test",
+		HashTag:     true,
+		BatchWindow: time.Millisecond,
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.TestStoreConcurrentAccess(t, store)
+}
+
+func TestRedisStoreClusterBatchAccess(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	client, err := newRedisClusterClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	for _, key := range []string{"foo", "bar", "baz"} {
+		_, err = client.Del(ctx, "limiter:redis:cluster-batch-test:{"+key+"}").Result()
+		is.NoError(err)
+	}
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix:  "limiter:redis:cluster-batch-test",
+		HashTag: true,
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.TestStoreBatchAccess(t, store)
+}
+
 func TestRedisClientExpiration(t *testing.T) {
 	is := require.New(t)
 
@@ -124,6 +588,67 @@ func BenchmarkRedisStoreConcurrentAccess(b *testing.B) {
 	tests.BenchmarkStoreConcurrentAccess(b, store)
 }
 
+func BenchmarkRedisStoreConcurrentLatency(b *testing.B) {
+	is := require.New(b)
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix: "limiter:redis:concurrent-latency-benchmark",
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.BenchmarkStoreConcurrentLatency(b, store)
+}
+
+func BenchmarkRedisStoreBatchedConcurrentLatency(b *testing.B) {
+	is := require.New(b)
+
+	client, err := newRedisClient()
+	is.NoError(err)
+	is.NotNil(client)
+
+	store, err := redis.NewStoreWithOptions(client, limiter.StoreOptions{
+		Prefix:      "limiter:redis:batched-concurrent-latency-benchmark",
+		BatchWindow: 500 * time.Microsecond,
+	})
+	is.NoError(err)
+	is.NotNil(store)
+
+	tests.BenchmarkStoreConcurrentLatency(b, store)
+}
+
+// pipelineCounter counts the number of pipelines sent to redis server.
+type pipelineCounter struct {
+	*libredis.Client
+	pipelines int64
+}
+
+func (counter *pipelineCounter) Pipelined(ctx context.Context,
+	fn func(libredis.Pipeliner) error) ([]libredis.Cmder, error) {
+
+	atomic.AddInt64(&counter.pipelines, 1)
+	return counter.Client.Pipelined(ctx, fn)
+}
+
+// hashTag returns the part of given key hashed by Redis Cluster to find its slot.
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+
+	return key[start+1 : start+1+end]
+}
+
 func newRedisClient() (*libredis.Client, error) {
 	uri := "redis://localhost:6379/0"
 	if os.Getenv("REDIS_URI") != "" {
@@ -138,3 +663,39 @@ func newRedisClient() (*libredis.Client, error) {
 	client := libredis.NewClient(opt)
 	return client, nil
 }
+
+// newRedisClusterClient returns a cluster client which spreads its slots on two nodes of the same redis server.
+// It allows to use a cluster client with a redis server without cluster mode.
+func newRedisClusterClient() (*libredis.ClusterClient, error) {
+	uri := "redis://localhost:6379/0"
+	if os.Getenv("REDIS_URI") != "" {
+		uri = os.Getenv("REDIS_URI")
+	}
+
+	opt, err := libredis.ParseURL(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := func(ctx context.Context) ([]libredis.ClusterSlot, error) {
+		return []libredis.ClusterSlot{
+			{
+				Start: 0,
+				End:   8191,
+				Nodes: []libredis.ClusterNode{{Addr: opt.Addr}},
+			},
+			{
+				Start: 8192,
+				End:   16383,
+				Nodes: []libredis.ClusterNode{{Addr: opt.Addr}},
+			},
+		}, nil
+	}
+
+	client := libredis.NewClusterClient(&libredis.ClusterOptions{
+		ClusterSlots: slots,
+		Username:     opt.Username,
+		Password:     opt.Password,
+	})
+	return client, nil
+}
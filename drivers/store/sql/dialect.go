@@ -0,0 +1,160 @@
+package sql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Dialect defines the SQL flavor of a database.
+type Dialect int
+
+const (
+	// Postgres is the dialect of PostgreSQL (9.5 or later).
+	Postgres Dialect = iota + 1
+	// MySQL is the dialect of MySQL and MariaDB.
+	MySQL
+	// SQLite is the dialect of SQLite (3.35 or later).
+	SQLite
+)
+
+// String returns the name of the dialect.
+func (dialect Dialect) String() string {
+	switch dialect {
+	case Postgres:
+		return "postgres"
+	case MySQL:
+		return "mysql"
+	case SQLite:
+		return "sqlite"
+	default:
+		return fmt.Sprintf("dialect(%d)", int(dialect))
+	}
+}
+
+// queries are the statements used by a store for a table.
+//
+// A counter is a row with its identifier, its hits and its expiration, in milliseconds since epoch.
+type queries struct {
+	// migrate creates the table, if it doesn't exist.
+	migrate []string
+	// increment atomically creates or increments a counter, starting a new window if it has expired.
+	increment string
+	// incrementArgs returns the arguments of increment.
+	incrementArgs func(id string, hits int64, expiration int64, now int64) []interface{}
+	// returning is true if increment returns the hits and the expiration of the counter.
+	// Otherwise, they are read with get in the same transaction.
+	returning bool
+	// get returns the hits and the expiration of a counter.
+	get string
+	// reset deletes a counter.
+	reset string
+	// clean deletes every expired counter.
+	clean string
+}
+
+// tablePattern matches the valid table names: an identifier, optionally qualified by a schema.
+var tablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// getQueries returns the statements of given dialect for given table.
+// Table names are not quoted, so an error is returned if the table isn't a valid identifier.
+func getQueries(dialect Dialect, table string) (queries, error) {
+	if !tablePattern.MatchString(table) {
+		return queries{}, errors.Errorf("invalid sql table name: '%s'", table)
+	}
+
+	// An index is created in the schema of its table, so its name is never qualified.
+	index := strings.ReplaceAll(table, ".", "_") + "_expires_at"
+
+	switch dialect {
+	case Postgres:
+		return queries{
+			migrate: []string{
+				fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(255) NOT NULL PRIMARY KEY,
+	hits BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, table),
+				fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (expires_at)`, index, table),
+			},
+			increment: fmt.Sprintf(`INSERT INTO %s (id, hits, expires_at) VALUES ($1, $2, $3)
+ON CONFLICT (id) DO UPDATE SET
+	hits = CASE WHEN %s.expires_at < $4 THEN EXCLUDED.hits ELSE %s.hits + EXCLUDED.hits END,
+	expires_at = CASE WHEN %s.expires_at < $4 THEN EXCLUDED.expires_at ELSE %s.expires_at END
+RETURNING hits, expires_at`, table, table, table, table, table),
+			incrementArgs: numberedArgs,
+			returning:     true,
+			get:           fmt.Sprintf(`SELECT hits, expires_at FROM %s WHERE id = $1`, table),
+			reset:         fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, table),
+			clean:         fmt.Sprintf(`DELETE FROM %s WHERE expires_at < $1`, table),
+		}, nil
+
+	case SQLite:
+		return queries{
+			migrate: []string{
+				fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(255) NOT NULL PRIMARY KEY,
+	hits BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, table),
+				sqliteIndex(table),
+			},
+			increment: fmt.Sprintf(`INSERT INTO %s (id, hits, expires_at) VALUES (?1, ?2, ?3)
+ON CONFLICT (id) DO UPDATE SET
+	hits = CASE WHEN %s.expires_at < ?4 THEN excluded.hits ELSE %s.hits + excluded.hits END,
+	expires_at = CASE WHEN %s.expires_at < ?4 THEN excluded.expires_at ELSE %s.expires_at END
+RETURNING hits, expires_at`, table, table, table, table, table),
+			incrementArgs: numberedArgs,
+			returning:     true,
+			get:           fmt.Sprintf(`SELECT hits, expires_at FROM %s WHERE id = ?`, table),
+			reset:         fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, table),
+			clean:         fmt.Sprintf(`DELETE FROM %s WHERE expires_at < ?`, table),
+		}, nil
+
+	case MySQL:
+		// Assignments are evaluated from left to right: hits must be assigned first, so that both conditions
+		// use the previous expiration.
+		return queries{
+			migrate: []string{
+				fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(255) NOT NULL PRIMARY KEY,
+	hits BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL,
+	INDEX %s (expires_at)
+)`, table, index),
+			},
+			increment: fmt.Sprintf(`INSERT INTO %s (id, hits, expires_at) VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE
+	hits = IF(expires_at < ?, VALUES(hits), hits + VALUES(hits)),
+	expires_at = IF(expires_at < ?, VALUES(expires_at), expires_at)`, table),
+			incrementArgs: func(id string, hits int64, expiration int64, now int64) []interface{} {
+				return []interface{}{id, hits, expiration, now, now}
+			},
+			returning: false,
+			get:       fmt.Sprintf(`SELECT hits, expires_at FROM %s WHERE id = ?`, table),
+			reset:     fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, table),
+			clean:     fmt.Sprintf(`DELETE FROM %s WHERE expires_at < ?`, table),
+		}, nil
+
+	default:
+		return queries{}, errors.Errorf("unsupported sql dialect: %s", dialect)
+	}
+}
+
+// sqliteIndex returns the statement creating the expiration index of given table with SQLite, which expects
+// the schema before the name of the index rather than before the name of the table.
+func sqliteIndex(table string) string {
+	schema, name := "", table
+	if i := strings.IndexByte(table, '.'); i >= 0 {
+		schema, name = table[:i+1], table[i+1:]
+	}
+
+	return fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s%s_expires_at ON %s (expires_at)`, schema, name, name)
+}
+
+// numberedArgs returns the arguments of an increment statement using numbered placeholders.
+func numberedArgs(id string, hits int64, expiration int64, now int64) []interface{} {
+	return []interface{}{id, hits, expiration, now}
+}
@@ -0,0 +1,349 @@
+package sql_test
+
+import (
+	libsql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// fakeDriverName is the name of a fake database/sql driver, which understands the statements of sql store.
+const fakeDriverName = "limiter-fake"
+
+// fake is the registered fake driver.
+var fake = &fakeDriver{
+	databases: map[string]*fakeDatabase{},
+}
+
+func init() {
+	libsql.Register(fakeDriverName, fake)
+}
+
+// fakeDriver opens fake databases by name.
+type fakeDriver struct {
+	mutex     sync.Mutex
+	databases map[string]*fakeDatabase
+}
+
+// fakeRow is a counter in a fake database.
+type fakeRow struct {
+	hits       int64
+	expiration int64
+}
+
+// fakeDatabase is an in-memory database with a single kind of table, with counters.
+type fakeDatabase struct {
+	mutex   sync.Mutex
+	tables  map[string]map[string]fakeRow
+	queries []string
+}
+
+// maxQueries is the number of statements kept by a fake database.
+const maxQueries = 1000
+
+// log keeps given statement.
+// The caller must hold the database mutex.
+func (database *fakeDatabase) log(query string) {
+	if len(database.queries) < maxQueries {
+		database.queries = append(database.queries, query)
+	}
+}
+
+// lookupDatabase returns the fake database of given name.
+func lookupDatabase(name string) *fakeDatabase {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return fake.databases[name]
+}
+
+// Open returns a connection to the fake database of given name, creating it if required.
+func (fake *fakeDriver) Open(name string) (driver.Conn, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	database, ok := fake.databases[name]
+	if !ok {
+		database = &fakeDatabase{
+			tables: map[string]map[string]fakeRow{},
+		}
+		fake.databases[name] = database
+	}
+
+	return &fakeConn{database: database}, nil
+}
+
+// fakeConn is a connection to a fake database.
+// A transaction locks the whole database until it's committed or rolled back.
+type fakeConn struct {
+	database *fakeDatabase
+	tx       bool
+}
+
+func (conn *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: conn, query: query}, nil
+}
+
+func (conn *fakeConn) Close() error {
+	return nil
+}
+
+func (conn *fakeConn) Begin() (driver.Tx, error) {
+	conn.database.mutex.Lock()
+	conn.tx = true
+	conn.database.log("BEGIN")
+	return &fakeTx{conn: conn}, nil
+}
+
+// fakeTx is a transaction on a fake database.
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.conn.database.log("COMMIT")
+	tx.conn.tx = false
+	tx.conn.database.mutex.Unlock()
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.conn.database.log("ROLLBACK")
+	tx.conn.tx = false
+	tx.conn.database.mutex.Unlock()
+	return nil
+}
+
+// fakeStmt is a statement on a fake database.
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (stmt *fakeStmt) Close() error {
+	return nil
+}
+
+func (stmt *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (stmt *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	_, _, affected, err := stmt.execute(args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+func (stmt *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	columns, values, _, err := stmt.execute(args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, values: values}, nil
+}
+
+var (
+	numberedPlaceholder = regexp.MustCompile(`[$?](\d+)`)
+	tableName           = regexp.MustCompile(`(?:TABLE IF NOT EXISTS|TABLE|INTO|FROM) (\w+)`)
+)
+
+// parsedQuery is a statement analyzed by a fake database.
+type parsedQuery struct {
+	// query is the statement, with normalized whitespaces.
+	query string
+	// args is the number of arguments expected by the statement.
+	args int
+	// match contains the table name of the statement, if any.
+	match []string
+	// err is not nil if the statement is invalid.
+	err error
+}
+
+// parsedQueries caches analyzed statements, since the same ones are executed again and again.
+var parsedQueries sync.Map
+
+// parseQuery returns the analysis of given statement.
+func parseQuery(query string) *parsedQuery {
+	value, ok := parsedQueries.Load(query)
+	if ok {
+		return value.(*parsedQuery)
+	}
+
+	parsed := &parsedQuery{
+		query: strings.Join(strings.Fields(query), " "),
+	}
+	parsed.args, parsed.err = countPlaceholders(parsed.query)
+	parsed.match = tableName.FindStringSubmatch(parsed.query)
+	if parsed.match == nil && !strings.HasPrefix(parsed.query, "CREATE INDEX") {
+		parsed.err = errors.Errorf("unsupported query: %s", parsed.query)
+	}
+
+	parsedQueries.Store(query, parsed)
+	return parsed
+}
+
+// execute runs the statement, recognized by its kind, and checks its placeholders against given arguments.
+func (stmt *fakeStmt) execute(args []driver.Value) ([]string, [][]driver.Value, int64, error) {
+	database := stmt.conn.database
+	if !stmt.conn.tx {
+		database.mutex.Lock()
+		defer database.mutex.Unlock()
+	}
+
+	parsed := parseQuery(stmt.query)
+	query, match := parsed.query, parsed.match
+	database.log(query)
+
+	if parsed.err != nil {
+		return nil, nil, 0, parsed.err
+	}
+	if parsed.args != len(args) {
+		return nil, nil, 0, errors.Errorf("query expects %d arguments, %d given: %s", parsed.args, len(args), query)
+	}
+
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE"):
+		_, ok := database.tables[match[1]]
+		if ok && !strings.Contains(query, "IF NOT EXISTS") {
+			return nil, nil, 0, errors.Errorf("table %s already exists", match[1])
+		}
+		if !ok {
+			database.tables[match[1]] = map[string]fakeRow{}
+		}
+		return nil, nil, 0, nil
+
+	case strings.HasPrefix(query, "CREATE INDEX"):
+		return nil, nil, 0, nil
+	}
+
+	table, ok := database.tables[match[1]]
+	if !ok {
+		return nil, nil, 0, errors.Errorf("no such table: %s", match[1])
+	}
+
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO"):
+		id, hits, expiration, now := args[0].(string), args[1].(int64), args[2].(int64), args[3].(int64)
+		if utf8.RuneCountInString(id) > 255 {
+			return nil, nil, 0, errors.New("value too long for column id")
+		}
+
+		row, ok := table[id]
+		if !ok || row.expiration < now {
+			row = fakeRow{hits: hits, expiration: expiration}
+		} else {
+			row.hits += hits
+		}
+		table[id] = row
+
+		if strings.Contains(query, "RETURNING hits, expires_at") {
+			return []string{"hits", "expires_at"}, [][]driver.Value{{row.hits, row.expiration}}, 1, nil
+		}
+		return nil, nil, 1, nil
+
+	case strings.HasPrefix(query, "SELECT hits, expires_at FROM"):
+		row, ok := table[args[0].(string)]
+		if !ok {
+			return []string{"hits", "expires_at"}, nil, 0, nil
+		}
+		return []string{"hits", "expires_at"}, [][]driver.Value{{row.hits, row.expiration}}, 0, nil
+
+	case strings.HasPrefix(query, "DELETE FROM") && strings.Contains(query, "WHERE expires_at <"):
+		affected := int64(0)
+		for id, row := range table {
+			if row.expiration < args[0].(int64) {
+				delete(table, id)
+				affected++
+			}
+		}
+		return nil, nil, affected, nil
+
+	case strings.HasPrefix(query, "DELETE FROM") && strings.Contains(query, "WHERE id ="):
+		_, ok := table[args[0].(string)]
+		delete(table, args[0].(string))
+		if ok {
+			return nil, nil, 1, nil
+		}
+		return nil, nil, 0, nil
+
+	default:
+		return nil, nil, 0, errors.Errorf("unsupported query: %s", query)
+	}
+}
+
+// countPlaceholders returns the number of arguments expected by given query.
+// Numbered placeholders ($1, ?1) may be used several times, while anonymous ones (?) match a single argument.
+func countPlaceholders(query string) (int, error) {
+	expected := 0
+	for _, match := range numberedPlaceholder.FindAllStringSubmatch(query, -1) {
+		index, _ := strconv.Atoi(match[1])
+		if index > expected {
+			expected = index
+		}
+	}
+
+	anonymous := strings.Count(numberedPlaceholder.ReplaceAllString(query, ""), "?")
+	if anonymous > 0 && expected > 0 {
+		return 0, errors.Errorf("query mixes numbered and anonymous placeholders: %s", query)
+	}
+
+	return expected + anonymous, nil
+}
+
+// fakeRows are the rows returned by a statement on a fake database.
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	index   int
+}
+
+func (rows *fakeRows) Columns() []string {
+	return rows.columns
+}
+
+func (rows *fakeRows) Close() error {
+	return nil
+}
+
+func (rows *fakeRows) Next(dest []driver.Value) error {
+	if rows.index >= len(rows.values) {
+		return io.EOF
+	}
+
+	copy(dest, rows.values[rows.index])
+	rows.index++
+	return nil
+}
+
+// countRows returns the number of rows in given table of given fake database.
+func countRows(name string, table string) int {
+	database := lookupDatabase(name)
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	return len(database.tables[table])
+}
+
+// getQueries returns the statements executed by given fake database.
+func getQueries(name string) []string {
+	database := lookupDatabase(name)
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	return append([]string{}, database.queries...)
+}
+
+// newDatabaseName returns a unique name for a fake database.
+func newDatabaseName(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, atomic.AddInt64(&databaseCounter, 1))
+}
+
+// databaseCounter is used to give a unique name to each fake database.
+var databaseCounter int64
@@ -0,0 +1,303 @@
+package sql
+
+import (
+	"context"
+	"crypto/sha256"
+	libsql "database/sql"
+	"encoding/hex"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/common"
+)
+
+// maxKeyLength is the length, in characters, of the id column.
+const maxKeyLength = 255
+
+// Store is the sql store, which keeps counters in a table of a relational database through database/sql.
+// The table must be created beforehand, using Migrate.
+type Store struct {
+	// Prefix used for the key.
+	Prefix string
+	// table used to store counters.
+	table *table
+}
+
+// table is a table of counters.
+type table struct {
+	db      *libsql.DB
+	queries queries
+	clock   limiter.Clock
+	cleaner *cleaner
+}
+
+// NewStore returns an instance of sql store with defaults.
+func NewStore(db *libsql.DB, dialect Dialect) (limiter.Store, error) {
+	return NewStoreWithOptions(db, dialect, limiter.StoreOptions{
+		Prefix:          limiter.DefaultPrefix,
+		CleanUpInterval: limiter.DefaultCleanUpInterval,
+		Table:           limiter.DefaultTable,
+	})
+}
+
+// NewStoreWithOptions returns an instance of sql store with options.
+// Expired rows are deleted every CleanUpInterval.
+func NewStoreWithOptions(db *libsql.DB, dialect Dialect, options limiter.StoreOptions) (limiter.Store, error) {
+	queries, err := getQueries(dialect, getTableName(options))
+	if err != nil {
+		return nil, err
+	}
+
+	table := &table{
+		db:      db,
+		queries: queries,
+		clock:   options.Clock,
+	}
+
+	if table.clock == nil {
+		table.clock = limiter.SystemClock
+	}
+
+	store := &Store{
+		Prefix: options.Prefix,
+		table:  table,
+	}
+
+	if options.CleanUpInterval > 0 {
+		startCleaner(table, options.CleanUpInterval)
+		runtime.SetFinalizer(store, stopCleaner)
+	}
+
+	return store, nil
+}
+
+// Migrate creates the table used by a sql store with given options, if it doesn't exist.
+func Migrate(ctx context.Context, db *libsql.DB, dialect Dialect, options limiter.StoreOptions) error {
+	queries, err := getQueries(dialect, getTableName(options))
+	if err != nil {
+		return err
+	}
+
+	for _, query := range queries.migrate {
+		_, err = db.ExecContext(ctx, query)
+		if err != nil {
+			return errors.Wrap(err, "cannot migrate sql store")
+		}
+	}
+
+	return nil
+}
+
+// Get returns the limit for given identifier.
+func (store *Store) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return store.Increment(ctx, key, 1, rate)
+}
+
+// Increment increments the limit by given count & returns the new limit value for given identifier.
+func (store *Store) Increment(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
+	now := store.table.clock.Now()
+
+	hits, expiration, err := store.table.increment(ctx, store.getCacheKey(key), count, now, rate.Period)
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	return common.GetContextFromState(now, rate, expiration, hits), nil
+}
+
+// Peek returns the limit for given identifier, without modification on current values.
+func (store *Store) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	now := store.table.clock.Now()
+
+	hits, expiration, err := store.table.get(ctx, store.table.db, store.getCacheKey(key))
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	if expiration.Before(now) {
+		hits = 0
+		expiration = now.Add(rate.Period)
+	}
+
+	return common.GetContextFromState(now, rate, expiration, hits), nil
+}
+
+// Reset returns the limit for given identifier which is set to zero.
+func (store *Store) Reset(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	_, err := store.table.db.ExecContext(ctx, store.table.queries.reset, store.getCacheKey(key))
+	if err != nil {
+		return limiter.Context{}, errors.Wrap(err, "cannot reset counter")
+	}
+
+	now := store.table.clock.Now()
+	return common.GetContextFromState(now, rate, now.Add(rate.Period), 0), nil
+}
+
+// Clean deletes every expired row.
+func (store *Store) Clean(ctx context.Context) error {
+	return store.table.clean(ctx)
+}
+
+// getCacheKey returns the full path for an identifier.
+// A path longer than the id column is shortened: it keeps its first characters, followed by a hash of the whole
+// path, so it's still unique.
+func (store *Store) getCacheKey(key string) string {
+	buffer := strings.Builder{}
+	buffer.WriteString(store.Prefix)
+	buffer.WriteString(":")
+	buffer.WriteString(key)
+
+	path := buffer.String()
+	if utf8.RuneCountInString(path) <= maxKeyLength {
+		return path
+	}
+
+	sum := sha256.Sum256([]byte(path))
+	hash := hex.EncodeToString(sum[:])
+
+	buffer.Reset()
+	length := 0
+	for _, char := range path {
+		if length == maxKeyLength-len(hash)-1 {
+			break
+		}
+		buffer.WriteRune(char)
+		length++
+	}
+	buffer.WriteString("#")
+	buffer.WriteString(hash)
+
+	return buffer.String()
+}
+
+// querier is implemented by both *libsql.DB and *libsql.Tx.
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *libsql.Row
+}
+
+// increment creates or increments the counter of given key, and returns its hits and its expiration.
+func (table *table) increment(ctx context.Context, key string, count int64, now time.Time,
+	period time.Duration) (int64, time.Time, error) {
+
+	args := table.queries.incrementArgs(key, count, now.Add(period).UnixNano()/int64(time.Millisecond),
+		now.UnixNano()/int64(time.Millisecond))
+
+	if table.queries.returning {
+		hits, expiration := int64(0), int64(0)
+		err := table.db.QueryRowContext(ctx, table.queries.increment, args...).Scan(&hits, &expiration)
+		if err != nil {
+			return 0, time.Time{}, errors.Wrap(err, "cannot increment counter")
+		}
+
+		return hits, fromMillis(expiration), nil
+	}
+
+	// Without a RETURNING clause, the counter is read in the same transaction, which holds its row lock.
+	tx, err := table.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, time.Time{}, errors.Wrap(err, "cannot increment counter")
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, table.queries.increment, args...)
+	if err != nil {
+		return 0, time.Time{}, errors.Wrap(err, "cannot increment counter")
+	}
+
+	hits, expiration, err := table.get(ctx, tx, key)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, time.Time{}, errors.Wrap(err, "cannot increment counter")
+	}
+
+	return hits, expiration, nil
+}
+
+// get returns the hits and the expiration of the counter of given key.
+// A missing counter has no hits and is already expired.
+func (table *table) get(ctx context.Context, querier querier, key string) (int64, time.Time, error) {
+	hits, expiration := int64(0), int64(0)
+	err := querier.QueryRowContext(ctx, table.queries.get, key).Scan(&hits, &expiration)
+	if err == libsql.ErrNoRows {
+		return 0, time.Time{}, nil
+	}
+	if err != nil {
+		return 0, time.Time{}, errors.Wrap(err, "cannot get counter")
+	}
+
+	return hits, fromMillis(expiration), nil
+}
+
+// clean deletes every expired row.
+func (table *table) clean(ctx context.Context) error {
+	now := table.clock.Now().UnixNano() / int64(time.Millisecond)
+
+	_, err := table.db.ExecContext(ctx, table.queries.clean, now)
+	if err != nil {
+		return errors.Wrap(err, "cannot clean expired counters")
+	}
+
+	return nil
+}
+
+// fromMillis returns the time of given milliseconds since epoch.
+func fromMillis(value int64) time.Time {
+	return time.Unix(0, value*int64(time.Millisecond))
+}
+
+// getTableName returns the table defined in given options, or the default one.
+func getTableName(options limiter.StoreOptions) string {
+	if options.Table == "" {
+		return limiter.DefaultTable
+	}
+	return options.Table
+}
+
+// A cleaner will periodically delete expired rows of a table.
+type cleaner struct {
+	interval time.Duration
+	stop     chan bool
+}
+
+// Run will periodically delete expired rows of given table until GC notify that it should stop.
+func (cleaner *cleaner) Run(table *table) {
+	ticker := time.NewTicker(cleaner.interval)
+	for {
+		select {
+		case <-ticker.C:
+			// On failure, expired rows are deleted on the next attempt.
+			_ = table.clean(context.Background())
+		case <-cleaner.stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// stopCleaner is a callback from GC used to stop cleaner goroutine.
+func stopCleaner(store *Store) {
+	store.table.cleaner.stop <- true
+	store.table.cleaner = nil
+}
+
+// startCleaner will start a cleaner goroutine for given table.
+func startCleaner(table *table, interval time.Duration) {
+	cleaner := &cleaner{
+		interval: interval,
+		stop:     make(chan bool),
+	}
+
+	table.cleaner = cleaner
+	go cleaner.Run(table)
+}
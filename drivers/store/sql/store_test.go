@@ -0,0 +1,245 @@
+package sql_test
+
+import (
+	"context"
+	libsql "database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/sql"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
+)
+
+var dialects = []sql.Dialect{sql.Postgres, sql.MySQL, sql.SQLite}
+
+func TestSQLStoreSequentialAccess(t *testing.T) {
+	for _, dialect := range dialects {
+		store, _ := newStore(t, dialect, limiter.StoreOptions{
+			Prefix:          "limiter:sql:sequential-test",
+			CleanUpInterval: 30 * time.Second,
+		})
+		tests.TestStoreSequentialAccess(t, store)
+	}
+}
+
+func TestSQLStoreConcurrentAccess(t *testing.T) {
+	for _, dialect := range dialects {
+		store, _ := newStore(t, dialect, limiter.StoreOptions{
+			Prefix:          "limiter:sql:concurrent-test",
+			CleanUpInterval: 1 * time.Millisecond,
+		})
+		tests.TestStoreConcurrentAccess(t, store)
+	}
+}
+
+func TestSQLStoreBatchAccess(t *testing.T) {
+	for _, dialect := range dialects {
+		store, _ := newStore(t, dialect, limiter.StoreOptions{
+			Prefix: "limiter:sql:batch-test",
+		})
+		tests.TestStoreBatchAccess(t, store)
+	}
+}
+
+func TestSQLStoreOverAdmission(t *testing.T) {
+	for _, dialect := range dialects {
+		store, _ := newStore(t, dialect, limiter.StoreOptions{
+			Prefix: "limiter:sql:over-admission-test",
+		})
+		tests.TestStoreOverAdmission(t, []limiter.Store{store}, 0)
+	}
+}
+
+func TestSQLStoreWindowRollover(t *testing.T) {
+	for _, dialect := range dialects {
+		clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+		store, _ := newStore(t, dialect, limiter.StoreOptions{
+			Prefix: "limiter:sql:window-rollover-test",
+			Clock:  clock,
+		})
+		tests.TestStoreWindowRollover(t, store, clock)
+	}
+}
+
+func TestSQLStoreClean(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	store, name := newStore(t, sql.Postgres, limiter.StoreOptions{
+		Prefix: "limiter:sql:clean-test",
+		Clock:  clock,
+	})
+
+	_, err := store.Get(ctx, "foo", limiter.Rate{Limit: 10, Period: time.Minute})
+	is.NoError(err)
+	_, err = store.Get(ctx, "bar", limiter.Rate{Limit: 10, Period: time.Second})
+	is.NoError(err)
+
+	err = store.(*sql.Store).Clean(ctx)
+	is.NoError(err)
+	is.Equal(2, countRows(name, limiter.DefaultTable))
+
+	clock.Advance(2 * time.Second)
+
+	err = store.(*sql.Store).Clean(ctx)
+	is.NoError(err)
+	is.Equal(1, countRows(name, limiter.DefaultTable))
+}
+
+func TestSQLStoreLongKeys(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	rate := limiter.Rate{Limit: 10, Period: time.Minute}
+
+	for _, dialect := range dialects {
+		store, name := newStore(t, dialect, limiter.StoreOptions{
+			Prefix: "limiter:sql:long-keys-test",
+		})
+
+		// Keys longer than the id column are shortened, and still counted separately.
+		long := strings.Repeat("é", 300)
+		for i, key := range []string{long + "foo", long + "bar"} {
+			for j := 0; j < i+1; j++ {
+				_, err := store.Get(ctx, key, rate)
+				is.NoError(err)
+			}
+
+			lctx, err := store.Peek(ctx, key, rate)
+			is.NoError(err)
+			is.Equal(int64(9-i), lctx.Remaining)
+		}
+		is.Equal(2, countRows(name, limiter.DefaultTable))
+
+		_, err := store.Reset(ctx, long+"foo", rate)
+		is.NoError(err)
+		is.Equal(1, countRows(name, limiter.DefaultTable))
+	}
+}
+
+func TestSQLStoreMigrate(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	name := newDatabaseName("migrate-test")
+	db, err := libsql.Open(fakeDriverName, name)
+	is.NoError(err)
+	defer db.Close()
+
+	options := limiter.StoreOptions{
+		Prefix: "limiter:sql:migrate-test",
+		Table:  "rate_limits",
+	}
+
+	store, err := sql.NewStoreWithOptions(db, sql.Postgres, options)
+	is.NoError(err)
+
+	// The table must be created first.
+	_, err = store.Get(ctx, "foo", limiter.Rate{Limit: 10, Period: time.Minute})
+	is.Error(err)
+
+	err = sql.Migrate(ctx, db, sql.Postgres, options)
+	is.NoError(err)
+
+	// Migrate can be run several times.
+	err = sql.Migrate(ctx, db, sql.Postgres, options)
+	is.NoError(err)
+
+	lctx, err := store.Get(ctx, "foo", limiter.Rate{Limit: 10, Period: time.Minute})
+	is.NoError(err)
+	is.Equal(int64(9), lctx.Remaining)
+	is.Equal(1, countRows(name, "rate_limits"))
+
+	_, err = sql.NewStoreWithOptions(db, sql.Dialect(42), options)
+	is.Error(err)
+
+	err = sql.Migrate(ctx, db, sql.Dialect(42), options)
+	is.Error(err)
+
+	// Table names are not quoted, so they must be valid identifiers.
+	for _, table := range []string{"rate_limits; DROP TABLE users", "rate-limits", "1limits", "a.b.c", "a."} {
+		invalid := limiter.StoreOptions{Prefix: options.Prefix, Table: table}
+
+		_, err = sql.NewStoreWithOptions(db, sql.Postgres, invalid)
+		is.Error(err, table)
+
+		err = sql.Migrate(ctx, db, sql.Postgres, invalid)
+		is.Error(err, table)
+	}
+}
+
+func TestSQLStoreDialects(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	expected := map[sql.Dialect][]string{
+		sql.Postgres: {
+			"INSERT INTO limiter (id, hits, expires_at) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET " +
+				"hits = CASE WHEN limiter.expires_at < $4 THEN EXCLUDED.hits ELSE limiter.hits + EXCLUDED.hits END, " +
+				"expires_at = CASE WHEN limiter.expires_at < $4 THEN EXCLUDED.expires_at ELSE limiter.expires_at END " +
+				"RETURNING hits, expires_at",
+		},
+		sql.SQLite: {
+			"INSERT INTO limiter (id, hits, expires_at) VALUES (?1, ?2, ?3) ON CONFLICT (id) DO UPDATE SET " +
+				"hits = CASE WHEN limiter.expires_at < ?4 THEN excluded.hits ELSE limiter.hits + excluded.hits END, " +
+				"expires_at = CASE WHEN limiter.expires_at < ?4 THEN excluded.expires_at ELSE limiter.expires_at END " +
+				"RETURNING hits, expires_at",
+		},
+		sql.MySQL: {
+			"BEGIN",
+			"INSERT INTO limiter (id, hits, expires_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE " +
+				"hits = IF(expires_at < ?, VALUES(hits), hits + VALUES(hits)), " +
+				"expires_at = IF(expires_at < ?, VALUES(expires_at), expires_at)",
+			"SELECT hits, expires_at FROM limiter WHERE id = ?",
+			"COMMIT",
+		},
+	}
+
+	for _, dialect := range dialects {
+		store, name := newStore(t, dialect, limiter.StoreOptions{
+			Prefix: "limiter:sql:dialects-test",
+		})
+
+		_, err := store.Get(ctx, "foo", limiter.Rate{Limit: 10, Period: time.Minute})
+		is.NoError(err)
+
+		// Every expected statement is executed in order, after the migration.
+		queries := getQueries(name)
+		for _, statement := range expected[dialect] {
+			found := false
+			for len(queries) > 0 && !found {
+				found = queries[0] == statement
+				queries = queries[1:]
+			}
+			is.True(found, "%s: statement not found: %s", dialect, statement)
+		}
+	}
+}
+
+// newStore returns a sql store with given dialect, on a new migrated fake database, with the name of this database.
+func newStore(t *testing.T, dialect sql.Dialect, options limiter.StoreOptions) (limiter.Store, string) {
+	is := require.New(t)
+
+	name := newDatabaseName(dialect.String())
+	db, err := libsql.Open(fakeDriverName, name)
+	is.NoError(err)
+	t.Cleanup(func() {
+		is.NoError(db.Close())
+	})
+
+	db.SetMaxOpenConns(16)
+	db.SetMaxIdleConns(16)
+
+	err = sql.Migrate(context.Background(), db, dialect, options)
+	is.NoError(err)
+
+	store, err := sql.NewStoreWithOptions(db, dialect, options)
+	is.NoError(err)
+
+	return store, name
+}
@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"sync"
+	"time"
+)
+
+// ManualClock is a clock which only moves when told to, so that stores can be tested without waiting.
+type ManualClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewManualClock returns a manual clock set to given time.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (clock *ManualClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+// Advance moves the clock forward by given duration.
+func (clock *ManualClock) Advance(duration time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = clock.now.Add(duration)
+}
+
+// Set moves the clock to given time.
+func (clock *ManualClock) Set(now time.Time) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = now
+}
@@ -2,7 +2,9 @@ package tests
 
 import (
 	"context"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -143,6 +145,306 @@ func TestStoreConcurrentAccess(t *testing.T, store limiter.Store) {
 	wg.Wait()
 }
 
+// TestStoreBatchAccess verify that store works as expected with several identifiers at once.
+func TestStoreBatchAccess(t *testing.T, store limiter.Store) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	limiter := limiter.New(store, limiter.Rate{
+		Limit:  3,
+		Period: time.Minute,
+	})
+
+	keys := []string{"foo", "bar", "baz"}
+
+	_, err := limiter.Get(ctx, "bar")
+	is.NoError(err)
+
+	contexts, err := limiter.GetMulti(ctx, keys)
+	is.NoError(err)
+	is.Len(contexts, 3)
+	is.Equal(int64(2), contexts[0].Remaining)
+	is.Equal(int64(1), contexts[1].Remaining)
+	is.Equal(int64(2), contexts[2].Remaining)
+
+	for i := range contexts {
+		is.Equal(int64(3), contexts[i].Limit)
+		is.True((contexts[i].Reset - time.Now().Unix()) <= 60)
+		is.False(contexts[i].Reached)
+	}
+
+	contexts, err = limiter.IncrementMulti(ctx, keys, 2)
+	is.NoError(err)
+	is.Len(contexts, 3)
+	is.Equal(int64(0), contexts[0].Remaining)
+	is.False(contexts[0].Reached)
+	is.Equal(int64(0), contexts[1].Remaining)
+	is.True(contexts[1].Reached)
+	is.Equal(int64(0), contexts[2].Remaining)
+	is.False(contexts[2].Reached)
+
+	for i := range keys {
+		lctx, err := limiter.Peek(ctx, keys[i])
+		is.NoError(err)
+		is.Equal(contexts[i].Remaining, lctx.Remaining)
+		is.Equal(contexts[i].Reached, lctx.Reached)
+	}
+
+	contexts, err = limiter.GetMulti(ctx, []string{})
+	is.NoError(err)
+	is.Empty(contexts)
+}
+
+// TestStoreWindowRollover verify that store computes windows with given clock, which must be used by the store.
+func TestStoreWindowRollover(t *testing.T, store limiter.Store, clock *ManualClock) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	limiter := limiter.New(store, limiter.Rate{
+		Limit:  3,
+		Period: time.Minute,
+	})
+
+	start := clock.Now()
+
+	for i := 1; i <= 4; i++ {
+		lctx, err := limiter.Get(ctx, "foo")
+		is.NoError(err)
+		is.Equal(start.Add(time.Minute).Unix(), lctx.Reset)
+		is.Equal(i > 3, lctx.Reached)
+	}
+
+	// The window isn't over yet.
+	clock.Advance(30 * time.Second)
+
+	lctx, err := limiter.Peek(ctx, "foo")
+	is.NoError(err)
+	is.Equal(int64(0), lctx.Remaining)
+	is.True(lctx.Reached)
+	is.Equal(start.Add(time.Minute).Unix(), lctx.Reset)
+
+	// A new window starts once the previous one is over.
+	clock.Advance(31 * time.Second)
+
+	lctx, err = limiter.Get(ctx, "foo")
+	is.NoError(err)
+	is.Equal(int64(2), lctx.Remaining)
+	is.False(lctx.Reached)
+	is.Equal(clock.Now().Add(time.Minute).Unix(), lctx.Reset)
+
+	// Reset starts a new window from now.
+	clock.Advance(10 * time.Second)
+
+	lctx, err = limiter.Reset(ctx, "foo")
+	is.NoError(err)
+	is.Equal(int64(3), lctx.Remaining)
+	is.Equal(clock.Now().Add(time.Minute).Unix(), lctx.Reset)
+
+	lctx, err = limiter.Get(ctx, "foo")
+	is.NoError(err)
+	is.Equal(int64(2), lctx.Remaining)
+	is.Equal(clock.Now().Add(time.Minute).Unix(), lctx.Reset)
+}
+
+// TestStoreScan verify that store enumerates its identifiers, with their count and expiration.
+// Store must implement limiter.Scanner.
+func TestStoreScan(t *testing.T, store limiter.Store) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	scanner, ok := store.(limiter.Scanner)
+	is.True(ok)
+
+	rate := limiter.Rate{
+		Limit:  10,
+		Period: time.Minute,
+	}
+
+	counts := map[string]int64{
+		"foo:1": 2,
+		"foo:2": 1,
+		"foo*":  3,
+		"foo?":  1,
+		"bar":   1,
+	}
+
+	for key, count := range counts {
+		_, err := store.Reset(ctx, key, rate)
+		is.NoError(err)
+		_, err = store.Increment(ctx, key, count, rate)
+		is.NoError(err)
+	}
+
+	scan := func(prefix string) map[string]int64 {
+		entries := map[string]int64{}
+		err := scanner.Scan(ctx, prefix, func(entry limiter.Entry) bool {
+			is.True(entry.Expiration.After(time.Now()), entry.Key)
+			is.True(entry.Expiration.Before(time.Now().Add(rate.Period+time.Second)), entry.Key)
+			entries[entry.Key] = entry.Count
+			return true
+		})
+		is.NoError(err)
+		return entries
+	}
+
+	// Internal identifiers are skipped.
+	internal := limiter.InternalPrefix + "foo:3"
+	_, err := store.Reset(ctx, internal, rate)
+	is.NoError(err)
+	_, err = store.Increment(ctx, internal, 1, rate)
+	is.NoError(err)
+
+	is.Equal(counts, scan(""))
+	is.Equal(map[string]int64{"foo:1": 2, "foo:2": 1}, scan("foo:"))
+	is.Empty(scan(limiter.InternalPrefix))
+
+	// Prefix is not a pattern.
+	is.Equal(map[string]int64{"foo*": 3}, scan("foo*"))
+	is.Empty(scan("qux"))
+
+	// Scan stops when handler returns false.
+	calls := 0
+	err = scanner.Scan(ctx, "", func(entry limiter.Entry) bool {
+		calls++
+		return false
+	})
+	is.NoError(err)
+	is.Equal(1, calls)
+
+	// Reset identifiers are not enumerated anymore.
+	_, err = store.Reset(ctx, "foo:1", rate)
+	is.NoError(err)
+	is.Equal(map[string]int64{"foo:2": 1}, scan("foo:"))
+
+	_, err = store.Reset(ctx, internal, rate)
+	is.NoError(err)
+}
+
+// TestStoreIncrementAll verify that store increments several identifiers atomically, only if none of them would
+// exceed its limit, including under a concurrent access. Store must implement limiter.AtomicStore.
+func TestStoreIncrementAll(t *testing.T, store limiter.Store) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	atomicStore, ok := store.(limiter.AtomicStore)
+	is.True(ok)
+
+	rates := []limiter.Rate{
+		{Limit: 3, Period: time.Minute},
+		{Limit: 2, Period: time.Minute},
+	}
+	// Identifiers of a group are counted as the group followed by a colon and the identifier.
+	group := "all"
+	keys := []string{"user", "org", "other", "concurrent-user", "concurrent-org"}
+	for _, key := range keys {
+		_, err := store.Reset(ctx, group+":"+key, rates[0])
+		is.NoError(err)
+	}
+
+	for i := int64(1); i <= 2; i++ {
+		contexts, err := atomicStore.IncrementAll(ctx, group, keys[:2], 1, rates)
+		is.NoError(err)
+		is.Len(contexts, 2)
+		is.Equal(limiter.Context{Limit: 3, Remaining: 3 - i, Reset: contexts[0].Reset}, contexts[0])
+		is.Equal(limiter.Context{Limit: 2, Remaining: 2 - i, Reset: contexts[1].Reset}, contexts[1])
+		is.InDelta(time.Now().Add(time.Minute).Unix(), contexts[0].Reset, 1)
+	}
+
+	// The second identifier would exceed its limit: none is incremented.
+	contexts, err := atomicStore.IncrementAll(ctx, group, keys[:2], 1, rates)
+	is.NoError(err)
+	is.False(contexts[0].Reached)
+	is.Equal(int64(1), contexts[0].Remaining)
+	is.True(contexts[1].Reached)
+	is.Equal(int64(0), contexts[1].Remaining)
+
+	lctx, err := store.Peek(ctx, group+":"+keys[0], rates[0])
+	is.NoError(err)
+	is.Equal(int64(1), lctx.Remaining)
+
+	// Identifiers are only rejected by their own limit.
+	contexts, err = atomicStore.IncrementAll(ctx, group, []string{keys[0], keys[2]}, 1, rates)
+	is.NoError(err)
+	is.False(contexts[0].Reached)
+	is.Equal(int64(0), contexts[0].Remaining)
+	is.Equal(int64(1), contexts[1].Remaining)
+
+	// Every identifier requires a rate, and can't be given twice.
+	_, err = atomicStore.IncrementAll(ctx, group, keys[:3], 1, rates)
+	is.Error(err)
+	_, err = atomicStore.IncrementAll(ctx, group, []string{keys[2], keys[2]}, 1, rates)
+	is.Error(err)
+
+	lctx, err = store.Peek(ctx, group+":"+keys[2], rates[1])
+	is.NoError(err)
+	is.Equal(int64(1), lctx.Remaining)
+
+	// Concurrent increments never exceed any limit.
+	rates = []limiter.Rate{
+		{Limit: 100, Period: time.Minute},
+		{Limit: 20, Period: time.Minute},
+	}
+
+	goroutines := 20
+	admitted := int64(0)
+	wg := &sync.WaitGroup{}
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				contexts, err := atomicStore.IncrementAll(ctx, group, keys[3:], 1, rates)
+				is.NoError(err)
+				if !contexts[0].Reached && !contexts[1].Reached {
+					atomic.AddInt64(&admitted, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	is.Equal(int64(20), admitted)
+	lctx, err = store.Peek(ctx, group+":"+keys[3], rates[0])
+	is.NoError(err)
+	is.Equal(int64(80), lctx.Remaining)
+}
+
+// TestStoreOverAdmission verify that stores sharing the same backend never admit more requests than the limit
+// plus given bound, under a concurrent access.
+func TestStoreOverAdmission(t *testing.T, stores []limiter.Store, bound int64) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	rate := limiter.Rate{
+		Limit:  100,
+		Period: time.Minute,
+	}
+
+	goroutines := 50
+	ops := 20
+	admitted := int64(0)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(i int) {
+			instance := limiter.New(stores[i%len(stores)], rate)
+			for j := 0; j < ops; j++ {
+				lctx, err := instance.Get(ctx, "foo")
+				is.NoError(err)
+				if !lctx.Reached {
+					atomic.AddInt64(&admitted, 1)
+				}
+			}
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+
+	is.GreaterOrEqual(admitted, rate.Limit)
+	is.LessOrEqual(admitted, rate.Limit+bound)
+}
+
 // BenchmarkStoreSequentialAccess executes a benchmark against a store without parallel setting.
 func BenchmarkStoreSequentialAccess(b *testing.B, store limiter.Store) {
 	ctx := context.Background()
@@ -174,3 +476,44 @@ func BenchmarkStoreConcurrentAccess(b *testing.B, store limiter.Store) {
 		}
 	})
 }
+
+// BenchmarkStoreConcurrentLatency executes a benchmark against a store with a high parallel setting,
+// and reports latency percentiles.
+func BenchmarkStoreConcurrentLatency(b *testing.B, store limiter.Store) {
+	ctx := context.Background()
+
+	instance := limiter.New(store, limiter.Rate{
+		Limit:  100000,
+		Period: 10 * time.Second,
+	})
+
+	mutex := &sync.Mutex{}
+	latencies := make([]time.Duration, 0, b.N)
+
+	b.SetParallelism(64)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		local := []time.Duration{}
+		for pb.Next() {
+			start := time.Now()
+			_, _ = instance.Get(ctx, "foo")
+			local = append(local, time.Since(start))
+		}
+
+		mutex.Lock()
+		latencies = append(latencies, local...)
+		mutex.Unlock()
+	})
+	b.StopTimer()
+
+	if len(latencies) == 0 {
+		return
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+
+	b.ReportMetric(float64(latencies[len(latencies)/2].Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns")
+}
@@ -0,0 +1,110 @@
+package limiter
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Level is a level of a hierarchy of limits, like a user, an organization or a global limit.
+type Level struct {
+	// Name identifies the level, and prefixes its identifiers in the store.
+	Name string
+	// Limiter gives the rate of the level, including its overrides and its adaptive limit. Its store must be
+	// shared by every level.
+	Limiter *Limiter
+}
+
+// HierarchyContext is the limit context of a hierarchy.
+type HierarchyContext struct {
+	// Contexts are the limit contexts of the levels, in the same order.
+	Contexts []Context
+	// Reached is true if a level has rejected the request.
+	Reached bool
+	// Level is the name of the first level which has rejected the request, if any.
+	Level string
+}
+
+// Hierarchy is a limiter composed of several levels, which a request must all pass: it's counted at every level,
+// or at none of them. Levels are checked and incremented atomically, so their store must implement AtomicStore.
+// Levels use the current rate of their limiter, with its overrides and its adaptive limit, but not its bans.
+//
+// Levels go from the lowest to the highest, and form a tree: an identifier of a level must always belong to the
+// same identifier of the next level, like a user to its organization. The identifier of the last level groups the
+// identifiers of the other levels in the store, so they're kept together by a Redis Cluster or a sharded store.
+type Hierarchy struct {
+	Store  AtomicStore
+	Levels []Level
+}
+
+// NewHierarchy returns an instance of Hierarchy for given levels, sharing the same store.
+func NewHierarchy(levels ...Level) (*Hierarchy, error) {
+	if len(levels) == 0 {
+		return nil, errors.New("at least one level is required")
+	}
+
+	names := map[string]bool{}
+	for i, level := range levels {
+		if level.Name == "" {
+			return nil, errors.Errorf("level %d has no name", i)
+		}
+		if names[level.Name] {
+			return nil, errors.Errorf("level '%s' is defined twice", level.Name)
+		}
+		if level.Limiter == nil || level.Limiter.Store != levels[0].Limiter.Store {
+			return nil, errors.Errorf("level '%s' must use the same store as other levels", level.Name)
+		}
+		names[level.Name] = true
+	}
+
+	store, ok := levels[0].Limiter.Store.(AtomicStore)
+	if !ok {
+		return nil, errors.New("store doesn't support atomic increments")
+	}
+
+	return &Hierarchy{
+		Store:  store,
+		Levels: levels,
+	}, nil
+}
+
+// Get returns the limit for given identifiers, one per level in the same order.
+func (hierarchy *Hierarchy) Get(ctx context.Context, keys ...string) (HierarchyContext, error) {
+	return hierarchy.Increment(ctx, keys, 1)
+}
+
+// Increment increments the limit by given count & gives back the new limit for given identifiers, one per level
+// in the same order. If any level would exceed its limit, no level is incremented.
+func (hierarchy *Hierarchy) Increment(ctx context.Context, keys []string, count int64) (HierarchyContext, error) {
+	if len(keys) != len(hierarchy.Levels) {
+		return HierarchyContext{}, errors.Errorf("%d identifiers were expected, got %d",
+			len(hierarchy.Levels), len(keys))
+	}
+
+	prefixed := make([]string, len(keys))
+	rates := make([]Rate, len(keys))
+	for i, level := range hierarchy.Levels {
+		prefixed[i] = level.Name + ":" + keys[i]
+		rates[i] = level.Limiter.GetRate(keys[i])
+	}
+
+	// The identifier of the last level is the root of the others, so it's shared by all of them.
+	root := len(keys) - 1
+	group := hierarchy.Levels[root].Name + ":" + keys[root]
+
+	contexts, err := hierarchy.Store.IncrementAll(ctx, group, prefixed, count, rates)
+	if err != nil {
+		return HierarchyContext{}, err
+	}
+
+	result := HierarchyContext{Contexts: contexts}
+	for i := range contexts {
+		if contexts[i].Reached {
+			result.Reached = true
+			result.Level = hierarchy.Levels[i].Name
+			break
+		}
+	}
+
+	return result, nil
+}
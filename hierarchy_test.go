@@ -0,0 +1,143 @@
+package limiter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+)
+
+func TestNewHierarchy(t *testing.T) {
+	is := require.New(t)
+
+	store := memory.NewStore()
+	rate := limiter.Rate{Limit: 10, Period: time.Minute}
+
+	_, err := limiter.NewHierarchy()
+	is.Error(err)
+
+	_, err = limiter.NewHierarchy(limiter.Level{Name: "user"})
+	is.Error(err)
+
+	_, err = limiter.NewHierarchy(
+		limiter.Level{Name: "user", Limiter: limiter.New(store, rate)},
+		limiter.Level{Name: "user", Limiter: limiter.New(store, rate)})
+	is.EqualError(err, "level 'user' is defined twice")
+
+	_, err = limiter.NewHierarchy(
+		limiter.Level{Name: "user", Limiter: limiter.New(store, rate)},
+		limiter.Level{Name: "org", Limiter: limiter.New(memory.NewStore(), rate)})
+	is.EqualError(err, "level 'org' must use the same store as other levels")
+
+	_, err = limiter.NewHierarchy(limiter.Level{Name: "user", Limiter: limiter.New(struct{ limiter.Store }{store}, rate)})
+	is.EqualError(err, "store doesn't support atomic increments")
+}
+
+func TestHierarchy(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	store := memory.NewStore()
+	overrides := limiter.NewOverrides()
+
+	hierarchy, err := limiter.NewHierarchy(
+		limiter.Level{Name: "user", Limiter: limiter.New(store, limiter.Rate{Limit: 2, Period: time.Minute},
+			limiter.WithOverrides(overrides))},
+		limiter.Level{Name: "org", Limiter: limiter.New(store, limiter.Rate{Limit: 3, Period: time.Minute})},
+		limiter.Level{Name: "global", Limiter: limiter.New(store, limiter.Rate{Limit: 5, Period: time.Minute})})
+	is.NoError(err)
+
+	hctx, err := hierarchy.Get(ctx, "alice", "acme", "all")
+	is.NoError(err)
+	is.False(hctx.Reached)
+	is.Empty(hctx.Level)
+	is.Len(hctx.Contexts, 3)
+	is.Equal(int64(1), hctx.Contexts[0].Remaining)
+	is.Equal(int64(2), hctx.Contexts[1].Remaining)
+	is.Equal(int64(4), hctx.Contexts[2].Remaining)
+
+	_, err = hierarchy.Get(ctx, "alice", "acme", "all")
+	is.NoError(err)
+
+	// The user level rejects the request, which isn't counted by other levels.
+	hctx, err = hierarchy.Get(ctx, "alice", "acme", "all")
+	is.NoError(err)
+	is.True(hctx.Reached)
+	is.Equal("user", hctx.Level)
+	is.Equal(int64(1), hctx.Contexts[1].Remaining)
+	is.Equal(int64(3), hctx.Contexts[2].Remaining)
+
+	hctx, err = hierarchy.Get(ctx, "bob", "acme", "all")
+	is.NoError(err)
+	is.False(hctx.Reached)
+	is.Equal(int64(0), hctx.Contexts[1].Remaining)
+
+	// Then the organization level.
+	hctx, err = hierarchy.Get(ctx, "carol", "acme", "all")
+	is.NoError(err)
+	is.True(hctx.Reached)
+	is.Equal("org", hctx.Level)
+	is.False(hctx.Contexts[0].Reached)
+	is.Equal(int64(2), hctx.Contexts[0].Remaining)
+
+	// Then the global level, after a bulk increment.
+	hctx, err = hierarchy.Increment(ctx, []string{"dave", "initech", "all"}, 2)
+	is.NoError(err)
+	is.False(hctx.Reached)
+	is.Equal(int64(0), hctx.Contexts[2].Remaining)
+
+	hctx, err = hierarchy.Get(ctx, "erin", "globex", "all")
+	is.NoError(err)
+	is.True(hctx.Reached)
+	is.Equal("global", hctx.Level)
+
+	// Levels use the overrides of their limiter.
+	overrides.Set("alice", limiter.Rate{Limit: 10, Period: time.Minute}, 0)
+	hctx, err = hierarchy.Get(ctx, "alice", "acme", "all")
+	is.NoError(err)
+	is.Equal("org", hctx.Level)
+	is.Equal(int64(8), hctx.Contexts[0].Remaining)
+
+	_, err = hierarchy.Get(ctx, "alice", "acme")
+	is.EqualError(err, "3 identifiers were expected, got 2")
+}
+
+func TestHierarchyAdaptive(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	store := memory.NewStore()
+	global := limiter.New(store, limiter.Rate{Limit: 4, Period: time.Minute},
+		limiter.WithAdaptive(limiter.Adaptive{
+			Min:      1,
+			Increase: 1,
+			Decrease: 0.5,
+			Latency:  100 * time.Millisecond,
+		}))
+
+	hierarchy, err := limiter.NewHierarchy(
+		limiter.Level{Name: "user", Limiter: limiter.New(store, limiter.Rate{Limit: 10, Period: time.Minute})},
+		limiter.Level{Name: "global", Limiter: global})
+	is.NoError(err)
+
+	// Levels use the adapted limit of their limiter.
+	global.Signal(limiter.Signal{Overload: true})
+
+	hctx, err := hierarchy.Get(ctx, "alice", "all")
+	is.NoError(err)
+	is.False(hctx.Reached)
+	is.Equal(int64(2), hctx.Contexts[1].Limit)
+	is.Equal(int64(1), hctx.Contexts[1].Remaining)
+
+	_, err = hierarchy.Get(ctx, "bob", "all")
+	is.NoError(err)
+
+	hctx, err = hierarchy.Get(ctx, "carol", "all")
+	is.NoError(err)
+	is.True(hctx.Reached)
+	is.Equal("global", hctx.Level)
+}
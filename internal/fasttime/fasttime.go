@@ -1,6 +1,6 @@
 //go:build !windows
 
-// Package fasttime gets wallclock time, but super fast.
+// Package fasttime gets monotonic time, but super fast.
 package fasttime
 
 import (
@@ -13,9 +13,10 @@ import (
 //go:linkname now time.now
 func now() (sec int64, nsec int32, mono int64)
 
-// Now returns a monotonic clock value. The actual value will differ across
+// Now returns a monotonic clock value, in nanoseconds. The actual value will differ across
 // systems, but that's okay because we generally only care about the deltas.
-func Now() uint64 {
-	sec, nsec, _ := now()
-	return uint64(sec)*1e9 + uint64(nsec)
+// Unlike the wall clock, it's not affected by clock adjustments (ie: NTP).
+func Now() int64 {
+	_, _, mono := now()
+	return mono
 }
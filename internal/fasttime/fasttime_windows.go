@@ -6,8 +6,11 @@ import "time"
 
 // Forked from https://github.com/sethvargo/go-limiter
 
-// Now returns a monotonic clock value. On Windows, no such clock exists, so we
-// fallback to time.Now().
-func Now() uint64 {
-	return uint64(time.Now().UnixNano())
+// start is used as the origin of the monotonic clock.
+var start = time.Now()
+
+// Now returns a monotonic clock value, in nanoseconds. On Windows, no such fast clock exists, so we
+// fallback to the monotonic reading of time.Now().
+func Now() int64 {
+	return int64(time.Since(start))
 }
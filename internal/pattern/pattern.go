@@ -0,0 +1,38 @@
+package pattern
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Validate returns an error if given path pattern doesn't start with a slash or is malformed.
+func Validate(pattern string) error {
+	if !strings.HasPrefix(pattern, "/") {
+		return errors.Errorf("pattern '%s' must start with a slash", pattern)
+	}
+
+	_, err := path.Match(pattern, "")
+	if err != nil {
+		return errors.Errorf("pattern '%s' is malformed", pattern)
+	}
+
+	return nil
+}
+
+// Match returns true if given path matches given pattern.
+//
+// A pattern ending with a slash matches every path starting with it, like "/api/", and a pattern with
+// wildcards is matched with path.Match, like "/users/*/avatar". Other patterns match a single path.
+func Match(pattern string, name string) bool {
+	switch {
+	case strings.HasSuffix(pattern, "/"):
+		return strings.HasPrefix(name, pattern)
+	case strings.ContainsAny(pattern, "*?["):
+		matched, _ := path.Match(pattern, name)
+		return matched
+	default:
+		return name == pattern
+	}
+}
@@ -2,6 +2,7 @@ package limiter
 
 import (
 	"context"
+	"time"
 )
 
 // -----------------------------------------------------------------
@@ -14,6 +15,8 @@ type Context struct {
 	Remaining int64
 	Reset     int64
 	Reached   bool
+	// Lockout is the remaining duration of a lockout, given by Lockout. It's zero if the identifier is not locked.
+	Lockout time.Duration
 }
 
 // -----------------------------------------------------------------
@@ -25,6 +28,10 @@ type Limiter struct {
 	Store   Store
 	Rate    Rate
 	Options Options
+	// penalty bans the identifiers which keep reaching their limit, if enabled.
+	penalty *penaltyBox
+	// adaptive is the limit adapted to the health of the backend, if enabled.
+	adaptive *adaptiveLimit
 }
 
 // New returns an instance of Limiter.
@@ -38,28 +45,181 @@ func New(store Store, rate Rate, options ...Option) *Limiter {
 		o(&opt)
 	}
 	return &Limiter{
-		Store:   store,
-		Rate:    rate,
-		Options: opt,
+		Store:    store,
+		Rate:     rate,
+		Options:  opt,
+		penalty:  newPenaltyBox(opt.Penalty),
+		adaptive: newAdaptiveLimit(opt.Adaptive, rate),
 	}
 }
 
 // Get returns the limit for given identifier.
 func (limiter *Limiter) Get(ctx context.Context, key string) (Context, error) {
-	return limiter.Store.Get(ctx, key, limiter.Rate)
+	rate := limiter.GetRate(key)
+	return limiter.penalize(ctx, key, 1, rate, func() (Context, error) {
+		return limiter.Store.Get(ctx, key, rate)
+	})
 }
 
 // Peek returns the limit for given identifier, without modification on current values.
 func (limiter *Limiter) Peek(ctx context.Context, key string) (Context, error) {
-	return limiter.Store.Peek(ctx, key, limiter.Rate)
+	rate := limiter.GetRate(key)
+
+	if limiter.penalty != nil {
+		lctx, banned, err := limiter.penalty.check(ctx, limiter.Store, key, rate)
+		if err != nil || banned {
+			return lctx, err
+		}
+	}
+
+	return limiter.Store.Peek(ctx, key, rate)
 }
 
-// Reset sets the limit for given identifier to zero.
+// Reset sets the limit for given identifier to zero, and lifts its ban if any.
 func (limiter *Limiter) Reset(ctx context.Context, key string) (Context, error) {
-	return limiter.Store.Reset(ctx, key, limiter.Rate)
+	if limiter.penalty != nil {
+		err := limiter.penalty.lift(ctx, limiter.Store, key)
+		if err != nil {
+			return Context{}, err
+		}
+	}
+
+	return limiter.Store.Reset(ctx, key, limiter.GetRate(key))
 }
 
 // Increment increments the limit by given count & gives back the new limit for given identifier
 func (limiter *Limiter) Increment(ctx context.Context, key string, count int64) (Context, error) {
-	return limiter.Store.Increment(ctx, key, count, limiter.Rate)
+	rate := limiter.GetRate(key)
+	return limiter.penalize(ctx, key, count, rate, func() (Context, error) {
+		return limiter.Store.Increment(ctx, key, count, rate)
+	})
+}
+
+// penalize calls given function to count hits of given identifier, unless it's banned.
+// If the limit is reached, a violation is counted, which may ban the identifier.
+func (limiter *Limiter) penalize(ctx context.Context, key string, count int64, rate Rate,
+	fn func() (Context, error)) (Context, error) {
+
+	if limiter.penalty == nil {
+		return fn()
+	}
+
+	lctx, banned, err := limiter.penalty.check(ctx, limiter.Store, key, rate)
+	if err != nil || banned {
+		return lctx, err
+	}
+
+	lctx, err = fn()
+	if err != nil || count <= 0 || !lctx.Reached {
+		return lctx, err
+	}
+
+	banctx, banned, err := limiter.penalty.violate(ctx, limiter.Store, key, rate)
+	if err != nil || banned {
+		return banctx, err
+	}
+
+	return lctx, nil
+}
+
+// GetRate returns the rate of given identifier: its override if any, or the current rate of the limiter.
+func (limiter *Limiter) GetRate(key string) Rate {
+	if limiter.Options.Overrides == nil {
+		return limiter.currentRate()
+	}
+
+	override, ok := limiter.Options.Overrides.Get(key)
+	if !ok {
+		return limiter.currentRate()
+	}
+
+	return override.Rate
+}
+
+// currentRate returns the rate of the limiter, with its adapted limit if enabled.
+func (limiter *Limiter) currentRate() Rate {
+	if limiter.adaptive == nil {
+		return limiter.Rate
+	}
+
+	limit := limiter.adaptive.get()
+	if limit == limiter.Rate.Limit {
+		return limiter.Rate
+	}
+
+	return Rate{
+		Period: limiter.Rate.Period,
+		Limit:  limit,
+	}
+}
+
+// Signal gives a measure of the health of the backend to a limiter configured with WithAdaptive, which adapts
+// its limit accordingly. It does nothing otherwise.
+func (limiter *Limiter) Signal(signal Signal) {
+	if limiter.adaptive != nil {
+		limiter.adaptive.signal(signal)
+	}
+}
+
+// batchable returns true if given identifiers can be handled in a single operation: bans are disabled and none
+// of them has an override.
+func (limiter *Limiter) batchable(keys []string) bool {
+	if limiter.penalty != nil {
+		return false
+	}
+
+	if limiter.Options.Overrides == nil {
+		return true
+	}
+
+	for i := range keys {
+		_, ok := limiter.Options.Overrides.Get(keys[i])
+		if ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetMulti returns the limit for given identifiers.
+// If the store implements BatchStore, it's done in a single operation, unless bans are enabled or an identifier
+// has an override.
+func (limiter *Limiter) GetMulti(ctx context.Context, keys []string) ([]Context, error) {
+	store, ok := limiter.Store.(BatchStore)
+	if ok && limiter.batchable(keys) {
+		return store.GetMulti(ctx, keys, limiter.currentRate())
+	}
+
+	contexts := make([]Context, len(keys))
+	for i := range keys {
+		lctx, err := limiter.Get(ctx, keys[i])
+		if err != nil {
+			return nil, err
+		}
+		contexts[i] = lctx
+	}
+
+	return contexts, nil
+}
+
+// IncrementMulti increments the limit by given count & gives back the new limit for given identifiers.
+// If the store implements BatchStore, it's done in a single operation, unless bans are enabled or an identifier
+// has an override.
+func (limiter *Limiter) IncrementMulti(ctx context.Context, keys []string, count int64) ([]Context, error) {
+	store, ok := limiter.Store.(BatchStore)
+	if ok && limiter.batchable(keys) {
+		return store.IncrementMulti(ctx, keys, count, limiter.currentRate())
+	}
+
+	contexts := make([]Context, len(keys))
+	for i := range keys {
+		lctx, err := limiter.Increment(ctx, keys[i], count)
+		if err != nil {
+			return nil, err
+		}
+		contexts[i] = lctx
+	}
+
+	return contexts, nil
 }
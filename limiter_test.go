@@ -1,10 +1,12 @@
 package limiter_test
 
 import (
+	"testing"
 	"time"
 
 	"github.com/ulule/limiter/v3"
 	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
 )
 
 func New(options ...limiter.Option) *limiter.Limiter {
@@ -15,3 +17,9 @@ func New(options ...limiter.Option) *limiter.Limiter {
 	}
 	return limiter.New(store, rate, options...)
 }
+
+// TestLimiterBatchFallback tests batch methods with a store which doesn't implement limiter.BatchStore.
+func TestLimiterBatchFallback(t *testing.T) {
+	store := struct{ limiter.Store }{memory.NewStore()}
+	tests.TestStoreBatchAccess(t, store)
+}
@@ -0,0 +1,193 @@
+package limiter
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+const (
+	// lockoutFailuresPrefix prefixes the identifier of the key counting failures in the store.
+	lockoutFailuresPrefix = InternalPrefix + "lockout:failures:"
+	// lockoutStrikesPrefix prefixes the identifier of the key counting strikes in the store.
+	lockoutStrikesPrefix = InternalPrefix + "lockout:strikes:"
+	// lockoutUntilPrefix prefixes the identifier of the key marking a lockout in the store.
+	lockoutUntilPrefix = InternalPrefix + "lockout:until:"
+)
+
+// DefaultLockoutDurations are the default durations of successive lockouts.
+var DefaultLockoutDurations = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+// LockoutOptions are options for Lockout.
+type LockoutOptions struct {
+	// Durations are the durations of successive lockouts, by strike. The last one is used for every subsequent
+	// strike. If empty, DefaultLockoutDurations is used.
+	Durations []time.Duration
+	// Decay is the duration after a lockout during which its strike is remembered: an identifier which doesn't
+	// get a new strike during this duration starts again from the first one. If zero, DefaultLockoutDecay is used.
+	Decay time.Duration
+	// Clock gives the current time to compute the remaining duration of a lockout. If nil, SystemClock is used.
+	Clock Clock
+}
+
+// Lockout is a limiter for authentication, which locks out identifiers failing too often.
+// Failures are counted with its rate: once it's reached, the identifier gets a strike and is locked out for a
+// duration growing with its strikes. Strikes, failures and lockouts are kept in the store, so every instance
+// sharing the store agrees on them.
+type Lockout struct {
+	Store   Store
+	Rate    Rate
+	Options LockoutOptions
+}
+
+// NewLockout returns an instance of Lockout, allowing failures at given rate.
+func NewLockout(store Store, rate Rate, options LockoutOptions) *Lockout {
+	if len(options.Durations) == 0 {
+		options.Durations = DefaultLockoutDurations
+	}
+	if options.Decay <= 0 {
+		options.Decay = DefaultLockoutDecay
+	}
+	if options.Clock == nil {
+		options.Clock = SystemClock
+	}
+
+	return &Lockout{
+		Store:   store,
+		Rate:    rate,
+		Options: options,
+	}
+}
+
+// Get returns the limit for given identifier, without modification on current values.
+// If the identifier is locked out, the context is reached and gives the remaining duration of its lockout.
+func (lockout *Lockout) Get(ctx context.Context, key string) (Context, error) {
+	lctx, locked, err := lockout.check(ctx, key)
+	if err != nil || locked {
+		return lctx, err
+	}
+
+	return lockout.Store.Peek(ctx, lockoutFailuresPrefix+key, lockout.Rate)
+}
+
+// Fail counts a failure for given identifier, and locks it out if it has failed too often.
+// Failures are not counted while the identifier is locked out.
+func (lockout *Lockout) Fail(ctx context.Context, key string) (Context, error) {
+	lctx, locked, err := lockout.check(ctx, key)
+	if err != nil || locked {
+		return lctx, err
+	}
+
+	lctx, err = lockout.Store.Increment(ctx, lockoutFailuresPrefix+key, 1, lockout.Rate)
+	if err != nil || !lctx.Reached {
+		return lctx, err
+	}
+
+	return lockout.strike(ctx, key)
+}
+
+// Succeed resets the failures of given identifier, after a successful authentication.
+// Its strikes are kept until they decay.
+func (lockout *Lockout) Succeed(ctx context.Context, key string) error {
+	_, err := lockout.Store.Reset(ctx, lockoutFailuresPrefix+key, lockout.Rate)
+	return err
+}
+
+// Reset removes the failures, strikes and lockout of given identifier.
+func (lockout *Lockout) Reset(ctx context.Context, key string) (Context, error) {
+	prefixes := []string{lockoutUntilPrefix, lockoutStrikesPrefix, lockoutFailuresPrefix}
+	for _, prefix := range prefixes {
+		_, err := lockout.Store.Reset(ctx, prefix+key, lockout.Rate)
+		if err != nil {
+			return Context{}, err
+		}
+	}
+
+	return lockout.Store.Peek(ctx, lockoutFailuresPrefix+key, lockout.Rate)
+}
+
+// check returns the context of given identifier if it's locked out.
+func (lockout *Lockout) check(ctx context.Context, key string) (Context, bool, error) {
+	// A lockout is a key counted once, with a limit of zero, which expires with the lockout.
+	until, err := lockout.Store.Peek(ctx, lockoutUntilPrefix+key, Rate{Period: lockout.Options.Durations[0]})
+	if err != nil {
+		return Context{}, false, err
+	}
+	if !until.Reached {
+		return Context{}, false, nil
+	}
+
+	expiration := time.Unix(until.Reset, 0)
+	if !lockout.Options.Clock.Now().Before(expiration) {
+		return Context{}, false, nil
+	}
+
+	return lockout.lockedContext(expiration), true, nil
+}
+
+// strike adds a strike to given identifier, and locks it out for the duration of this strike.
+func (lockout *Lockout) strike(ctx context.Context, key string) (Context, error) {
+	// Strikes are counted without limit, so their number is given by the remaining hits.
+	strikes, err := lockout.Store.Peek(ctx, lockoutStrikesPrefix+key, Rate{
+		Limit:  math.MaxInt64,
+		Period: lockout.Options.Decay,
+	})
+	if err != nil {
+		return Context{}, err
+	}
+
+	count := strikes.Limit - strikes.Remaining + 1
+	duration := lockout.duration(count)
+
+	// Strikes are stored again, so they decay from the end of the last lockout.
+	_, err = lockout.Store.Reset(ctx, lockoutStrikesPrefix+key, lockout.Rate)
+	if err != nil {
+		return Context{}, err
+	}
+
+	_, err = lockout.Store.Increment(ctx, lockoutStrikesPrefix+key, count, Rate{
+		Limit:  math.MaxInt64,
+		Period: duration + lockout.Options.Decay,
+	})
+	if err != nil {
+		return Context{}, err
+	}
+
+	_, err = lockout.Store.Reset(ctx, lockoutUntilPrefix+key, lockout.Rate)
+	if err != nil {
+		return Context{}, err
+	}
+
+	_, err = lockout.Store.Increment(ctx, lockoutUntilPrefix+key, 1, Rate{Period: duration})
+	if err != nil {
+		return Context{}, err
+	}
+
+	_, err = lockout.Store.Reset(ctx, lockoutFailuresPrefix+key, lockout.Rate)
+	if err != nil {
+		return Context{}, err
+	}
+
+	return lockout.lockedContext(lockout.Options.Clock.Now().Add(duration)), nil
+}
+
+// duration returns the duration of the lockout of given strike.
+func (lockout *Lockout) duration(strike int64) time.Duration {
+	durations := lockout.Options.Durations
+	if strike > int64(len(durations)) {
+		return durations[len(durations)-1]
+	}
+
+	return durations[strike-1]
+}
+
+// lockedContext returns the context of an identifier locked out until given time.
+func (lockout *Lockout) lockedContext(until time.Time) Context {
+	return Context{
+		Limit:     lockout.Rate.Limit,
+		Remaining: 0,
+		Reset:     until.Unix(),
+		Reached:   true,
+		Lockout:   until.Sub(lockout.Options.Clock.Now()),
+	}
+}
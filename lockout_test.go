@@ -0,0 +1,108 @@
+package limiter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
+)
+
+func TestLockout(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:          "limiter:lockout",
+		CleanUpInterval: time.Minute,
+		Clock:           clock,
+	})
+
+	lockout := limiter.NewLockout(store, limiter.Rate{Limit: 2, Period: 15 * time.Minute}, limiter.LockoutOptions{
+		Durations: []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute},
+		Decay:     time.Hour,
+		Clock:     clock,
+	})
+
+	// strike fails until the identifier is locked out, and returns the duration of the lockout.
+	strike := func(key string) time.Duration {
+		for i := int64(1); i <= 2; i++ {
+			lctx, err := lockout.Fail(ctx, key)
+			is.NoError(err)
+			is.False(lctx.Reached)
+			is.Equal(2-i, lctx.Remaining)
+			is.Zero(lctx.Lockout)
+		}
+
+		lctx, err := lockout.Fail(ctx, key)
+		is.NoError(err)
+		is.True(lctx.Reached)
+
+		get, err := lockout.Get(ctx, key)
+		is.NoError(err)
+		is.Equal(lctx, get)
+
+		return lctx.Lockout
+	}
+
+	is.Equal(time.Minute, strike("alice"))
+
+	// Failures are not counted during a lockout.
+	clock.Advance(30 * time.Second)
+	lctx, err := lockout.Fail(ctx, "alice")
+	is.NoError(err)
+	is.True(lctx.Reached)
+	is.Equal(30*time.Second, lctx.Lockout)
+
+	// Other identifiers are not locked out.
+	lctx, err = lockout.Get(ctx, "bob")
+	is.NoError(err)
+	is.False(lctx.Reached)
+	is.Equal(int64(2), lctx.Remaining)
+
+	clock.Advance(30*time.Second + time.Second)
+	lctx, err = lockout.Get(ctx, "alice")
+	is.NoError(err)
+	is.False(lctx.Reached)
+	is.Equal(int64(2), lctx.Remaining)
+	is.Zero(lctx.Lockout)
+
+	// Lockouts grow with each strike, up to the last duration.
+	is.Equal(5*time.Minute, strike("alice"))
+	clock.Advance(5*time.Minute + time.Second)
+	is.Equal(30*time.Minute, strike("alice"))
+	clock.Advance(30*time.Minute + time.Second)
+	is.Equal(30*time.Minute, strike("alice"))
+
+	// Strikes decay after a lockout without new strike.
+	clock.Advance(30*time.Minute + time.Hour + time.Second)
+	is.Equal(time.Minute, strike("alice"))
+	clock.Advance(time.Minute + time.Second)
+
+	// Successes reset failures.
+	_, err = lockout.Fail(ctx, "alice")
+	is.NoError(err)
+	_, err = lockout.Fail(ctx, "alice")
+	is.NoError(err)
+	is.NoError(lockout.Succeed(ctx, "alice"))
+
+	lctx, err = lockout.Fail(ctx, "alice")
+	is.NoError(err)
+	is.False(lctx.Reached)
+	is.Equal(int64(1), lctx.Remaining)
+
+	// Reset lifts lockouts and forgets strikes.
+	is.Equal(time.Minute, strike("carol"))
+	_, err = lockout.Reset(ctx, "carol")
+	is.NoError(err)
+
+	lctx, err = lockout.Get(ctx, "carol")
+	is.NoError(err)
+	is.False(lctx.Reached)
+	is.Equal(time.Minute, strike("carol"))
+}
@@ -24,6 +24,12 @@ type Options struct {
 	// proxy is not configured properly to forward a trustworthy client IP.
 	// Please read the section "Limiter behind a reverse proxy" in the README for further information.
 	ClientIPHeader string
+	// Overrides defines a registry of rates replacing the rate of the limiter for some identifiers.
+	Overrides *Overrides
+	// Penalty defines the ban of identifiers which keep reaching their limit.
+	Penalty Penalty
+	// Adaptive defines a limit adapting to the health of the backend, fed by Limiter.Signal.
+	Adaptive *Adaptive
 }
 
 // WithIPv4Mask will configure the limiter to use given mask for IPv4 address.
@@ -59,3 +65,25 @@ func WithClientIPHeader(header string) Option {
 		o.ClientIPHeader = header
 	}
 }
+
+// WithOverrides will configure the limiter to use the rates of given registry for the identifiers it overrides.
+func WithOverrides(overrides *Overrides) Option {
+	return func(o *Options) {
+		o.Overrides = overrides
+	}
+}
+
+// WithPenalty will configure the limiter to ban identifiers which keep reaching their limit.
+func WithPenalty(penalty Penalty) Option {
+	return func(o *Options) {
+		o.Penalty = penalty
+	}
+}
+
+// WithAdaptive will configure the limiter to adapt its limit to the signals given to Limiter.Signal, up to the limit
+// of its rate by default.
+func WithAdaptive(adaptive Adaptive) Option {
+	return func(o *Options) {
+		o.Adaptive = &adaptive
+	}
+}
@@ -0,0 +1,94 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// Override is a rate replacing the rate of a limiter for an identifier, until it expires.
+type Override struct {
+	// Rate used for the identifier.
+	Rate Rate
+	// Expiration is the time at which the override is removed. It's zero if the override never expires.
+	Expiration time.Time
+}
+
+// Overrides is a registry of overrides, shared by the limiters configured with WithOverrides.
+// It only lives in memory: every instance of a service has its own registry.
+type Overrides struct {
+	mutex     sync.RWMutex
+	overrides map[string]Override
+	clock     Clock
+}
+
+// NewOverrides returns an empty registry of overrides.
+func NewOverrides() *Overrides {
+	return NewOverridesWithClock(SystemClock)
+}
+
+// NewOverridesWithClock returns an empty registry of overrides, using given clock to expire them.
+func NewOverridesWithClock(clock Clock) *Overrides {
+	return &Overrides{
+		overrides: map[string]Override{},
+		clock:     clock,
+	}
+}
+
+// Set overrides the rate of given identifier for given duration. Zero means forever.
+func (overrides *Overrides) Set(key string, rate Rate, duration time.Duration) Override {
+	override := Override{Rate: rate}
+	if duration > 0 {
+		override.Expiration = overrides.clock.Now().Add(duration)
+	}
+
+	overrides.mutex.Lock()
+	defer overrides.mutex.Unlock()
+
+	overrides.overrides[key] = override
+	return override
+}
+
+// Delete removes the override of given identifier, if any.
+func (overrides *Overrides) Delete(key string) {
+	overrides.mutex.Lock()
+	defer overrides.mutex.Unlock()
+
+	delete(overrides.overrides, key)
+}
+
+// Get returns the override of given identifier, if it exists and has not expired.
+func (overrides *Overrides) Get(key string) (Override, bool) {
+	overrides.mutex.RLock()
+	override, ok := overrides.overrides[key]
+	overrides.mutex.RUnlock()
+
+	if !ok || override.expired(overrides.clock.Now()) {
+		return Override{}, false
+	}
+
+	return override, true
+}
+
+// List returns every override which has not expired, by identifier. Expired ones are removed.
+func (overrides *Overrides) List() map[string]Override {
+	now := overrides.clock.Now()
+
+	overrides.mutex.Lock()
+	defer overrides.mutex.Unlock()
+
+	list := make(map[string]Override, len(overrides.overrides))
+	for key, override := range overrides.overrides {
+		if override.expired(now) {
+			delete(overrides.overrides, key)
+			continue
+		}
+		list[key] = override
+	}
+
+	return list
+}
+
+// expired returns true if the override has expired at given time.
+func (override Override) expired(now time.Time) bool {
+	return !override.Expiration.IsZero() && !now.Before(override.Expiration)
+}
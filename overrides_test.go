@@ -0,0 +1,90 @@
+package limiter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
+)
+
+func TestOverrides(t *testing.T) {
+	is := require.New(t)
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	overrides := limiter.NewOverridesWithClock(clock)
+
+	rate := limiter.Rate{Limit: 100, Period: time.Minute}
+
+	_, ok := overrides.Get("foo")
+	is.False(ok)
+
+	override := overrides.Set("foo", rate, time.Hour)
+	is.Equal(clock.Now().Add(time.Hour), override.Expiration)
+
+	override = overrides.Set("bar", rate, 0)
+	is.True(override.Expiration.IsZero())
+
+	override, ok = overrides.Get("foo")
+	is.True(ok)
+	is.Equal(rate, override.Rate)
+	is.Len(overrides.List(), 2)
+
+	// Overrides expire, unless they have no expiration.
+	clock.Advance(time.Hour)
+
+	_, ok = overrides.Get("foo")
+	is.False(ok)
+	_, ok = overrides.Get("bar")
+	is.True(ok)
+	is.Len(overrides.List(), 1)
+
+	overrides.Delete("bar")
+	is.Empty(overrides.List())
+}
+
+func TestLimiterOverrides(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	overrides := limiter.NewOverrides()
+	instance := limiter.New(memory.NewStore(), limiter.Rate{
+		Limit:  1,
+		Period: time.Minute,
+	}, limiter.WithOverrides(overrides))
+
+	overrides.Set("foo", limiter.Rate{Limit: 5, Period: time.Minute}, time.Hour)
+
+	lctx, err := instance.Get(ctx, "foo")
+	is.NoError(err)
+	is.Equal(int64(5), lctx.Limit)
+	is.Equal(int64(4), lctx.Remaining)
+
+	lctx, err = instance.Peek(ctx, "foo")
+	is.NoError(err)
+	is.Equal(int64(4), lctx.Remaining)
+
+	lctx, err = instance.Get(ctx, "bar")
+	is.NoError(err)
+	is.Equal(int64(1), lctx.Limit)
+	is.Equal(int64(0), lctx.Remaining)
+
+	// Batch methods use the rate of each identifier.
+	contexts, err := instance.GetMulti(ctx, []string{"foo", "baz"})
+	is.NoError(err)
+	is.Equal(int64(3), contexts[0].Remaining)
+	is.Equal(int64(5), contexts[0].Limit)
+	is.Equal(int64(0), contexts[1].Remaining)
+	is.Equal(int64(1), contexts[1].Limit)
+
+	overrides.Delete("foo")
+
+	lctx, err = instance.Get(ctx, "foo")
+	is.NoError(err)
+	is.Equal(int64(1), lctx.Limit)
+	is.True(lctx.Reached)
+}
@@ -0,0 +1,234 @@
+package limiter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// penaltyBanPrefix prefixes the identifier of the key marking a ban in the store.
+	penaltyBanPrefix = InternalPrefix + "penalty:ban:"
+	// penaltyViolationsPrefix prefixes the identifier of the key counting violations in the store.
+	penaltyViolationsPrefix = InternalPrefix + "penalty:violations:"
+	// penaltyBansPrefix prefixes the identifier of the key counting bans in the store.
+	penaltyBansPrefix = InternalPrefix + "penalty:bans:"
+	// maxLocalBans is the number of bans, or of checks, cached locally above which expired ones are removed.
+	maxLocalBans = 10000
+)
+
+// Penalty configures the ban of identifiers which keep reaching their limit.
+// Violations and bans are kept in the store of the limiter, so every instance sharing the store agrees on them.
+type Penalty struct {
+	// Violations is the number of reached limits, within Period, after which an identifier is banned.
+	// Zero disables bans.
+	Violations int64
+	// Period is the window in which violations are counted.
+	Period time.Duration
+	// Duration is the duration of the first ban.
+	Duration time.Duration
+	// Multiplier grows the duration of each subsequent ban exponentially, if greater than one.
+	Multiplier float64
+	// MaxDuration caps the duration of a ban grown by Multiplier. Zero means no cap.
+	MaxDuration time.Duration
+	// Memory is the duration during which bans are remembered to grow the next one.
+	// If zero, DefaultPenaltyMemory is used.
+	Memory time.Duration
+	// CheckInterval is the duration during which an identifier found without ban in the store is not checked
+	// again, so the ban of an identifier is not read from the store on every request. A ban given by another
+	// instance sharing the store may be enforced up to this duration late. If zero, DefaultPenaltyCheckInterval
+	// is used.
+	CheckInterval time.Duration
+	// Clock gives the current time to the local cache of bans. If nil, SystemClock is used.
+	Clock Clock
+}
+
+// penaltyBox bans the identifiers of a limiter according to its penalty.
+// Bans are cached locally, so banned identifiers are rejected without any access to the store, and so are the
+// checks of identifiers without ban.
+type penaltyBox struct {
+	penalty Penalty
+	mutex   sync.RWMutex
+	// bans contains the time until which each banned identifier is rejected.
+	bans map[string]time.Time
+	// checks contains the time until which each identifier found without ban is not checked again.
+	checks map[string]time.Time
+}
+
+// newPenaltyBox returns a penalty box for given penalty, or nil if bans are disabled.
+func newPenaltyBox(penalty Penalty) *penaltyBox {
+	if penalty.Violations <= 0 || penalty.Duration <= 0 {
+		return nil
+	}
+
+	if penalty.Period <= 0 {
+		penalty.Period = penalty.Duration
+	}
+	if penalty.Memory <= 0 {
+		penalty.Memory = DefaultPenaltyMemory
+	}
+	if penalty.CheckInterval <= 0 {
+		penalty.CheckInterval = DefaultPenaltyCheckInterval
+	}
+	if penalty.Clock == nil {
+		penalty.Clock = SystemClock
+	}
+
+	return &penaltyBox{
+		penalty: penalty,
+		bans:    map[string]time.Time{},
+		checks:  map[string]time.Time{},
+	}
+}
+
+// check returns the context of given identifier if it's banned, using the local cache, then the store.
+func (box *penaltyBox) check(ctx context.Context, store Store, key string, rate Rate) (Context, bool, error) {
+	now := box.penalty.Clock.Now()
+
+	box.mutex.RLock()
+	until, banned := box.bans[key]
+	checked, ok := box.checks[key]
+	box.mutex.RUnlock()
+
+	if banned && now.Before(until) {
+		return bannedContext(rate, until), true, nil
+	}
+	if ok && now.Before(checked) {
+		return Context{}, false, nil
+	}
+
+	// A ban is a key counted once, with a limit of zero, which expires with the ban.
+	ban, err := store.Peek(ctx, penaltyBanPrefix+key, Rate{Period: box.penalty.Duration})
+	if err != nil {
+		return Context{}, false, err
+	}
+	if !ban.Reached {
+		box.mutex.Lock()
+		cache(box.checks, key, now.Add(box.penalty.CheckInterval), now)
+		box.mutex.Unlock()
+		return Context{}, false, nil
+	}
+
+	until = time.Unix(ban.Reset, 0)
+	box.ban(key, until, now)
+
+	return bannedContext(rate, until), true, nil
+}
+
+// violate counts a reached limit of given identifier, and bans it if it has reached too many.
+// It returns the context of the identifier if it has been banned.
+func (box *penaltyBox) violate(ctx context.Context, store Store, key string, rate Rate) (Context, bool, error) {
+	violations, err := store.Increment(ctx, penaltyViolationsPrefix+key, 1, Rate{
+		Limit:  box.penalty.Violations - 1,
+		Period: box.penalty.Period,
+	})
+	if err != nil {
+		return Context{}, false, err
+	}
+	if !violations.Reached {
+		return Context{}, false, nil
+	}
+
+	// Bans are counted without limit, so their number is given by the remaining hits.
+	bans, err := store.Increment(ctx, penaltyBansPrefix+key, 1, Rate{
+		Limit:  math.MaxInt64,
+		Period: box.penalty.Memory,
+	})
+	if err != nil {
+		return Context{}, false, err
+	}
+
+	duration := box.duration(bans.Limit - bans.Remaining)
+
+	_, err = store.Increment(ctx, penaltyBanPrefix+key, 1, Rate{Period: duration})
+	if err != nil {
+		return Context{}, false, err
+	}
+
+	_, err = store.Reset(ctx, penaltyViolationsPrefix+key, Rate{Period: box.penalty.Period})
+	if err != nil {
+		return Context{}, false, err
+	}
+
+	now := box.penalty.Clock.Now()
+	until := now.Add(duration)
+	box.ban(key, until, now)
+
+	return bannedContext(rate, until), true, nil
+}
+
+// lift removes the ban and violations of given identifier.
+func (box *penaltyBox) lift(ctx context.Context, store Store, key string) error {
+	box.mutex.Lock()
+	delete(box.bans, key)
+	delete(box.checks, key)
+	box.mutex.Unlock()
+
+	prefixes := []string{penaltyBanPrefix, penaltyViolationsPrefix, penaltyBansPrefix}
+	for _, prefix := range prefixes {
+		_, err := store.Reset(ctx, prefix+key, Rate{Period: box.penalty.Duration})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// duration returns the duration of the nth ban of an identifier.
+func (box *penaltyBox) duration(nth int64) time.Duration {
+	duration := box.penalty.Duration
+	if box.penalty.Multiplier <= 1 || nth <= 1 {
+		return duration
+	}
+
+	grown := float64(duration) * math.Pow(box.penalty.Multiplier, float64(nth-1))
+	if box.penalty.MaxDuration > 0 && grown > float64(box.penalty.MaxDuration) {
+		return box.penalty.MaxDuration
+	}
+	if grown > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(grown)
+}
+
+// ban keeps given ban locally, so the identifier is no longer checked in the store until it expires.
+func (box *penaltyBox) ban(key string, until time.Time, now time.Time) {
+	box.mutex.Lock()
+	defer box.mutex.Unlock()
+
+	delete(box.checks, key)
+	cache(box.bans, key, until, now)
+}
+
+// cache adds given identifier to entries until given time, removing expired ones when there are too many.
+// If none has expired, every entry is removed: they're read again from the store.
+func cache(entries map[string]time.Time, key string, until time.Time, now time.Time) {
+	if len(entries) >= maxLocalBans {
+		for entry, expiration := range entries {
+			if !now.Before(expiration) {
+				delete(entries, entry)
+			}
+		}
+	}
+
+	if len(entries) >= maxLocalBans {
+		for entry := range entries {
+			delete(entries, entry)
+		}
+	}
+
+	entries[key] = until
+}
+
+// bannedContext returns the context of an identifier banned until given time.
+func bannedContext(rate Rate, until time.Time) Context {
+	return Context{
+		Limit:     rate.Limit,
+		Remaining: 0,
+		Reset:     until.Unix(),
+		Reached:   true,
+	}
+}
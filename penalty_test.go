@@ -0,0 +1,206 @@
+package limiter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/drivers/store/tests"
+)
+
+func TestLimiterPenalty(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:          "limiter:penalty",
+		CleanUpInterval: time.Minute,
+		Clock:           clock,
+	})
+
+	rate := limiter.Rate{Limit: 2, Period: time.Minute}
+	penalty := limiter.WithPenalty(limiter.Penalty{
+		Violations:  3,
+		Period:      time.Minute,
+		Duration:    10 * time.Minute,
+		Multiplier:  2,
+		MaxDuration: 30 * time.Minute,
+		Clock:       clock,
+	})
+
+	instance := limiter.New(store, rate, penalty)
+	replica := limiter.New(store, rate, penalty)
+
+	// ban hits the identifier until it's banned, and returns the duration of the ban.
+	ban := func(key string) time.Duration {
+		for i := 0; i < 2; i++ {
+			lctx, err := instance.Get(ctx, key)
+			is.NoError(err)
+			is.False(lctx.Reached)
+		}
+
+		for i := 0; i < 3; i++ {
+			lctx, err := instance.Get(ctx, key)
+			is.NoError(err)
+			is.True(lctx.Reached)
+		}
+
+		lctx, err := instance.Peek(ctx, key)
+		is.NoError(err)
+		is.True(lctx.Reached)
+		return time.Unix(lctx.Reset, 0).Sub(clock.Now())
+	}
+
+	is.Equal(10*time.Minute, ban("foo"))
+
+	// Banned identifiers are rejected without incrementing their counter.
+	for i := 0; i < 10; i++ {
+		lctx, err := instance.Get(ctx, "foo")
+		is.NoError(err)
+		is.True(lctx.Reached)
+		is.Equal(int64(0), lctx.Remaining)
+	}
+
+	lctx, err := store.Peek(ctx, "foo", rate)
+	is.NoError(err)
+	is.Equal(int64(0), lctx.Remaining)
+	is.Equal(time.Minute, time.Unix(lctx.Reset, 0).Sub(clock.Now()))
+
+	lctx, err = instance.Increment(ctx, "foo", 2)
+	is.NoError(err)
+	is.True(lctx.Reached)
+
+	// Every instance sharing the store agrees on bans.
+	lctx, err = replica.Get(ctx, "foo")
+	is.NoError(err)
+	is.True(lctx.Reached)
+	is.Equal(clock.Now().Add(10*time.Minute).Unix(), lctx.Reset)
+
+	// Other identifiers are not banned.
+	lctx, err = instance.Get(ctx, "bar")
+	is.NoError(err)
+	is.False(lctx.Reached)
+
+	// Ban durations grow exponentially, up to their maximum.
+	clock.Advance(10*time.Minute + time.Second)
+	is.Equal(20*time.Minute, ban("foo"))
+	clock.Advance(20*time.Minute + time.Second)
+	is.Equal(30*time.Minute, ban("foo"))
+
+	// Reset lifts the ban.
+	_, err = instance.Reset(ctx, "foo")
+	is.NoError(err)
+
+	lctx, err = instance.Get(ctx, "foo")
+	is.NoError(err)
+	is.False(lctx.Reached)
+	is.Equal(int64(1), lctx.Remaining)
+
+	// Violations are only counted when hits are added.
+	lctx, err = instance.Increment(ctx, "baz", 10)
+	is.NoError(err)
+	is.True(lctx.Reached)
+
+	for i := 0; i < 5; i++ {
+		lctx, err = instance.Increment(ctx, "baz", -1)
+		is.NoError(err)
+		is.True(lctx.Reached)
+	}
+
+	lctx, err = instance.Peek(ctx, "baz")
+	is.NoError(err)
+	is.Equal(clock.Now().Add(time.Minute).Unix(), lctx.Reset)
+}
+
+func TestLimiterPenaltyMulti(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	instance := limiter.New(memory.NewStore(), limiter.Rate{Limit: 1, Period: time.Minute},
+		limiter.WithPenalty(limiter.Penalty{
+			Violations: 1,
+			Period:     time.Minute,
+			Duration:   time.Hour,
+		}))
+
+	contexts, err := instance.GetMulti(ctx, []string{"foo", "foo", "bar"})
+	is.NoError(err)
+	is.False(contexts[0].Reached)
+	is.True(contexts[1].Reached)
+	is.False(contexts[2].Reached)
+
+	// The second hit has banned the identifier.
+	is.InDelta(time.Now().Add(time.Hour).Unix(), contexts[1].Reset, 1)
+
+	contexts, err = instance.IncrementMulti(ctx, []string{"foo", "bar"}, 1)
+	is.NoError(err)
+	is.True(contexts[0].Reached)
+	is.InDelta(time.Now().Add(time.Hour).Unix(), contexts[0].Reset, 1)
+	is.True(contexts[1].Reached)
+}
+
+func TestLimiterPenaltyCheckInterval(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	clock := tests.NewManualClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := &peekCounter{Store: memory.NewStoreWithOptions(limiter.StoreOptions{
+		Prefix:          "limiter:penalty-check",
+		CleanUpInterval: time.Minute,
+		Clock:           clock,
+	})}
+
+	rate := limiter.Rate{Limit: 1, Period: time.Minute}
+	penalty := limiter.WithPenalty(limiter.Penalty{
+		Violations:    1,
+		Duration:      10 * time.Minute,
+		CheckInterval: 5 * time.Second,
+		Clock:         clock,
+	})
+
+	instance := limiter.New(store, rate, penalty)
+	replica := limiter.New(store, rate, penalty)
+
+	// The ban of an identifier is read from the store once per interval.
+	for i := 0; i < 5; i++ {
+		_, err := instance.Peek(ctx, "foo")
+		is.NoError(err)
+	}
+	is.Equal(6, store.peeks)
+
+	// A ban given by another instance is enforced once the interval has elapsed.
+	_, err := replica.Get(ctx, "foo")
+	is.NoError(err)
+	lctx, err := replica.Get(ctx, "foo")
+	is.NoError(err)
+	is.True(lctx.Reached)
+
+	// Until then, the window of the identifier is given instead of its ban.
+	lctx, err = instance.Peek(ctx, "foo")
+	is.NoError(err)
+	is.Equal(clock.Now().Add(time.Minute).Unix(), lctx.Reset)
+
+	clock.Advance(5 * time.Second)
+
+	lctx, err = instance.Peek(ctx, "foo")
+	is.NoError(err)
+	is.True(lctx.Reached)
+	is.Equal(clock.Now().Add(10*time.Minute-5*time.Second).Unix(), lctx.Reset)
+}
+
+// peekCounter is a store counting its calls to Peek.
+type peekCounter struct {
+	limiter.Store
+	peeks int
+}
+
+// Peek counts the call, and returns the limit of given identifier.
+func (store *peekCounter) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	store.peeks++
+	return store.Store.Peek(ctx, key, rate)
+}
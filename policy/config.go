@@ -0,0 +1,84 @@
+package policy
+
+// Config is the declarative configuration of a policy, usually decoded from a JSON document like:
+//
+//	{
+//	  "trust_forward_header": false,
+//	  "client_ip_header": "",
+//	  "ipv4_mask": 32,
+//	  "ipv6_mask": 128,
+//	  "allowlist": ["127.0.0.1", "10.0.0.0/8", "internal-service-key"],
+//	  "default": {
+//	    "rate": "1000-H"
+//	  },
+//	  "routes": [
+//	    {
+//	      "name": "login",
+//	      "method": "POST",
+//	      "pattern": "/login",
+//	      "rate": "5-M"
+//	    },
+//	    {
+//	      "pattern": "/api/",
+//	      "rate": "100-M",
+//	      "key": {"source": "header", "name": "X-API-Key"},
+//	      "overrides": {"premium-customer-key": "1000-M"}
+//	    }
+//	  ]
+//	}
+//
+// Every field is optional, except the pattern and the rate of routes, and the rate of the default route.
+type Config struct {
+	// TrustForwardHeader enables the X-Forwarded-For and X-Real-IP headers to get the client IP address.
+	TrustForwardHeader bool `json:"trust_forward_header"`
+	// ClientIPHeader is the header holding the client IP address, if any.
+	ClientIPHeader string `json:"client_ip_header"`
+	// IPv4Mask is the size of the IPv4 networks sharing a limit. Default is 32.
+	IPv4Mask int `json:"ipv4_mask"`
+	// IPv6Mask is the size of the IPv6 networks sharing a limit. Default is 128.
+	IPv6Mask int `json:"ipv6_mask"`
+	// Allowlist contains keys, IP addresses or CIDR ranges which are never limited.
+	Allowlist []string `json:"allowlist"`
+	// Default is the route of the requests matching no other route. If nil, they are not limited.
+	Default *RouteConfig `json:"default"`
+	// Routes are matched in order, the first matching one limits the request.
+	Routes []RouteConfig `json:"routes"`
+}
+
+// RouteConfig is the configuration of a route.
+type RouteConfig struct {
+	// Name prefixes the keys of the route, so routes don't share their limits.
+	// If empty, the method and the pattern are used.
+	Name string `json:"name"`
+	// Method matched by the route, like "POST". If empty, every method is matched.
+	Method string `json:"method"`
+	// Pattern matched by the path of the request. A pattern ending with a slash matches every path starting
+	// with it, like "/api/", and a pattern with wildcards is matched with path.Match, like "/users/*/avatar".
+	// It must be empty for the default route.
+	Pattern string `json:"pattern"`
+	// Rate of the route, using the format of limiter.NewRateFromFormatted, like "100-M".
+	Rate string `json:"rate"`
+	// Key defines how requests are identified. If empty, the client IP address is used.
+	Key Key `json:"key"`
+	// Overrides gives a different rate to some keys, like "premium-customer-key": "1000-M".
+	Overrides map[string]string `json:"overrides"`
+}
+
+// Key defines how requests are identified.
+type Key struct {
+	// Source is one of "ip", "header", "query" and "cookie". Default is "ip".
+	Source string `json:"source"`
+	// Name is the name of the header, query parameter or cookie. It must be empty for the "ip" source.
+	Name string `json:"name"`
+}
+
+const (
+	// SourceIP identifies requests by their client IP address.
+	SourceIP = "ip"
+	// SourceHeader identifies requests by the value of a header.
+	SourceHeader = "header"
+	// SourceQuery identifies requests by the value of a query parameter.
+	SourceQuery = "query"
+	// SourceCookie identifies requests by the value of a cookie.
+	SourceCookie = "cookie"
+)
@@ -0,0 +1,386 @@
+// Package policy builds limiters from a declarative configuration, so rates, key sources, overrides,
+// allowlists and routes can be defined in a JSON file instead of Go code.
+//
+// The middleware packages of the drivers directory turn a Policy into a middleware with NewPolicyMiddleware.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/internal/pattern"
+)
+
+// FieldError is returned when a field of a configuration is invalid.
+type FieldError struct {
+	// Field is the path of the invalid field, like "routes[1].rate".
+	Field string
+	// Message describes why the field is invalid.
+	Message string
+}
+
+// Error returns the path of the field followed by the message.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Route is a route of a policy, with its own limiter.
+type Route struct {
+	// Name prefixes the keys of the route: its limiter expects keys like "login:127.0.0.1".
+	Name string
+	// Method matched by the route. If empty, every method is matched.
+	Method string
+	// Pattern matched by the path of the request. It's empty for the default route.
+	Pattern string
+	// Key defines how requests are identified.
+	Key Key
+	// Limiter used for the requests matching the route.
+	Limiter *limiter.Limiter
+}
+
+// Match returns true if given method and path match the route.
+func (route *Route) Match(method string, path string) bool {
+	if route.Method != "" && route.Method != method {
+		return false
+	}
+
+	return pattern.Match(route.Pattern, path)
+}
+
+// Policy is a set of routes, each one with its own limiter.
+type Policy struct {
+	// Routes are matched in order.
+	Routes []Route
+	// Default is the route of the requests matching no other route, if any.
+	Default *Route
+
+	allowlist map[string]bool
+	networks  []*net.IPNet
+}
+
+// Load decodes a JSON configuration from given reader, and returns the policy it defines using given store.
+// Unknown fields are rejected.
+func Load(r io.Reader, store limiter.Store) (*Policy, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	config := Config{}
+	err := decoder.Decode(&config)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return nil, &FieldError{
+				Field:   typeErr.Field,
+				Message: fmt.Sprintf("must be a %s, got %s", typeErr.Type, typeErr.Value),
+			}
+		}
+		return nil, errors.Wrap(err, "cannot decode policy")
+	}
+
+	return New(config, store)
+}
+
+// LoadFile decodes the JSON configuration of given file, and returns the policy it defines using given store.
+func LoadFile(name string, store limiter.Store) (*Policy, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open policy")
+	}
+	defer file.Close()
+
+	return Load(file, store)
+}
+
+// New returns the policy defined by given configuration, using given store for every route.
+// A *FieldError is returned if the configuration is invalid.
+func New(config Config, store limiter.Store) (*Policy, error) {
+	if config.Default == nil && len(config.Routes) == 0 {
+		return nil, &FieldError{Field: "routes", Message: "at least one route or a default route is required"}
+	}
+
+	options, err := newOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{
+		Routes:    make([]Route, len(config.Routes)),
+		allowlist: map[string]bool{},
+	}
+
+	err = policy.setAllowlist(config.Allowlist)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	for i := range config.Routes {
+		field := fmt.Sprintf("routes[%d]", i)
+		route, err := newRoute(config.Routes[i], field, store, options, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if previous, ok := names[route.Name]; ok {
+			return nil, &FieldError{
+				Field:   field + ".name",
+				Message: fmt.Sprintf("name '%s' is already used by %s", route.Name, previous),
+			}
+		}
+		names[route.Name] = field
+
+		policy.Routes[i] = route
+	}
+
+	if config.Default != nil {
+		route, err := newRoute(*config.Default, "default", store, options, true)
+		if err != nil {
+			return nil, err
+		}
+
+		if previous, ok := names[route.Name]; ok {
+			return nil, &FieldError{
+				Field:   "default.name",
+				Message: fmt.Sprintf("name '%s' is already used by %s", route.Name, previous),
+			}
+		}
+
+		policy.Default = &route
+	}
+
+	return policy, nil
+}
+
+// Match returns the first route matching given method and path, or the default route.
+// It returns nil if there is no default route and no route is matched.
+func (policy *Policy) Match(method string, path string) *Route {
+	for i := range policy.Routes {
+		if policy.Routes[i].Match(method, path) {
+			return &policy.Routes[i]
+		}
+	}
+
+	return policy.Default
+}
+
+// Excluded returns true if given key is in the allowlist, or if it's an IP address of an allowed range.
+// It's meant to be used as the excluded key function of a middleware.
+func (policy *Policy) Excluded(key string) bool {
+	if policy.allowlist[key] {
+		return true
+	}
+
+	if len(policy.networks) == 0 {
+		return false
+	}
+
+	ip := net.ParseIP(key)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range policy.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setAllowlist adds given entries to the allowlist, or to the allowed ranges if they are CIDR ranges.
+func (policy *Policy) setAllowlist(entries []string) error {
+	for i, entry := range entries {
+		field := fmt.Sprintf("allowlist[%d]", i)
+
+		if entry == "" {
+			return &FieldError{Field: field, Message: "must not be empty"}
+		}
+
+		if !strings.Contains(entry, "/") {
+			policy.allowlist[entry] = true
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return &FieldError{Field: field, Message: fmt.Sprintf("invalid CIDR range '%s'", entry)}
+		}
+		policy.networks = append(policy.networks, network)
+	}
+
+	return nil
+}
+
+// newOptions returns the options of the limiters from given configuration.
+func newOptions(config Config) ([]limiter.Option, error) {
+	options := []limiter.Option{
+		limiter.WithTrustForwardHeader(config.TrustForwardHeader),
+		limiter.WithClientIPHeader(config.ClientIPHeader),
+	}
+
+	if config.IPv4Mask != 0 {
+		if config.IPv4Mask < 0 || config.IPv4Mask > 32 {
+			return nil, &FieldError{
+				Field:   "ipv4_mask",
+				Message: fmt.Sprintf("must be between 1 and 32, got %d", config.IPv4Mask),
+			}
+		}
+		options = append(options, limiter.WithIPv4Mask(net.CIDRMask(config.IPv4Mask, 32)))
+	}
+
+	if config.IPv6Mask != 0 {
+		if config.IPv6Mask < 0 || config.IPv6Mask > 128 {
+			return nil, &FieldError{
+				Field:   "ipv6_mask",
+				Message: fmt.Sprintf("must be between 1 and 128, got %d", config.IPv6Mask),
+			}
+		}
+		options = append(options, limiter.WithIPv6Mask(net.CIDRMask(config.IPv6Mask, 128)))
+	}
+
+	return options, nil
+}
+
+// methods are the methods a route can match.
+var methods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "CONNECT": true, "OPTIONS": true, "TRACE": true,
+}
+
+// newRoute returns the route defined by given configuration, whose fields are prefixed by given field path.
+// A default route has no method and no pattern.
+func newRoute(config RouteConfig, field string, store limiter.Store,
+	options []limiter.Option, fallback bool) (Route, error) {
+
+	name := config.Name
+	if name == "" && fallback {
+		name = "default"
+	}
+	if name == "" {
+		name = strings.TrimSpace(config.Method + " " + config.Pattern)
+	}
+
+	switch {
+	case fallback && config.Method != "":
+		return Route{}, &FieldError{Field: field + ".method", Message: "must be empty for the default route"}
+	case fallback && config.Pattern != "":
+		return Route{}, &FieldError{Field: field + ".pattern", Message: "must be empty for the default route"}
+	case config.Method != "" && !methods[config.Method]:
+		return Route{}, &FieldError{
+			Field:   field + ".method",
+			Message: fmt.Sprintf("unknown method '%s'", config.Method),
+		}
+	case !fallback && config.Pattern == "":
+		return Route{}, &FieldError{Field: field + ".pattern", Message: "is required"}
+	}
+
+	if !fallback {
+		err := pattern.Validate(config.Pattern)
+		if err != nil {
+			return Route{}, &FieldError{Field: field + ".pattern", Message: err.Error()}
+		}
+	}
+
+	rate, err := newRate(config.Rate, field+".rate")
+	if err != nil {
+		return Route{}, err
+	}
+
+	key, err := newKey(config.Key, field+".key")
+	if err != nil {
+		return Route{}, err
+	}
+
+	if len(config.Overrides) > 0 {
+		overrides, err := newOverrides(config.Overrides, name, field+".overrides")
+		if err != nil {
+			return Route{}, err
+		}
+		options = append(options[:len(options):len(options)], limiter.WithOverrides(overrides))
+	}
+
+	return Route{
+		Name:    name,
+		Method:  config.Method,
+		Pattern: config.Pattern,
+		Key:     key,
+		Limiter: limiter.New(store, rate, options...),
+	}, nil
+}
+
+// newRate returns the rate of given format, at given field path.
+func newRate(formatted string, field string) (limiter.Rate, error) {
+	if formatted == "" {
+		return limiter.Rate{}, &FieldError{Field: field, Message: "is required"}
+	}
+
+	rate, err := limiter.NewRateFromFormatted(formatted)
+	if err != nil {
+		return limiter.Rate{}, &FieldError{Field: field, Message: err.Error()}
+	}
+
+	return rate, nil
+}
+
+// newKey returns given key with its default source, or an error if it's invalid.
+func newKey(key Key, field string) (Key, error) {
+	if key.Source == "" {
+		key.Source = SourceIP
+	}
+
+	switch key.Source {
+	case SourceIP:
+		if key.Name != "" {
+			return Key{}, &FieldError{Field: field + ".name", Message: "must be empty for the ip source"}
+		}
+	case SourceHeader, SourceQuery, SourceCookie:
+		if key.Name == "" {
+			return Key{}, &FieldError{
+				Field:   field + ".name",
+				Message: fmt.Sprintf("is required for the %s source", key.Source),
+			}
+		}
+	default:
+		return Key{}, &FieldError{
+			Field:   field + ".source",
+			Message: fmt.Sprintf("unknown source '%s', expected ip, header, query or cookie", key.Source),
+		}
+	}
+
+	return key, nil
+}
+
+// newOverrides returns the overrides of given rates by key for the route of given name, at given field path.
+// Keys are sorted, so the first invalid one is always reported.
+func newOverrides(rates map[string]string, name string, field string) (*limiter.Overrides, error) {
+	keys := make([]string, 0, len(rates))
+	for key := range rates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	overrides := limiter.NewOverrides()
+	for _, key := range keys {
+		if key == "" {
+			return nil, &FieldError{Field: field, Message: "keys must not be empty"}
+		}
+
+		rate, err := newRate(rates[key], fmt.Sprintf("%s.%s", field, key))
+		if err != nil {
+			return nil, err
+		}
+
+		overrides.Set(name+":"+key, rate, 0)
+	}
+
+	return overrides, nil
+}
@@ -0,0 +1,139 @@
+package policy_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/ulule/limiter/v3/policy"
+)
+
+const config = `{
+	"ipv4_mask": 24,
+	"allowlist": ["internal", "10.0.0.0/8"],
+	"default": {"rate": "100-H"},
+	"routes": [
+		{"name": "login", "method": "POST", "pattern": "/login", "rate": "5-M"},
+		{
+			"pattern": "/api/",
+			"rate": "10-M",
+			"key": {"source": "header", "name": "X-API-Key"},
+			"overrides": {"premium": "1000-M"}
+		}
+	]
+}`
+
+func TestLoad(t *testing.T) {
+	is := require.New(t)
+
+	p, err := policy.Load(strings.NewReader(config), memory.NewStore())
+	is.NoError(err)
+	is.Len(p.Routes, 2)
+
+	login := p.Routes[0]
+	is.Equal("login", login.Name)
+	is.Equal(policy.Key{Source: policy.SourceIP}, login.Key)
+	is.Equal(limiter.Rate{Formatted: "5-M", Limit: 5, Period: time.Minute}, login.Limiter.Rate)
+	is.Equal(net.CIDRMask(24, 32), login.Limiter.Options.IPv4Mask)
+
+	api := p.Routes[1]
+	is.Equal("/api/", api.Name)
+	is.Equal(policy.Key{Source: policy.SourceHeader, Name: "X-API-Key"}, api.Key)
+	is.Equal(int64(10), api.Limiter.GetRate("/api/:foo").Limit)
+	is.Equal(int64(1000), api.Limiter.GetRate("/api/:premium").Limit)
+
+	is.NotNil(p.Default)
+	is.Equal("default", p.Default.Name)
+	is.Equal(int64(100), p.Default.Limiter.Rate.Limit)
+
+	is.Equal(&p.Routes[0], p.Match("POST", "/login"))
+	is.Equal(p.Default, p.Match("GET", "/login"))
+	is.Equal(&p.Routes[1], p.Match("GET", "/api/orders"))
+	is.Equal(p.Default, p.Match("GET", "/"))
+
+	is.True(p.Excluded("internal"))
+	is.True(p.Excluded("10.1.2.0"))
+	is.False(p.Excluded("192.168.1.0"))
+	is.False(p.Excluded("foo"))
+
+	ctx, err := login.Limiter.Get(context.Background(), "login:192.168.1.0")
+	is.NoError(err)
+	is.Equal(int64(4), ctx.Remaining)
+}
+
+func TestLoadFile(t *testing.T) {
+	is := require.New(t)
+
+	name := filepath.Join(t.TempDir(), "policy.json")
+	is.NoError(os.WriteFile(name, []byte(config), 0o600))
+
+	p, err := policy.LoadFile(name, memory.NewStore())
+	is.NoError(err)
+	is.Len(p.Routes, 2)
+
+	_, err = policy.LoadFile(filepath.Join(t.TempDir(), "missing.json"), memory.NewStore())
+	is.Error(err)
+}
+
+func TestLoadErrors(t *testing.T) {
+	is := require.New(t)
+
+	cases := []struct {
+		config string
+		field  string
+		err    string
+	}{
+		{`{}`, "routes", "routes: at least one route or a default route is required"},
+		{`{"routes": [{"pattern": "/", "rate": "5-M"}], "ipv4_mask": 33}`, "ipv4_mask",
+			"ipv4_mask: must be between 1 and 32, got 33"},
+		{`{"default": {"rate": "5-M"}, "allowlist": ["foo", ""]}`, "allowlist[1]",
+			"allowlist[1]: must not be empty"},
+		{`{"default": {"rate": "5-M"}, "allowlist": ["10.0.0.0/33"]}`, "allowlist[0]",
+			"allowlist[0]: invalid CIDR range '10.0.0.0/33'"},
+		{`{"routes": [{"pattern": "/", "rate": "5-M"}, {"pattern": "/login"}]}`, "routes[1].rate",
+			"routes[1].rate: is required"},
+		{`{"routes": [{"pattern": "/login", "rate": "5-X"}]}`, "routes[0].rate",
+			"routes[0].rate: incorrect period 'X'"},
+		{`{"routes": [{"rate": "5-M"}]}`, "routes[0].pattern", "routes[0].pattern: is required"},
+		{`{"routes": [{"pattern": "login", "rate": "5-M"}]}`, "routes[0].pattern",
+			"routes[0].pattern: pattern 'login' must start with a slash"},
+		{`{"routes": [{"method": "get", "pattern": "/", "rate": "5-M"}]}`, "routes[0].method",
+			"routes[0].method: unknown method 'get'"},
+		{`{"routes": [{"pattern": "/", "rate": "5-M", "key": {"source": "body"}}]}`, "routes[0].key.source",
+			"routes[0].key.source: unknown source 'body', expected ip, header, query or cookie"},
+		{`{"routes": [{"pattern": "/", "rate": "5-M", "key": {"source": "query"}}]}`, "routes[0].key.name",
+			"routes[0].key.name: is required for the query source"},
+		{`{"routes": [{"pattern": "/", "rate": "5-M", "key": {"name": "X-User"}}]}`, "routes[0].key.name",
+			"routes[0].key.name: must be empty for the ip source"},
+		{`{"routes": [{"pattern": "/", "rate": "5-M", "overrides": {"foo": "5-M", "bar": "many"}}]}`,
+			"routes[0].overrides.bar", "routes[0].overrides.bar: incorrect format 'many'"},
+		{`{"routes": [{"name": "api", "pattern": "/a", "rate": "5-M"}, {"name": "api", "pattern": "/b", "rate": "1-S"}]}`,
+			"routes[1].name", "routes[1].name: name 'api' is already used by routes[0]"},
+		{`{"default": {"pattern": "/", "rate": "5-M"}}`, "default.pattern",
+			"default.pattern: must be empty for the default route"},
+		{`{"default": {"rate": 5}}`, "default.rate", "default.rate: must be a string, got number"},
+	}
+
+	for _, c := range cases {
+		_, err := policy.Load(strings.NewReader(c.config), memory.NewStore())
+		is.EqualError(err, c.err, c.config)
+
+		fieldErr, ok := err.(*policy.FieldError)
+		is.True(ok, c.config)
+		is.Equal(c.field, fieldErr.Field, c.config)
+	}
+
+	_, err := policy.Load(strings.NewReader(`{"default": {"rate": "5-M", "limit": 5}}`), memory.NewStore())
+	is.EqualError(err, `cannot decode policy: json: unknown field "limit"`)
+
+	_, err = policy.Load(strings.NewReader(`{"default": `), memory.NewStore())
+	is.Error(err)
+}
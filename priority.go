@@ -0,0 +1,201 @@
+package limiter
+
+import (
+	"context"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// priorityGroup is the group of the keys counting the hits of the classes in the store.
+const priorityGroup = InternalPrefix + "priority"
+
+// PriorityClass is a class of requests sharing a global rate with other classes.
+type PriorityClass struct {
+	// Name identifies the class.
+	Name string
+	// Share is the part of the global limit reserved to the class, between zero and one.
+	Share float64
+}
+
+// Priority sheds requests by priority class when a global rate is exhausted.
+// Each class has a reserved share of the limit, and can borrow the unused capacity of lower classes, along with
+// the capacity reserved to no class. Hits of each class are counted in the store, so every instance sharing the
+// store agrees on them: a store with a dedicated prefix should be used for each Priority.
+type Priority struct {
+	Store   Store
+	Rate    Rate
+	Classes []PriorityClass
+	// capacities gives, for each class, the capacity shared by the class and every lower class.
+	capacities []int64
+}
+
+// NewPriority returns an instance of Priority for given classes, from the highest priority to the lowest.
+// The shares of the classes must not exceed one.
+func NewPriority(store Store, rate Rate, classes ...PriorityClass) (*Priority, error) {
+	if len(classes) == 0 {
+		return nil, errors.New("at least one priority class is required")
+	}
+
+	names := map[string]bool{}
+	shares := float64(0)
+	reserved := make([]int64, len(classes))
+
+	for i, class := range classes {
+		if class.Name == "" {
+			return nil, errors.Errorf("priority class %d has no name", i)
+		}
+		if names[class.Name] {
+			return nil, errors.Errorf("priority class '%s' is defined twice", class.Name)
+		}
+		if class.Share < 0 || class.Share > 1 {
+			return nil, errors.Errorf("share of priority class '%s' must be between 0 and 1", class.Name)
+		}
+
+		names[class.Name] = true
+		shares += class.Share
+		reserved[i] = int64(float64(rate.Limit) * class.Share)
+	}
+
+	if shares > 1 {
+		return nil, errors.Errorf("shares of priority classes must not exceed 1, got %g", shares)
+	}
+
+	// The capacity reserved to no class is shared by every class.
+	capacities := make([]int64, len(classes))
+	capacity := rate.Limit
+	for i := range reserved {
+		capacities[i] = capacity
+		capacity -= reserved[i]
+	}
+
+	return &Priority{
+		Store:      store,
+		Rate:       rate,
+		Classes:    classes,
+		capacities: capacities,
+	}, nil
+}
+
+// Get counts a hit for given class, and returns the limit of this class: its reserved share and the capacity it
+// can currently borrow. An unknown class has the lowest priority. A rejected hit is not counted.
+//
+// With an AtomicStore, hits are counted in a single atomic increment. Otherwise, counters are read before being
+// incremented, and a hit exceeding the capacity of its class after a concurrent one is rejected but counted.
+func (priority *Priority) Get(ctx context.Context, class string) (Context, error) {
+	index := priority.index(class)
+	keys, rates := priority.keys(index)
+
+	if store, ok := priority.Store.(AtomicStore); ok {
+		contexts, err := store.IncrementAll(ctx, priorityGroup, keys, 1, rates)
+		if err != nil {
+			return Context{}, err
+		}
+		return priority.context(contexts, rates), nil
+	}
+
+	lctx, err := priority.peek(ctx, keys, rates)
+	if err != nil || lctx.Reached {
+		return lctx, err
+	}
+
+	contexts := make([]Context, len(keys))
+	for i := range keys {
+		contexts[i], err = priority.Store.Increment(ctx, priorityGroup+":"+keys[i], 1, rates[i])
+		if err != nil {
+			return Context{}, err
+		}
+	}
+
+	return priority.context(contexts, rates), nil
+}
+
+// Peek returns the limit of given class without counting a hit. Unlike Get, the context is reached as soon as
+// the class can't add a hit, so a request can be rejected before being counted by other limits.
+func (priority *Priority) Peek(ctx context.Context, class string) (Context, error) {
+	keys, rates := priority.keys(priority.index(class))
+	return priority.peek(ctx, keys, rates)
+}
+
+// peek returns the context of given keys without counting a hit, which is reached if a hit would be rejected.
+func (priority *Priority) peek(ctx context.Context, keys []string, rates []Rate) (Context, error) {
+	contexts := make([]Context, len(keys))
+	for i := range keys {
+		lctx, err := priority.Store.Peek(ctx, priorityGroup+":"+keys[i], rates[i])
+		if err != nil {
+			return Context{}, err
+		}
+		contexts[i] = lctx
+
+		if i > 0 && lctx.Remaining == 0 {
+			contexts[i].Reached = true
+		}
+	}
+
+	return priority.context(contexts, rates), nil
+}
+
+// keys returns the keys counting a hit of the class at given index, with their rates. The first key counts the
+// hits of the class, without limit. Each following key counts the hits of a class and every lower class, with
+// the capacity they share, for the class at given index and every higher class.
+func (priority *Priority) keys(index int) ([]string, []Rate) {
+	keys := make([]string, 0, index+2)
+	rates := make([]Rate, 0, index+2)
+
+	keys = append(keys, "class:"+priority.Classes[index].Name)
+	rates = append(rates, Rate{
+		Limit:  math.MaxInt64,
+		Period: priority.Rate.Period,
+	})
+
+	for i := 0; i <= index; i++ {
+		keys = append(keys, "shared:"+priority.Classes[i].Name)
+		rates = append(rates, Rate{
+			Limit:  priority.capacities[i],
+			Period: priority.Rate.Period,
+		})
+	}
+
+	return keys, rates
+}
+
+// context returns the limit of a class from the contexts of its keys: the hits of the class, and the number of
+// hits it can still add. It's reached if one of the shared capacities is reached.
+func (priority *Priority) context(contexts []Context, rates []Rate) Context {
+	count := rates[0].Limit - contexts[0].Remaining
+	remaining := int64(math.MaxInt64)
+	reached := false
+
+	for i := 1; i < len(contexts); i++ {
+		if contexts[i].Remaining < remaining {
+			remaining = contexts[i].Remaining
+		}
+		reached = reached || contexts[i].Reached
+	}
+
+	if reached {
+		return Context{
+			Limit:     count,
+			Remaining: 0,
+			Reset:     contexts[0].Reset,
+			Reached:   true,
+		}
+	}
+
+	return Context{
+		Limit:     count + remaining,
+		Remaining: remaining,
+		Reset:     contexts[0].Reset,
+	}
+}
+
+// index returns the index of given class, or the index of the lowest class if it's unknown.
+func (priority *Priority) index(class string) int {
+	for i := range priority.Classes {
+		if priority.Classes[i].Name == class {
+			return i
+		}
+	}
+
+	return len(priority.Classes) - 1
+}
@@ -0,0 +1,193 @@
+package limiter_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+)
+
+func TestNewPriority(t *testing.T) {
+	is := require.New(t)
+
+	store := memory.NewStore()
+	rate := limiter.Rate{Limit: 10, Period: time.Minute}
+
+	_, err := limiter.NewPriority(store, rate)
+	is.Error(err)
+
+	_, err = limiter.NewPriority(store, rate, limiter.PriorityClass{Share: 0.5})
+	is.Error(err)
+
+	_, err = limiter.NewPriority(store, rate,
+		limiter.PriorityClass{Name: "paid", Share: 0.5},
+		limiter.PriorityClass{Name: "paid", Share: 0.2})
+	is.EqualError(err, "priority class 'paid' is defined twice")
+
+	_, err = limiter.NewPriority(store, rate, limiter.PriorityClass{Name: "paid", Share: 1.5})
+	is.Error(err)
+
+	_, err = limiter.NewPriority(store, rate,
+		limiter.PriorityClass{Name: "paid", Share: 0.8},
+		limiter.PriorityClass{Name: "free", Share: 0.5})
+	is.EqualError(err, "shares of priority classes must not exceed 1, got 1.3")
+}
+
+func TestPriority(t *testing.T) {
+	testPriority(t, func() limiter.Store {
+		return memory.NewStore()
+	})
+
+	// Without an AtomicStore, counters are read before being incremented.
+	testPriority(t, func() limiter.Store {
+		return sequentialStore{Store: memory.NewStore()}
+	})
+}
+
+func testPriority(t *testing.T, newStore func() limiter.Store) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	newPriority := func() *limiter.Priority {
+		priority, err := limiter.NewPriority(newStore(), limiter.Rate{Limit: 10, Period: time.Minute},
+			limiter.PriorityClass{Name: "paid", Share: 0.5},
+			limiter.PriorityClass{Name: "free", Share: 0.3},
+			limiter.PriorityClass{Name: "background", Share: 0.2})
+		is.NoError(err)
+		return priority
+	}
+
+	// hit counts given number of hits for a class, and returns the number of admitted ones and the last context.
+	hit := func(priority *limiter.Priority, class string, count int) (int, limiter.Context) {
+		admitted := 0
+		lctx := limiter.Context{}
+		for i := 0; i < count; i++ {
+			var err error
+			lctx, err = priority.Get(ctx, class)
+			is.NoError(err)
+			if !lctx.Reached {
+				admitted++
+			}
+		}
+		return admitted, lctx
+	}
+
+	// Each class uses its reserved share, and borrows the unused capacity of lower classes.
+	priority := newPriority()
+
+	admitted, lctx := hit(priority, "background", 3)
+	is.Equal(2, admitted)
+	is.Equal(limiter.Context{Limit: 2, Remaining: 0, Reset: lctx.Reset, Reached: true}, lctx)
+
+	admitted, lctx = hit(priority, "free", 1)
+	is.Equal(1, admitted)
+	is.Equal(int64(3), lctx.Limit)
+	is.Equal(int64(2), lctx.Remaining)
+
+	admitted, _ = hit(priority, "free", 3)
+	is.Equal(2, admitted)
+
+	admitted, lctx = hit(priority, "paid", 6)
+	is.Equal(5, admitted)
+	is.True(lctx.Reached)
+	is.Equal(int64(5), lctx.Limit)
+
+	// Higher classes borrow the capacity of lower classes, which are then shed.
+	priority = newPriority()
+
+	admitted, lctx = hit(priority, "paid", 8)
+	is.Equal(8, admitted)
+	is.Equal(int64(10), lctx.Limit)
+	is.Equal(int64(2), lctx.Remaining)
+
+	admitted, _ = hit(priority, "background", 1)
+	is.Equal(1, admitted)
+
+	admitted, lctx = hit(priority, "free", 2)
+	is.Equal(1, admitted)
+	is.True(lctx.Reached)
+
+	// Rejected hits are not counted, and unknown classes have the lowest priority.
+	admitted, _ = hit(priority, "crawler", 1)
+	is.Equal(0, admitted)
+
+	admitted, lctx = hit(priority, "paid", 1)
+	is.Equal(0, admitted)
+	is.Equal(int64(8), lctx.Limit)
+}
+
+func TestPriorityPeek(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	priority, err := limiter.NewPriority(memory.NewStore(), limiter.Rate{Limit: 4, Period: time.Minute},
+		limiter.PriorityClass{Name: "paid", Share: 0.5},
+		limiter.PriorityClass{Name: "free", Share: 0.5})
+	is.NoError(err)
+
+	lctx, err := priority.Peek(ctx, "free")
+	is.NoError(err)
+	is.Equal(limiter.Context{Limit: 2, Remaining: 2, Reset: lctx.Reset}, lctx)
+
+	for i := 0; i < 2; i++ {
+		lctx, err = priority.Get(ctx, "free")
+		is.NoError(err)
+		is.False(lctx.Reached)
+	}
+
+	// A class is reached as soon as it can't add a hit, and peeking never counts one.
+	for i := 0; i < 2; i++ {
+		lctx, err = priority.Peek(ctx, "free")
+		is.NoError(err)
+		is.Equal(limiter.Context{Limit: 2, Remaining: 0, Reset: lctx.Reset, Reached: true}, lctx)
+	}
+
+	lctx, err = priority.Peek(ctx, "paid")
+	is.NoError(err)
+	is.Equal(limiter.Context{Limit: 2, Remaining: 2, Reset: lctx.Reset}, lctx)
+}
+
+func TestPriorityConcurrency(t *testing.T) {
+	is := require.New(t)
+	ctx := context.Background()
+
+	priority, err := limiter.NewPriority(memory.NewStore(), limiter.Rate{Limit: 10, Period: time.Minute},
+		limiter.PriorityClass{Name: "paid", Share: 0.5},
+		limiter.PriorityClass{Name: "free", Share: 0.5})
+	is.NoError(err)
+
+	// Concurrent hits never exceed the capacity of a class, and rejected ones are never counted.
+	admitted := int64(0)
+	wg := &sync.WaitGroup{}
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				lctx, err := priority.Get(ctx, "free")
+				is.NoError(err)
+				if !lctx.Reached {
+					atomic.AddInt64(&admitted, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	is.Equal(int64(5), admitted)
+
+	lctx, err := priority.Peek(ctx, "paid")
+	is.NoError(err)
+	is.Equal(int64(5), lctx.Remaining)
+}
+
+// sequentialStore is a store which isn't an AtomicStore.
+type sequentialStore struct {
+	limiter.Store
+}
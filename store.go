@@ -2,9 +2,15 @@ package limiter
 
 import (
 	"context"
+	"net/http"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
+// ErrStoreFull is returned by a bounded store which can't accept a new key.
+var ErrStoreFull = errors.New("store is full")
+
 // Store is the common interface for limiter stores.
 type Store interface {
 	// Get returns the limit for given identifier.
@@ -17,11 +23,60 @@ type Store interface {
 	Increment(ctx context.Context, key string, count int64, rate Rate) (Context, error)
 }
 
+// BatchStore is an optional interface for stores able to handle several identifiers in a single operation.
+type BatchStore interface {
+	// GetMulti returns the limit for given identifiers.
+	GetMulti(ctx context.Context, keys []string, rate Rate) ([]Context, error)
+	// IncrementMulti increments the limit by given count & gives back the new limit for given identifiers.
+	IncrementMulti(ctx context.Context, keys []string, count int64, rate Rate) ([]Context, error)
+}
+
+// AtomicStore is an optional interface for stores able to increment several identifiers atomically.
+type AtomicStore interface {
+	// IncrementAll increments the limit of given identifiers by given count, each one with the rate at the same
+	// index, only if none of them would exceed its limit. Otherwise, nothing is incremented: contexts give the
+	// current values, and are reached for every identifier which would exceed its limit.
+	//
+	// Identifiers belong to given group, which is shared by all of them: each one is counted as the group
+	// followed by a colon and the identifier, like "group:identifier". Stores partitioning their keys, like a
+	// Redis Cluster with hash tags, keep the identifiers of a group together. An error is returned if there
+	// isn't a rate for every identifier, or if an identifier is given twice.
+	IncrementAll(ctx context.Context, group string, keys []string, count int64, rates []Rate) ([]Context, error)
+}
+
+// InternalPrefix prefixes the identifiers kept in the store by the limiter itself, like the bans of Penalty, the
+// lockouts of Lockout and the hits of the classes of Priority. Identifiers starting with it are reserved.
+const InternalPrefix = "__limiter__:"
+
+// Scanner is an optional interface for stores able to enumerate the identifiers they currently count.
+type Scanner interface {
+	// Scan calls handler for every identifier starting with given prefix, until handler returns false.
+	// Identifiers are given without the prefix of the store, in no particular order. An identifier may be
+	// given more than once if it's modified during the scan. Identifiers starting with InternalPrefix are
+	// skipped.
+	Scan(ctx context.Context, prefix string, handler func(entry Entry) bool) error
+}
+
+// Entry is the state of an identifier, as given by a Scanner.
+type Entry struct {
+	// Key is the identifier.
+	Key string
+	// Count is the number of hits during the current window.
+	Count int64
+	// Expiration is the end of the current window. It's zero if the identifier never expires.
+	Expiration time.Time
+}
+
 // StoreOptions are options for store.
 type StoreOptions struct {
 	// Prefix is the prefix to use for the key.
 	Prefix string
 
+	// Clock gives the current time to the store. If nil, SystemClock is used.
+	// On memory store, a custom clock replaces the monotonic clock used for expirations.
+	// On redis store, expirations are handled by the server, so it's only used to report Reset.
+	Clock Clock
+
 	// MaxRetry is the maximum number of retry under race conditions on redis store.
 	// Deprecated: this option is no longer required since all operations are atomic now.
 	MaxRetry int
@@ -31,4 +86,81 @@ type StoreOptions struct {
 	// reduce performance and increase lock contention.
 	// Setting this to a high value will maximum throughput, but will increase the memory footprint.
 	CleanUpInterval time.Duration
+
+	// HashTag wraps the identifier in a hash tag on redis store (ie: "prefix:{key}").
+	// Every key derived from the same identifier is then stored in the same slot of a Redis Cluster,
+	// which is required by lua scripts using several keys. The prefix must not contain any curly brace.
+	// Closing braces and backslashes of identifiers are escaped, like an empty identifier, so the hash tag
+	// always covers the whole identifier.
+	HashTag bool
+
+	// BatchWindow is the window used on redis store to coalesce concurrent calls into a single pipeline.
+	// Calls are delayed up to this duration, so it should be kept short (around a millisecond).
+	// Setting this to zero disables batching.
+	BatchWindow time.Duration
+
+	// BatchMaxSize is the maximum number of calls coalesced into a single pipeline on redis store.
+	// A pipeline is sent as soon as it reaches this size, without waiting for the end of the window.
+	BatchMaxSize int
+
+	// CacheShards is the number of shards used by memory store to reduce lock contention, rounded up to a
+	// power of two. Each shard is cleaned up once per CleanUpInterval, on a staggered schedule.
+	// Zero uses a single shard. This option is ignored if MaxKeys or MaxBytes is defined.
+	CacheShards int
+
+	// MaxKeys is the maximum number of keys kept by memory store. Zero means unlimited.
+	MaxKeys int
+
+	// MaxBytes is the approximate maximum size, in bytes, of keys kept by memory store. Zero means unlimited.
+	MaxBytes int64
+
+	// Overflow defines how memory store handles a new key when MaxKeys or MaxBytes is reached.
+	Overflow OverflowPolicy
+
+	// MaxDrift is the maximum number of increments counted locally by a hybrid store before they are
+	// flushed to its remote store. It bounds the over-admission error: each instance admits at most
+	// MaxDrift requests per key and window that the others don't know about yet.
+	MaxDrift int64
+
+	// SyncInterval is the interval at which a hybrid store flushes its pending increments to its remote store.
+	SyncInterval time.Duration
+
+	// SnapshotFile is the file used by a persistent memory store to restore its counters on start,
+	// and to save them periodically and on close.
+	SnapshotFile string
+
+	// SnapshotInterval is the interval at which a persistent memory store saves its counters.
+	// Setting this to zero only saves counters on close.
+	SnapshotInterval time.Duration
+
+	// Table is the name of the table used by sql store. It must be a valid identifier for the dialect.
+	Table string
+
+	// PeerTimeout is the timeout of a request forwarded by a peer store to the peer owning an identifier.
+	PeerTimeout time.Duration
+
+	// PeerRetryInterval is the time duration during which a peer store counts locally the identifiers owned
+	// by a peer which has failed, before trying to reach it again.
+	PeerRetryInterval time.Duration
+
+	// PeerClient is the client used by a peer store to forward requests, like a client with a TLS configuration.
+	// PeerTimeout is ignored if it's defined.
+	PeerClient *http.Client
+
+	// PeerPrepareRequest is called by a peer store before forwarding a request, like to add the credentials
+	// checked by the Authorizer of the other peers.
+	PeerPrepareRequest func(r *http.Request)
 }
+
+// OverflowPolicy defines how a bounded store handles a new key when it's full.
+type OverflowPolicy int
+
+const (
+	// OverflowEvict evicts a key, expired or least recently used, to make room for the new one.
+	OverflowEvict OverflowPolicy = iota
+	// OverflowReject rejects the new key with ErrStoreFull.
+	// Expired keys still count until they are removed by the cleanup.
+	OverflowReject
+	// OverflowLimit considers that the new key has reached its limit.
+	OverflowLimit
+)